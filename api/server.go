@@ -0,0 +1,245 @@
+package api
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	backendpkg "ynab-monthly-expenses-manager/backend"
+)
+
+// OpenAPIDescription is the OpenAPI 3 description of the local HTTP API, served at /openapi.yaml
+//
+//go:embed openapi.yaml
+var OpenAPIDescription []byte
+
+// APIKeyHeader is the header carrying the API key, as an alternative to a bearer token in the Authorization header
+const APIKeyHeader string = "X-API-Key"
+
+// MinimumAPIKeyLength is the minimum number of characters of the API key, so that it cannot be guessed by other local processes
+const MinimumAPIKeyLength int = 16
+
+// exampleAPIKey is the placeholder API key of earlier example configurations, which is refused so that the API is never served with a well-known key
+const exampleAPIKey string = "change-me"
+
+// Server represents the local HTTP API, exposing the same Backend operations as the Wails bindings
+// Requests are served one at a time, as the Backend keeps the monthly expenses being split and imported
+type Server struct {
+	Backend *backendpkg.Backend
+	APIKey  string
+	mutex   sync.Mutex
+}
+
+// AmountsRequest represents the body of the requests that split the shared monthly expenses, with the amount of each category by name
+type AmountsRequest struct {
+	Amounts map[string]decimal.Decimal `json:"amounts"`
+}
+
+// ErrorResponse represents the body of every unsuccessful response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a new Server instance, which requires an API key of at least MinimumAPIKeyLength characters
+func NewServer(backend *backendpkg.Backend, apiKey string) (*Server, error) {
+	switch {
+	case apiKey == "":
+		return nil, errors.New("an API key is required, set server.api_key in the configuration or the " + backendpkg.APIKeyEnvironmentVariable + " environment variable")
+	case apiKey == exampleAPIKey:
+		return nil, fmt.Errorf("the API key %q of the example configuration cannot be used, set server.api_key in the configuration or the %s environment variable to a random key", exampleAPIKey, backendpkg.APIKeyEnvironmentVariable)
+	case len(apiKey) < MinimumAPIKeyLength:
+		return nil, fmt.Errorf("the API key must be at least %d characters long, set server.api_key in the configuration or the %s environment variable to a longer random key", MinimumAPIKeyLength, backendpkg.APIKeyEnvironmentVariable)
+	}
+
+	return &Server{Backend: backend, APIKey: apiKey}, nil
+}
+
+// Handler returns the HTTP handler routing every endpoint of the API
+// Every endpoint but the OpenAPI description requires the API key
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/openapi.yaml", server.handleOpenAPIDescription)
+	mux.Handle("/v1/participants", server.authenticate(http.MethodGet, server.handleParticipants))
+	mux.Handle("/v1/categories", server.authenticate(http.MethodGet, server.handleCategories))
+	mux.Handle("/v1/shared-monthly-expenses", server.authenticate(http.MethodGet, server.handleSharedMonthlyExpenses))
+	mux.Handle("/v1/split", server.authenticate(http.MethodPost, server.handleSplit))
+	mux.Handle("/v1/plan", server.authenticate(http.MethodPost, server.handlePlan))
+	mux.Handle("/v1/import", server.authenticate(http.MethodPost, server.handleImport))
+	mux.Handle("/v1/rounding/balances", server.authenticate(http.MethodGet, server.handleRoundingBalances))
+	mux.Handle("/v1/outbox", server.authenticate(http.MethodGet, server.handleOutbox))
+	mux.Handle("/v1/diagnostics", server.authenticate(http.MethodGet, server.handleDiagnostics))
+
+	return mux
+}
+
+// ListenAndServe serves the API on the given address until the server fails
+func (server *Server) ListenAndServe(address string) error {
+	return http.ListenAndServe(address, server.Handler())
+}
+
+// authenticate wraps an endpoint, rejecting requests with another method or without the API key and serving the others one at a time
+func (server *Server) authenticate(method string, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != method {
+			writer.Header().Set("Allow", method)
+			writeError(writer, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		apiKey := request.Header.Get(APIKeyHeader)
+		if bearerToken, found := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer "); found {
+			apiKey = bearerToken
+		}
+
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(server.APIKey)) != 1 {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(writer, http.StatusUnauthorized, errors.New("missing or invalid API key"))
+			return
+		}
+
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		handler(writer, request)
+	})
+}
+
+// handleOpenAPIDescription serves the OpenAPI description of the API
+func (server *Server) handleOpenAPIDescription(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/yaml")
+	_, _ = writer.Write(OpenAPIDescription)
+}
+
+// handleParticipants serves the names of the participants in the order declared in the household configuration
+func (server *Server) handleParticipants(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.Backend.GetParticipantNames())
+}
+
+// handleCategories serves the names of the monthly expenses categories in the order declared in the household configuration
+func (server *Server) handleCategories(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.Backend.GetCategoryNames())
+}
+
+// handleSharedMonthlyExpenses serves the shared monthly expenses
+func (server *Server) handleSharedMonthlyExpenses(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.Backend.GetSharedMonthlyExpenses())
+}
+
+// handleSplit splits the shared monthly expenses among the participants and serves the result
+func (server *Server) handleSplit(writer http.ResponseWriter, request *http.Request) {
+	combinedMonthlyExpenses, ok := server.split(writer, request)
+	if !ok {
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, combinedMonthlyExpenses)
+}
+
+// handlePlan splits the shared monthly expenses and serves the YNAB transactions that importing them would create, without sending anything to YNAB
+func (server *Server) handlePlan(writer http.ResponseWriter, request *http.Request) {
+	combinedMonthlyExpenses, ok := server.split(writer, request)
+	if !ok {
+		return
+	}
+
+	importPlan, err := server.Backend.GetImportPlan(combinedMonthlyExpenses)
+	if err != nil {
+		writeError(writer, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, importPlan)
+}
+
+// handleImport splits the shared monthly expenses and imports them into YNAB, serving what happened in each YNAB budget
+// The duplicates query parameter tells how to resolve the transactions already entered in YNAB that the import would duplicate, which without it are served with status 409 instead of importing anything
+// A failed import is served with status 502, as it is YNAB that failed the request, unless it was queued in the outbox because YNAB could not be reached, which is served with status 202
+func (server *Server) handleImport(writer http.ResponseWriter, request *http.Request) {
+	if !server.Backend.IsSetupValid() {
+		writeError(writer, http.StatusServiceUnavailable, errors.New("the YNAB budgets, accounts or categories declared in the configuration could not be found"))
+		return
+	}
+
+	duplicateResolution := request.URL.Query().Get("duplicates")
+	if !backendpkg.IsValidDuplicateResolution(duplicateResolution) {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("unknown duplicate resolution %q", duplicateResolution))
+		return
+	}
+
+	combinedMonthlyExpenses, ok := server.split(writer, request)
+	if !ok {
+		return
+	}
+
+	importResult := server.Backend.CreateMonthlyExpensesTransactions(combinedMonthlyExpenses, duplicateResolution)
+	if importResult.Queued {
+		writeJSON(writer, http.StatusAccepted, importResult)
+		return
+	}
+	if len(importResult.PossibleDuplicates) > 0 {
+		writeJSON(writer, http.StatusConflict, importResult)
+		return
+	}
+	if !importResult.Success {
+		writeJSON(writer, http.StatusBadGateway, importResult)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, importResult)
+}
+
+// handleRoundingBalances serves the cumulative rounding in each participant's favour recorded in the rounding ledger
+func (server *Server) handleRoundingBalances(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.Backend.GetRoundingBalances())
+}
+
+// handleOutbox serves the imports queued in the outbox, waiting for YNAB to be reachable or rejected by it
+func (server *Server) handleOutbox(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.Backend.GetQueuedImports())
+}
+
+// handleDiagnostics serves the report of the checks run while setting up the backend
+func (server *Server) handleDiagnostics(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.Backend.GetDiagnostics())
+}
+
+// split decodes the amounts in the request body and splits the shared monthly expenses, writing an error response if either fails
+func (server *Server) split(writer http.ResponseWriter, request *http.Request) (*backendpkg.CombinedMonthlyExpenses, bool) {
+	var amountsRequest AmountsRequest
+
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&amountsRequest); err != nil {
+		writeError(writer, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	combinedMonthlyExpenses, err := server.Backend.SplitMonthlyExpenses(amountsRequest.Amounts)
+	if err != nil {
+		writeError(writer, http.StatusUnprocessableEntity, err)
+		return nil, false
+	}
+
+	return combinedMonthlyExpenses, true
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(value)
+}
+
+// writeError writes an ErrorResponse with the given status
+func writeError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	backendpkg "ynab-monthly-expenses-manager/backend"
+)
+
+const testAPIKey string = "test-api-key-4f9c2a7e"
+
+func TestServer(t *testing.T) {
+	ynabServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"data": {"transactions": [{"id": "created"}], "duplicate_import_ids": []}}`))
+	}))
+	defer ynabServer.Close()
+
+	server, err := NewServer(createTestBackend(ynabServer.URL), testAPIKey)
+	assert.NoError(t, err)
+
+	apiServer := httptest.NewServer(server.Handler())
+	defer apiServer.Close()
+
+	testCases := map[string]struct {
+		method           string
+		path             string
+		headers          map[string]string
+		body             string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		"missing API key": {
+			method:           http.MethodGet,
+			path:             "/v1/participants",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: "missing or invalid API key",
+		},
+		"invalid API key": {
+			method:           http.MethodGet,
+			path:             "/v1/participants",
+			headers:          map[string]string{"Authorization": "Bearer another-api-key"},
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: "missing or invalid API key",
+		},
+		"API key as bearer token": {
+			method:           http.MethodGet,
+			path:             "/v1/participants",
+			headers:          map[string]string{"Authorization": "Bearer " + testAPIKey},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `["Magui","Jão"]`,
+		},
+		"API key header": {
+			method:           http.MethodGet,
+			path:             "/v1/categories",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `["Water"]`,
+		},
+		"method not allowed": {
+			method:           http.MethodGet,
+			path:             "/v1/split",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedResponse: "method not allowed",
+		},
+		"split": {
+			method:           http.MethodPost,
+			path:             "/v1/split",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			body:             `{"amounts": {"Water": "60.25"}}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `"amount":"30.13"`,
+		},
+		"split - malformed body": {
+			method:           http.MethodPost,
+			path:             "/v1/split",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			body:             `{"Water": "60.25"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `unknown field \"Water\"`,
+		},
+		"split - unknown category": {
+			method:           http.MethodPost,
+			path:             "/v1/split",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			body:             `{"amounts": {"Water": 60.25, "Gas": 20}}`,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedResponse: `category \"Gas\" is not declared in the configuration`,
+		},
+		"plan": {
+			method:           http.MethodPost,
+			path:             "/v1/plan",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			body:             `{"amounts": {"Water": 60.25}}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `"amount":-60250`,
+		},
+		"import - unknown duplicate resolution": {
+			method:           http.MethodPost,
+			path:             "/v1/import?duplicates=ignore",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			body:             `{"amounts": {"Water": 60.25}}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `unknown duplicate resolution \"ignore\"`,
+		},
+		"import": {
+			method:           http.MethodPost,
+			path:             "/v1/import",
+			headers:          map[string]string{APIKeyHeader: testAPIKey},
+			body:             `{"amounts": {"Water": 60.25}}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `"success":true`,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			request, err := http.NewRequest(testCase.method, apiServer.URL+testCase.path, strings.NewReader(testCase.body))
+			assert.NoError(t, err)
+			for header, value := range testCase.headers {
+				request.Header.Set(header, value)
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			assert.NoError(t, err)
+			defer response.Body.Close()
+
+			var body json.RawMessage
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+			assert.Equal(t, testCase.expectedStatus, response.StatusCode)
+			assert.Contains(t, string(body), testCase.expectedResponse)
+		})
+	}
+}
+
+func TestServerRequiresAPIKey(t *testing.T) {
+	testCases := map[string]struct {
+		apiKey        string
+		expectedError string
+	}{
+		"no API key": {
+			expectedError: "an API key is required",
+		},
+		"API key of the example configuration": {
+			apiKey:        "change-me",
+			expectedError: "of the example configuration cannot be used",
+		},
+		"short API key": {
+			apiKey:        "0123456789abcde",
+			expectedError: "must be at least 16 characters long",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			_, err := NewServer(&backendpkg.Backend{}, testCase.apiKey)
+
+			assert.ErrorContains(t, err, testCase.expectedError)
+			assert.ErrorContains(t, err, backendpkg.APIKeyEnvironmentVariable)
+		})
+	}
+}
+
+func TestOpenAPIDescription(t *testing.T) {
+	server, err := NewServer(&backendpkg.Backend{}, testAPIKey)
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Expected the OpenAPI description to be served without the API key")
+
+	var description struct {
+		OpenAPI string                 `yaml:"openapi"`
+		Paths   map[string]interface{} `yaml:"paths"`
+	}
+	assert.NoError(t, yaml.Unmarshal(recorder.Body.Bytes(), &description))
+	assert.Equal(t, "3.0.3", description.OpenAPI)
+
+	for _, path := range []string{"/v1/participants", "/v1/categories", "/v1/shared-monthly-expenses", "/v1/split", "/v1/plan", "/v1/import", "/v1/rounding/balances", "/v1/diagnostics"} {
+		assert.Contains(t, description.Paths, path, "Expected every endpoint to be described")
+	}
+}
+
+// createTestBackend creates a Backend with two participants sharing a single category equally, importing into the given YNAB API
+func createTestBackend(ynabURL string) *backendpkg.Backend {
+	return &backendpkg.Backend{
+		Config: &backendpkg.Config{
+			Participants: []backendpkg.ParticipantConfig{{Name: "Magui"}, {Name: "Jão"}},
+			Categories:   backendpkg.CategoriesConfig{Expenses: []backendpkg.ExpenseConfig{{Name: "Water"}}},
+		},
+		BudgetService: &backendpkg.APIClient{Client: resty.New().SetBaseURL(ynabURL)},
+		CombinedMonthlyExpenses: &backendpkg.CombinedMonthlyExpenses{
+			SharedMonthlyExpenses: &backendpkg.MonthlyExpenses{
+				BudgetId:  "shared",
+				AccountId: "shared-account",
+				Expenses: map[string]*backendpkg.MonthlyExpense{
+					"Water": {CategoryId: to.StringPtr("water"), PayeeName: to.StringPtr("EPAL"), Memo: to.StringPtr("")},
+				},
+			},
+			IndividualMonthlyExpenses: []*backendpkg.MonthlyExpenses{
+				{ParticipantName: "Magui", Expenses: map[string]*backendpkg.MonthlyExpense{"Water": {}}},
+				{ParticipantName: "Jão", Expenses: map[string]*backendpkg.MonthlyExpense{"Water": {}}},
+			},
+			RoundingStrategy: &backendpkg.LargestRemainderRoundingStrategy{},
+		},
+	}
+}
@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// AccessTokenEnvironmentVariable is the environment variable holding the YNAB Personal Access Token, which takes precedence over the token and vault files
+const AccessTokenEnvironmentVariable string = "YNAB_ACCESS_TOKEN"
+
+// VaultPassphraseEnvironmentVariable is the environment variable holding the passphrase that unlocks the access token vault, e.g. when running the CLI
+const VaultPassphraseEnvironmentVariable string = "YNAB_MONTHLY_EXPENSES_VAULT_PASSPHRASE"
+
+// AccessTokenFileName is the name of the file, in the application directory, holding the YNAB Personal Access Token in plain text
+// The file must only be readable and writable by its owner
+const AccessTokenFileName string = "access_token"
+
+// AccessTokenVaultFileName is the name of the file, in the application directory, holding the YNAB Personal Access Token encrypted with a passphrase
+const AccessTokenVaultFileName string = "access_token.vault"
+
+// Sources of the YNAB Personal Access Token
+const (
+	AccessTokenSourceEnvironment string = "environment"
+	AccessTokenSourceFile        string = "file"
+	AccessTokenSourceVault       string = "vault"
+)
+
+// scrypt parameters used to derive the key of the access token vault from its passphrase
+const (
+	vaultKeyCost        int = 1 << 15
+	vaultKeyBlockSize   int = 8
+	vaultKeyParallelism int = 1
+	vaultKeyLength      int = 32
+	vaultSaltLength     int = 16
+)
+
+// ErrAccessTokenMissing is returned when no YNAB Personal Access Token is configured
+var ErrAccessTokenMissing = errors.New("no YNAB Personal Access Token is configured")
+
+// ErrVaultLocked is returned when the YNAB Personal Access Token is in the vault but no passphrase was given to unlock it
+var ErrVaultLocked = errors.New("the YNAB Personal Access Token vault is locked")
+
+// ErrVaultPassphrase is returned when the passphrase does not unlock the access token vault
+var ErrVaultPassphrase = errors.New("the passphrase does not unlock the YNAB Personal Access Token vault")
+
+// ErrNoAccessTokenStore is returned when saving an access token or OAuth tokens while there is nowhere to save them, e.g. in demo mode or when the application directory cannot be located
+var ErrNoAccessTokenStore = errors.New("there is nowhere to save the YNAB access token")
+
+// AccessTokenStatus represents where the YNAB Personal Access Token is configured, if anywhere, and whether it still has to be unlocked
+// OAuth is available when an OAuth application is configured, so the YNAB login can be authorized instead of entering a token
+type AccessTokenStatus struct {
+	Configured     bool   `json:"configured"`
+	Source         string `json:"source"`
+	Locked         bool   `json:"locked"`
+	OAuthAvailable bool   `json:"oauth_available"`
+	Error          string `json:"error"`
+}
+
+// AccessTokenVault represents the access token vault file, holding the YNAB Personal Access Token encrypted with AES-256-GCM under a key derived from a passphrase with scrypt
+type AccessTokenVault struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// AccessTokenStore represents the locations the YNAB Personal Access Token, or the OAuth tokens of an authorized YNAB login, are read from and saved to
+type AccessTokenStore struct {
+	FilePath           string
+	VaultFilePath      string
+	OAuthTokenFilePath string
+}
+
+// DefaultAccessTokenStore returns the AccessTokenStore with the token and vault files in the application directory
+func DefaultAccessTokenStore() (*AccessTokenStore, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessTokenStore{
+		FilePath:           filepath.Join(applicationDirectory, AccessTokenFileName),
+		VaultFilePath:      filepath.Join(applicationDirectory, AccessTokenVaultFileName),
+		OAuthTokenFilePath: filepath.Join(applicationDirectory, OAuthTokenFileName),
+	}, nil
+}
+
+// LoadTokenSource returns the TokenSource authenticating the YNAB API requests, and where its access token was found
+// When an OAuth application is configured and a YNAB login was authorized, its refreshing OAuth tokens are used unless an access token is set in the environment
+// Otherwise the YNAB Personal Access Token is read as Load does
+func (store *AccessTokenStore) LoadTokenSource(passphrase string, oauthConfig OAuthConfig) (TokenSource, string, error) {
+	if oauthConfig.ClientId != "" && os.Getenv(AccessTokenEnvironmentVariable) == "" {
+		oauthTokenSource, err := NewOAuthTokenSource(NewOAuthClient(oauthConfig), store.OAuthTokenFilePath)
+		if err == nil {
+			return oauthTokenSource, AccessTokenSourceOAuth, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, AccessTokenSourceOAuth, err
+		}
+	}
+
+	accessToken, accessTokenSource, err := store.Load(passphrase)
+	if err != nil {
+		return nil, accessTokenSource, err
+	}
+
+	return StaticTokenSource(accessToken), accessTokenSource, nil
+}
+
+// Load reads the YNAB Personal Access Token from the environment, the token file or the vault, in that order, returning where it was found
+// The vault is only unlocked if a passphrase is given, otherwise ErrVaultLocked is returned
+func (store *AccessTokenStore) Load(passphrase string) (string, string, error) {
+	if accessToken := strings.TrimSpace(os.Getenv(AccessTokenEnvironmentVariable)); accessToken != "" {
+		return accessToken, AccessTokenSourceEnvironment, nil
+	}
+
+	accessToken, err := store.loadFile()
+	if err == nil {
+		return accessToken, AccessTokenSourceFile, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", AccessTokenSourceFile, err
+	}
+
+	accessToken, err = store.loadVault(passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", ErrAccessTokenMissing
+	}
+
+	return accessToken, AccessTokenSourceVault, err
+}
+
+// loadFile reads the YNAB Personal Access Token from the token file, refusing to do so if other users could read it
+func (store *AccessTokenStore) loadFile() (string, error) {
+	fileInfo, err := os.Stat(store.FilePath)
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS != "windows" && fileInfo.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("access token file %s must only be accessible by its owner, e.g. chmod 600, but has permissions %s", store.FilePath, fileInfo.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(store.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("reading access token file: %w", err)
+	}
+
+	accessToken := strings.TrimSpace(string(data))
+	if accessToken == "" {
+		return "", fmt.Errorf("access token file %s is empty", store.FilePath)
+	}
+
+	return accessToken, nil
+}
+
+// loadVault reads and decrypts the YNAB Personal Access Token from the vault
+func (store *AccessTokenStore) loadVault(passphrase string) (string, error) {
+	data, err := os.ReadFile(store.VaultFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase == "" {
+		return "", ErrVaultLocked
+	}
+
+	var vault AccessTokenVault
+	if err = json.Unmarshal(data, &vault); err != nil {
+		return "", fmt.Errorf("decoding access token vault %s: %w", store.VaultFilePath, err)
+	}
+
+	gcm, err := getVaultCipher(passphrase, vault.Salt)
+	if err != nil {
+		return "", err
+	}
+
+	accessToken, err := gcm.Open(nil, vault.Nonce, vault.Ciphertext, nil)
+	if err != nil {
+		return "", ErrVaultPassphrase
+	}
+
+	return string(accessToken), nil
+}
+
+// Save persists the YNAB Personal Access Token, encrypted in the vault if a passphrase is given or in the token file otherwise
+// The other file and any OAuth tokens are removed, so that the token is only ever persisted in one place and takes effect
+func (store *AccessTokenStore) Save(accessToken string, passphrase string) error {
+	accessToken = strings.TrimSpace(accessToken)
+	if accessToken == "" {
+		return ErrAccessTokenMissing
+	}
+
+	path, otherPath := store.FilePath, store.VaultFilePath
+	data := []byte(accessToken)
+
+	if passphrase != "" {
+		path, otherPath = store.VaultFilePath, store.FilePath
+
+		vault := AccessTokenVault{Version: 1, Salt: make([]byte, vaultSaltLength)}
+		if _, err := rand.Read(vault.Salt); err != nil {
+			return err
+		}
+
+		gcm, err := getVaultCipher(passphrase, vault.Salt)
+		if err != nil {
+			return err
+		}
+
+		vault.Nonce = make([]byte, gcm.NonceSize())
+		if _, err = rand.Read(vault.Nonce); err != nil {
+			return err
+		}
+		vault.Ciphertext = gcm.Seal(nil, vault.Nonce, data, nil)
+
+		if data, err = json.MarshalIndent(vault, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating access token directory: %w", err)
+	}
+
+	if err := writeFileAtomically(path, data, 0600); err != nil {
+		return fmt.Errorf("writing access token: %w", err)
+	}
+
+	for _, previousPath := range []string{otherPath, store.OAuthTokenFilePath} {
+		if previousPath == "" {
+			continue
+		}
+		if err := os.Remove(previousPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing previous access token: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getVaultCipher derives the key of the access token vault from its passphrase and salt
+func getVaultCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, vaultKeyCost, vaultKeyBlockSize, vaultKeyParallelism, vaultKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("deriving access token vault key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
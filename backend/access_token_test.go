@@ -0,0 +1,137 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessTokenStore(t *testing.T) {
+	accessToken := gofakeit.UUID()
+
+	testCases := map[string]struct {
+		environmentAccessToken string
+		savePassphrase         string
+		loadPassphrase         string
+		filePermissions        os.FileMode
+		expectedSource         string
+		expectedError          error
+		expectedErrorMessage   string
+	}{
+		"nothing configured": {
+			expectedError: ErrAccessTokenMissing,
+		},
+		"environment": {
+			environmentAccessToken: accessToken,
+			expectedSource:         AccessTokenSourceEnvironment,
+		},
+		"file": {
+			savePassphrase: "",
+			expectedSource: AccessTokenSourceFile,
+		},
+		"file readable by other users": {
+			filePermissions:      0644,
+			expectedSource:       AccessTokenSourceFile,
+			expectedErrorMessage: "must only be accessible by its owner",
+		},
+		"vault": {
+			savePassphrase: "correct horse battery staple",
+			loadPassphrase: "correct horse battery staple",
+			expectedSource: AccessTokenSourceVault,
+		},
+		"vault locked": {
+			savePassphrase: "correct horse battery staple",
+			expectedSource: AccessTokenSourceVault,
+			expectedError:  ErrVaultLocked,
+		},
+		"vault with wrong passphrase": {
+			savePassphrase: "correct horse battery staple",
+			loadPassphrase: "incorrect horse battery staple",
+			expectedSource: AccessTokenSourceVault,
+			expectedError:  ErrVaultPassphrase,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			t.Setenv(AccessTokenEnvironmentVariable, testCase.environmentAccessToken)
+
+			directory := t.TempDir()
+			store := &AccessTokenStore{
+				FilePath:      filepath.Join(directory, AccessTokenFileName),
+				VaultFilePath: filepath.Join(directory, AccessTokenVaultFileName),
+			}
+
+			if testCase.expectedSource == AccessTokenSourceFile || testCase.expectedSource == AccessTokenSourceVault {
+				assert.NoError(t, store.Save(accessToken, testCase.savePassphrase))
+			}
+			if testCase.filePermissions != 0 {
+				if runtime.GOOS == "windows" {
+					t.Skip("file permissions are not checked on Windows")
+				}
+				assert.NoError(t, os.Chmod(store.FilePath, testCase.filePermissions))
+			}
+
+			actualAccessToken, actualSource, err := store.Load(testCase.loadPassphrase)
+
+			assert.Equal(t, testCase.expectedSource, actualSource)
+
+			if testCase.expectedError != nil || testCase.expectedErrorMessage != "" {
+				if testCase.expectedError != nil {
+					assert.ErrorIs(t, err, testCase.expectedError)
+				}
+				assert.ErrorContains(t, err, testCase.expectedErrorMessage)
+				assert.Empty(t, actualAccessToken)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, accessToken, actualAccessToken)
+		})
+	}
+}
+
+func TestSaveAccessTokenKeepsASingleCopy(t *testing.T) {
+	t.Setenv(AccessTokenEnvironmentVariable, "")
+
+	directory := t.TempDir()
+	store := &AccessTokenStore{
+		FilePath:      filepath.Join(directory, AccessTokenFileName),
+		VaultFilePath: filepath.Join(directory, AccessTokenVaultFileName),
+	}
+	accessToken := gofakeit.UUID()
+
+	assert.NoError(t, store.Save(accessToken, ""))
+	assert.FileExists(t, store.FilePath)
+
+	assert.NoError(t, store.Save(accessToken, "passphrase"))
+	assert.NoFileExists(t, store.FilePath, "Expected the plain text access token to be removed once it is in the vault")
+
+	vaultData, err := os.ReadFile(store.VaultFilePath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(vaultData), accessToken, "Expected the access token to be encrypted in the vault")
+
+	if runtime.GOOS != "windows" {
+		fileInfo, err := os.Stat(store.VaultFilePath)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+	}
+}
+
+func TestSaveAccessTokenWithoutAccessTokenStore(t *testing.T) {
+	setupApplicationDirectory(t)
+
+	config, err := LoadConfig(ConfigPath())
+	assert.NoError(t, err)
+
+	backend := SetupBackendWithBudgetService(NewMemoryBudgetServiceFromConfig(config))
+	assert.Nil(t, backend.AccessTokenStore, "Expected no access token store to be needed by the budget service")
+	backend.Config.OAuth = OAuthConfig{ClientId: "client", ClientSecret: "secret", RedirectAddress: "127.0.0.1:8788"}
+
+	assert.ErrorIs(t, backend.SaveAccessToken(gofakeit.UUID(), ""), ErrNoAccessTokenStore)
+	assert.ErrorIs(t, backend.AuthorizeWithYNAB(), ErrNoAccessTokenStore)
+}
@@ -1,5 +1,10 @@
 package backend
 
+import (
+	"context"
+	"fmt"
+)
+
 // Account represents a YNAB account
 // This struct corresponds to the data structure defined in the YNAB API documentation
 type Account struct {
@@ -26,11 +31,28 @@ type Account struct {
 // Accounts represents a collection of YNAB accounts
 type Accounts []Account
 
-// SharedMonthlyExpensesAccountName is the predefined name of the YNAB account designated for shared monthly expenses
-const SharedMonthlyExpensesAccountName string = "Millennium bcp"
+// GetAccounts fetches the YNAB accounts of a YNAB budget changed since the given server knowledge, or every account if it is 0, along with the current server knowledge
+// GET https://api.ynab.com/v1/budgets/{budget_id}/accounts
+func (client *APIClient) GetAccounts(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (Accounts, int64, error) {
+	accountsResponse := struct {
+		Data struct {
+			Accounts        Accounts `json:"accounts"`
+			ServerKnowledge int64    `json:"server_knowledge"`
+		} `json:"data"`
+	}{}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetQueryParams(getDeltaQueryParams(lastKnowledgeOfServer)).
+		SetResult(&accountsResponse).
+		Get(fmt.Sprintf("budgets/%s/accounts", budgetId))
 
-// IndividualMonthlyExpensesAccountName is the predefined name of the YNAB account designated for individual monthly expenses
-const IndividualMonthlyExpensesAccountName string = "CGD"
+	if err = client.ValidateResponse(response, err); err != nil {
+		return nil, 0, err
+	}
+
+	return accountsResponse.Data.Accounts, accountsResponse.Data.ServerKnowledge, nil
+}
 
 // GetMonthlyExpensesAccount fetches the YNAB account designated for monthly expenses based on its name
 func (accounts *Accounts) GetMonthlyExpensesAccount(accountName string) Account {
@@ -1,36 +1,206 @@
 package backend
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
+// DefaultYNABBaseURL is the base URL of the YNAB API
+const DefaultYNABBaseURL string = "https://api.ynab.com/v1"
+
+// YNABBaseURLEnvironmentVariable is the name of the environment variable that overrides the base URL of the YNAB API, e.g. to point it to a fake YNAB API in tests
+const YNABBaseURLEnvironmentVariable string = "YNAB_MONTHLY_EXPENSES_YNAB_URL"
+
+// Defaults of the HTTP transport used to reach the YNAB API, unless declared otherwise in YNABConfig
+const (
+	DefaultYNABTimeout        time.Duration = 30 * time.Second
+	DefaultYNABConnectTimeout time.Duration = 10 * time.Second
+	DefaultYNABUserAgent      string        = "ynab-monthly-expenses-manager"
+)
+
+// Retries of the YNAB API requests failing transiently, waiting exponentially longer between attempts
+const (
+	APIRetryCount       int           = 3
+	APIRetryWaitTime    time.Duration = time.Second
+	APIRetryMaxWaitTime time.Duration = 30 * time.Second
+)
+
 // APIClient represents an API client for interacting with the YNAB API, embedding a Resty client to handle HTTP requests
+// Its token bucket, if any, keeps the requests within the YNAB API rate limit
 type APIClient struct {
 	*resty.Client
+	RateLimiter *TokenBucket
 }
 
-// AccessToken is the YNAB Personal Access Token
-// Please ensure this token is kept secure and not exposed publicly
-const AccessToken string = ""
+// Configure sets up the APIClient with the necessary configurations for interacting with the YNAB API, reaching it as declared in the YNAB configuration
+// Every request is authenticated with the access token the token source returns at that time, so OAuth access tokens are refreshed as they expire
+// Requests failing transiently are retried with exponential backoff, and requests beyond the YNAB API rate limit fail without being sent
+func (client *APIClient) Configure(tokenSource TokenSource, ynabConfig YNABConfig) error {
+	if client.RateLimiter == nil {
+		client.RateLimiter = NewTokenBucket(YNABRateLimit, YNABRateLimitInterval)
+	}
 
-// Configure sets up the APIClient with the necessary configurations for interacting with the YNAB API
-func (client *APIClient) Configure() {
-	client.SetBaseURL("https://api.ynab.com/v1")
+	transport, err := NewYNABTransport(ynabConfig)
+	if err != nil {
+		return err
+	}
+
+	client.SetTransport(transport)
+	client.SetTimeout(getDurationOrDefault(ynabConfig.Timeout, DefaultYNABTimeout))
+	client.SetBaseURL(GetYNABBaseURL(ynabConfig))
 	client.SetHeader("Accept", "application/json")
-	client.SetAuthToken(AccessToken)
+	client.SetHeader("User-Agent", getStringOrDefault(ynabConfig.UserAgent, DefaultYNABUserAgent))
+	client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		accessToken, err := tokenSource.Token(request.Context())
+		if err != nil {
+			return err
+		}
+
+		request.SetAuthToken(accessToken)
+
+		return nil
+	})
+
+	client.SetRateLimiter(client.RateLimiter)
+	client.OnAfterResponse(func(_ *resty.Client, response *resty.Response) error {
+		client.RateLimiter.Sync(response.Header().Get("X-Rate-Limit"))
+		return nil
+	})
+
+	client.SetRetryCount(APIRetryCount)
+	client.SetRetryWaitTime(APIRetryWaitTime)
+	client.SetRetryMaxWaitTime(APIRetryMaxWaitTime)
+	client.AddRetryCondition(isTransientFailure)
+	client.SetRetryAfter(func(_ *resty.Client, response *resty.Response) (time.Duration, error) {
+		return getRetryAfter(response), nil
+	})
+
+	return nil
+}
+
+// NewYNABTransport creates the HTTP transport used to reach the YNAB API, through the declared proxy and trusting the declared CA bundle in addition to the system certificates
+func NewYNABTransport(ynabConfig YNABConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   getDurationOrDefault(ynabConfig.ConnectTimeout, DefaultYNABConnectTimeout),
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = getDurationOrDefault(ynabConfig.ConnectTimeout, DefaultYNABConnectTimeout)
+
+	if ynabConfig.Proxy != "" {
+		proxyURL, err := url.Parse(ynabConfig.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing YNAB proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if ynabConfig.CABundle != "" {
+		certificates, err := os.ReadFile(ynabConfig.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading YNAB CA bundle: %w", err)
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(certificates) {
+			return nil, fmt.Errorf("reading YNAB CA bundle: no PEM certificates found in %s", ynabConfig.CABundle)
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+
+	return transport, nil
 }
 
-// ValidateResponse checks if the API response indicates an error
+// GetYNABBaseURL returns the base URL of the YNAB API, preferring the environment variable over the declared one and the declared one over the default one
+func GetYNABBaseURL(ynabConfig YNABConfig) string {
+	if baseURL := os.Getenv(YNABBaseURLEnvironmentVariable); baseURL != "" {
+		return baseURL
+	}
+
+	return getStringOrDefault(strings.TrimSuffix(ynabConfig.BaseURL, "/"), DefaultYNABBaseURL)
+}
+
+// getDurationOrDefault parses a declared duration, falling back to the default one if it is not declared or invalid
+func getDurationOrDefault(value string, defaultDuration time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration
+	}
+
+	return defaultDuration
+}
+
+// getStringOrDefault returns the declared value, falling back to the default one if it is not declared
+func getStringOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// isTransientFailure checks if a YNAB API request failed in a way that may succeed when retried
+// Retrying the creation of transactions is safe, as YNAB does not create transactions with an import id it already has
+// Requests whose context is done are never retried, as the retry would be aborted as well
+func isTransientFailure(response *resty.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if isNetworkError(err) {
+		return true
+	}
+
+	return response != nil && (response.StatusCode() == http.StatusTooManyRequests || response.StatusCode() >= http.StatusInternalServerError)
+}
+
+// ValidateResponse checks if the API response indicates an error, returning it as an APIError
+// A request the client-side rate limiter held back is reported as a rate limited APIError as well, and a request whose context was canceled as ErrCanceled
 func (client *APIClient) ValidateResponse(response *resty.Response, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return ErrCanceled
+	}
+
+	if errors.Is(err, resty.ErrRateLimitExceeded) && client.RateLimiter != nil {
+		return &APIError{
+			StatusCode: http.StatusTooManyRequests,
+			Id:         "429",
+			Name:       "too_many_requests",
+			Detail:     "the client-side limit of requests per hour was reached",
+			RetryAfter: client.RateLimiter.GetWaitTime(),
+		}
+	}
+
 	if err != nil {
 		return err
 	}
 
 	if response.IsError() {
-		return errors.New(string(response.Body()))
+		return newAPIError(response)
 	}
 
 	return nil
 }
+
+// GetRateLimitStatus returns how many YNAB API requests the client may still send, or the full rate limit if it is not limited
+func (client *APIClient) GetRateLimitStatus() RateLimitStatus {
+	if client.RateLimiter == nil {
+		return RateLimitStatus{Limit: YNABRateLimit, Remaining: YNABRateLimit}
+	}
+
+	return client.RateLimiter.GetStatus()
+}
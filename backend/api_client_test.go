@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResponse(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode         int
+		headers            map[string]string
+		body               string
+		expectedKind       error
+		expectedError      string
+		expectedRetryAfter time.Duration
+	}{
+		"unauthorized": {
+			statusCode:    http.StatusUnauthorized,
+			body:          `{"error": {"id": "401", "name": "unauthorized", "detail": "Unauthorized"}}`,
+			expectedKind:  ErrUnauthorized,
+			expectedError: "YNAB API error 401 unauthorized: Unauthorized",
+		},
+		"not found": {
+			statusCode:    http.StatusNotFound,
+			body:          `{"error": {"id": "404.2", "name": "resource_not_found", "detail": "Resource not found"}}`,
+			expectedKind:  ErrNotFound,
+			expectedError: "YNAB API error 404 resource_not_found: Resource not found",
+		},
+		"conflict": {
+			statusCode:    http.StatusConflict,
+			body:          `{"error": {"id": "409", "name": "conflict", "detail": "Resource conflicts with an existing one"}}`,
+			expectedKind:  ErrConflict,
+			expectedError: "YNAB API error 409 conflict: Resource conflicts with an existing one",
+		},
+		"rate limited": {
+			statusCode:         http.StatusTooManyRequests,
+			headers:            map[string]string{"Retry-After": "120"},
+			body:               `{"error": {"id": "429", "name": "too_many_requests", "detail": "Too many requests"}}`,
+			expectedKind:       ErrRateLimited,
+			expectedError:      "YNAB API error 429 too_many_requests: Too many requests",
+			expectedRetryAfter: 2 * time.Minute,
+		},
+		"server error without envelope": {
+			statusCode:    http.StatusBadGateway,
+			body:          "upstream unavailable",
+			expectedKind:  ErrServerError,
+			expectedError: "YNAB API error 502 bad_gateway: upstream unavailable",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				for name, value := range testCase.headers {
+					writer.Header().Set(name, value)
+				}
+				writer.WriteHeader(testCase.statusCode)
+				_, _ = writer.Write([]byte(testCase.body))
+			}))
+			defer server.Close()
+
+			client := APIClient{Client: resty.New().SetBaseURL(server.URL)}
+
+			_, err := client.GetUser(context.Background())
+
+			var apiError *APIError
+			assert.True(t, errors.As(err, &apiError), "Expected an APIError, but got %v", err)
+			assert.ErrorIs(t, err, testCase.expectedKind)
+			assert.EqualError(t, err, testCase.expectedError)
+			assert.Equal(t, testCase.expectedRetryAfter, apiError.RetryAfter)
+
+			for _, otherKind := range []error{ErrUnauthorized, ErrNotFound, ErrConflict, ErrRateLimited, ErrServerError} {
+				if otherKind != testCase.expectedKind {
+					assert.NotErrorIs(t, err, otherKind)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigureRetriesTransientFailures(t *testing.T) {
+	var attempts int
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		attempts++
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("X-Rate-Limit", fmt.Sprintf("%d/200", 149+attempts))
+
+		if attempts < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = writer.Write([]byte(`{"data": {"user": {"id": "user"}}}`))
+	}))
+	defer server.Close()
+
+	client := APIClient{Client: resty.New()}
+	assert.NoError(t, client.Configure(StaticTokenSource("token"), YNABConfig{BaseURL: server.URL}))
+	client.SetRetryWaitTime(time.Millisecond).SetRetryMaxWaitTime(time.Millisecond)
+
+	user, err := client.GetUser(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user", user.Id)
+	assert.Equal(t, 3, attempts, "Expected the request to be retried until it succeeded")
+	assert.Equal(t, RateLimitStatus{Limit: YNABRateLimit, Remaining: 48}, client.GetRateLimitStatus(), "Expected the requests YNAB reports as used to be taken out of the token bucket")
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	bucket := NewTokenBucket(2, time.Hour)
+	bucket.now = func() time.Time { return now }
+	bucket.updatedAt = now
+
+	assert.True(t, bucket.Allow())
+	assert.True(t, bucket.Allow())
+	assert.False(t, bucket.Allow(), "Expected the bucket to be empty")
+	assert.Equal(t, 30*time.Minute, bucket.GetWaitTime())
+
+	now = now.Add(30 * time.Minute)
+
+	assert.Equal(t, RateLimitStatus{Limit: 2, Remaining: 1}, bucket.GetStatus(), "Expected the bucket to refill over time")
+	assert.True(t, bucket.Allow())
+
+	now = now.Add(3 * time.Hour)
+
+	assert.Equal(t, 2, bucket.GetStatus().Remaining, "Expected the bucket not to refill beyond its capacity")
+}
+
+func TestValidateResponseReportsClientSideRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"data": {"user": {"id": "user"}}}`))
+	}))
+	defer server.Close()
+
+	client := APIClient{Client: resty.New(), RateLimiter: NewTokenBucket(1, time.Hour)}
+	assert.NoError(t, client.Configure(StaticTokenSource("token"), YNABConfig{BaseURL: server.URL}))
+
+	_, err := client.GetUser(context.Background())
+	assert.NoError(t, err)
+
+	_, err = client.GetUser(context.Background())
+	assert.ErrorIs(t, err, ErrRateLimited, "Expected the request beyond the limit not to be sent")
+	assert.Equal(t, 0, client.GetRateLimitStatus().Remaining)
+}
+
+func TestConfigureReachesYNABAsDeclared(t *testing.T) {
+	var userAgent string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		userAgent = request.Header.Get("User-Agent")
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"data": {"user": {"id": "user"}}}`))
+	}))
+	defer server.Close()
+
+	caBundlePath := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caBundlePath, certificate, 0600))
+
+	testCases := map[string]struct {
+		ynabConfig        YNABConfig
+		expectedUserAgent string
+		expectedError     string
+	}{
+		"CA bundle trusted": {
+			ynabConfig:        YNABConfig{BaseURL: server.URL + "/", CABundle: caBundlePath, UserAgent: "household-budget/1.0"},
+			expectedUserAgent: "household-budget/1.0",
+		},
+		"CA bundle not declared": {
+			ynabConfig:    YNABConfig{BaseURL: server.URL},
+			expectedError: "certificate signed by unknown authority",
+		},
+		"CA bundle not found": {
+			ynabConfig:    YNABConfig{BaseURL: server.URL, CABundle: filepath.Join(t.TempDir(), "missing.pem")},
+			expectedError: "reading YNAB CA bundle",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			userAgent = ""
+
+			client := APIClient{Client: resty.New()}
+			err := client.Configure(StaticTokenSource("token"), testCase.ynabConfig)
+			if err == nil {
+				client.SetRetryCount(0)
+				_, err = client.GetUser(context.Background())
+			}
+
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedUserAgent, userAgent)
+		})
+	}
+}
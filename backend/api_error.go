@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Kinds of YNAB API errors, matched with errors.Is against an APIError
+var (
+	ErrBadRequest   = errors.New("YNAB rejected the request")
+	ErrUnauthorized = errors.New("YNAB rejected the access token")
+	ErrNotFound     = errors.New("YNAB resource not found")
+	ErrConflict     = errors.New("YNAB resource conflicts with an existing one")
+	ErrRateLimited  = errors.New("YNAB API rate limit reached")
+	ErrServerError  = errors.New("YNAB failed to handle the request")
+)
+
+// ErrCanceled is returned by YNAB API requests aborted because their context was canceled, e.g. because the window was closed or the user canceled, rather than because they failed
+// It wraps context.Canceled, so either may be matched with errors.Is
+var ErrCanceled = fmt.Errorf("YNAB API request canceled: %w", context.Canceled)
+
+// APIError represents an error response of the YNAB API
+// This struct corresponds to the error object defined in the YNAB API documentation, plus the HTTP status code and how long to wait before retrying when rate limited
+type APIError struct {
+	StatusCode int           `json:"-"`
+	Id         string        `json:"id"`
+	Name       string        `json:"name"`
+	Detail     string        `json:"detail"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+// Error describes the YNAB API error by its status code, name and detail
+func (apiError *APIError) Error() string {
+	return fmt.Sprintf("YNAB API error %d %s: %s", apiError.StatusCode, apiError.Name, apiError.Detail)
+}
+
+// Is matches the YNAB API error against the kind of error its status code corresponds to
+func (apiError *APIError) Is(target error) bool {
+	switch {
+	case apiError.StatusCode == http.StatusBadRequest:
+		return target == ErrBadRequest
+	case apiError.StatusCode == http.StatusUnauthorized, apiError.StatusCode == http.StatusForbidden:
+		return target == ErrUnauthorized
+	case apiError.StatusCode == http.StatusNotFound:
+		return target == ErrNotFound
+	case apiError.StatusCode == http.StatusConflict:
+		return target == ErrConflict
+	case apiError.StatusCode == http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case apiError.StatusCode >= http.StatusInternalServerError:
+		return target == ErrServerError
+	}
+
+	return false
+}
+
+// newAPIError parses the error envelope of a YNAB API error response
+// Responses without the envelope, e.g. from a proxy in between, keep their body as the error detail
+func newAPIError(response *resty.Response) *APIError {
+	errorResponse := struct {
+		Error *APIError `json:"error"`
+	}{}
+
+	apiError := &APIError{}
+	if err := json.Unmarshal(response.Body(), &errorResponse); err == nil && errorResponse.Error != nil {
+		apiError = errorResponse.Error
+	} else {
+		apiError.Id = strconv.Itoa(response.StatusCode())
+		apiError.Name = strings.ToLower(strings.ReplaceAll(http.StatusText(response.StatusCode()), " ", "_"))
+		apiError.Detail = strings.TrimSpace(string(response.Body()))
+	}
+
+	apiError.StatusCode = response.StatusCode()
+	apiError.RetryAfter = getRetryAfter(response)
+
+	return apiError
+}
+
+// isUnreachable checks if an import failed because YNAB could not be reached or failed transiently, in which case it may be queued and submitted later
+// A canceled import is not, as it was aborted on purpose
+func isUnreachable(err error) bool {
+	return isNetworkError(err) || errors.Is(err, ErrServerError) || errors.Is(err, ErrRateLimited)
+}
+
+// getRetryAfter reads how long to wait before retrying from the Retry-After header of a response, in seconds, or returns 0 if it is absent
+func getRetryAfter(response *resty.Response) time.Duration {
+	seconds, err := strconv.Atoi(response.Header().Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -2,84 +2,418 @@ package backend
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/forPelevin/gomoji"
 	"github.com/go-resty/resty/v2"
 	"github.com/mitchellh/mapstructure"
+	"github.com/shopspring/decimal"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-// Backend encapsulates the YNAB API client and the shared and individual monthly expenses
+// Backend encapsulates the household configuration, the YNAB budget service, the rounding ledger, the history and the shared and individual monthly expenses
+// Its YNAB API requests share a context that CancelRequests cancels, e.g. when the user gives up on a slow import or the window is closed
+// The state replaced by setup is guarded by stateMutex, as the backend is set up in the background and set up again when the access token changes, while the frontend and the outbox use it
 type Backend struct {
 	Context                 context.Context
-	APIClient               *APIClient
+	Config                  *Config
+	SetupError              error
+	AccessTokenStore        *AccessTokenStore
+	AccessTokenStatus       AccessTokenStatus
+	Diagnostics             *Diagnostics
+	BudgetService           BudgetService
+	BudgetCache             *BudgetCache
+	RateLimiter             *TokenBucket
+	RoundingLedger          *RoundingLedger
+	Outbox                  *Outbox
+	UndoLog                 *UndoLog
+	ImportAttempts          *ImportAttempts
+	History                 *History
+	CurrencyFormats         map[string]CurrencyFormat
 	CombinedMonthlyExpenses *CombinedMonthlyExpenses
+	requestsContext         context.Context
+	cancelRequests          context.CancelFunc
+	requestsMutex           sync.Mutex
+	setupProgress           SetupProgress
+	progressMutex           sync.Mutex
+	setupDone               bool
+	domReady                bool
+	startupMutex            sync.Mutex
+	stateMutex              sync.RWMutex
+	outboxContext           context.Context
+	stopOutbox              context.CancelFunc
+	outboxDone              chan struct{}
+	outboxMutex             sync.Mutex
+	budgetServiceOverride   BudgetService
 }
 
-// SetupBackend creates a new Backend instance
+// SetupBackend creates a new Backend instance from the household configuration
+// The vault holding the YNAB Personal Access Token, if any, is unlocked with the passphrase in the environment variable named by VaultPassphraseEnvironmentVariable
 func SetupBackend() *Backend {
-	var apiClient APIClient
-	apiClient.Client = resty.New()
-	apiClient.Configure()
+	backend := NewBackend()
+	backend.setup(os.Getenv(VaultPassphraseEnvironmentVariable))
+
+	return backend
+}
+
+// NewBackend creates a new Backend instance that is set up in the background once the application starts, so that the window opens right away
+func NewBackend() *Backend {
+	return &Backend{
+		CombinedMonthlyExpenses: &CombinedMonthlyExpenses{
+			SharedMonthlyExpenses: &MonthlyExpenses{Expenses: make(map[string]*MonthlyExpense)},
+		},
+	}
+}
+
+// SetupBackendWithBudgetService creates a new Backend instance from the household configuration that uses the given budget service instead of the YNAB API, e.g. a MemoryBudgetService in tests
+// No access token is needed, and the budgets it serves are cached in memory only
+func SetupBackendWithBudgetService(budgetService BudgetService) *Backend {
+	backend := NewBackend()
+	backend.budgetServiceOverride = budgetService
+	backend.setup("")
+
+	return backend
+}
+
+// setup loads the household configuration, the rounding ledger and the YNAB Personal Access Token, and fetches the YNAB budgets, accounts and categories
+// Every step is reported as a diagnostic check, and setup goes as far as it can, so that the report lists every problem at once
+// It may run again, e.g. once the access token is configured, replacing the previous state while holding stateMutex
+func (backend *Backend) setup(passphrase string) {
+	backend.stateMutex.Lock()
+	defer backend.stateMutex.Unlock()
+
+	// The token bucket outlives the API client, as the requests of previous setups count towards the YNAB API rate limit as well
+	if backend.RateLimiter == nil {
+		backend.RateLimiter = NewTokenBucket(YNABRateLimit, YNABRateLimitInterval)
+	}
+
+	backend.BudgetService = nil
+	backend.SetupError = nil
+	backend.Diagnostics = &Diagnostics{Checks: []DiagnosticCheck{}}
+	backend.CurrencyFormats = make(map[string]CurrencyFormat)
+	backend.CombinedMonthlyExpenses = &CombinedMonthlyExpenses{
+		SharedMonthlyExpenses: &MonthlyExpenses{Expenses: make(map[string]*MonthlyExpense)},
+	}
+
+	diagnostics := backend.Diagnostics
+	defer func() {
+		diagnostics.Ready = backend.SetupError == nil && len(diagnostics.GetFailedChecks()) == 0 && backend.isSetupValid()
+		backend.finishSetupProgress()
+	}()
+
+	backend.startSetupProgress("Loading the configuration")
+
+	configPath := ConfigPath()
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		backend.SetupError = err
+		diagnostics.fail(DiagnosticCheckConfiguration, err.Error(),
+			fmt.Sprintf("Create or fix the configuration file %s, following config.example.yaml, or set its location in the %s environment variable", configPath, ConfigPathEnvironmentVariable))
+		return
+	}
+	backend.Config = config
+	diagnostics.pass(DiagnosticCheckConfiguration, "Configuration %s loaded", configPath)
+
+	// Loading the rounding ledger, the outbox and the access token, syncing each budget and checking them all
+	backend.setSetupProgressTotal(4 + len(config.GetBudgetNames()) + 1)
+	backend.reportSetupProgress("Configuration loaded")
+
+	roundingLedgerPath, err := RoundingLedgerPath()
+	if err == nil {
+		backend.RoundingLedger, err = LoadRoundingLedger(roundingLedgerPath)
+	}
+	if err == nil {
+		backend.CombinedMonthlyExpenses.RoundingStrategy, err = RoundingStrategyFromConfig(config.Rounding, backend.RoundingLedger)
+	}
+	if err != nil {
+		backend.SetupError = err
+		diagnostics.fail(DiagnosticCheckRounding, err.Error(), "Fix or remove the rounding ledger file, or declare another rounding strategy in the configuration")
+		return
+	}
+	diagnostics.pass(DiagnosticCheckRounding, "Rounding ledger loaded")
+	backend.reportSetupProgress("Rounding ledger loaded")
 
-	budgets, _ := apiClient.GetBudgets()
+	outboxPath, err := OutboxPath()
+	if err == nil {
+		backend.Outbox, err = LoadOutbox(outboxPath)
+	}
+	if err != nil {
+		backend.SetupError = err
+		diagnostics.fail(DiagnosticCheckOutbox, err.Error(), "Fix the outbox file, or remove it to discard the imports queued in it")
+		return
+	}
+	diagnostics.pass(DiagnosticCheckOutbox, "%d imports waiting for YNAB", len(backend.Outbox.GetImports()))
+	backend.reportSetupProgress("Outbox loaded")
 
-	sharedBudget := budgets.GetBudget(SharedBudgetName)
-	sharedMonthlyExpensesAccount := sharedBudget.Accounts.GetMonthlyExpensesAccount(SharedMonthlyExpensesAccountName)
-	sharedCategories, _ := apiClient.GetCategories(sharedBudget.Id)
+	backend.loadUndoLog()
+	backend.loadImportAttempts()
+	backend.loadHistory()
 
-	individualBudget := budgets.GetBudget(IndividualBudgetName)
-	individualMonthlyExpensesAccount := individualBudget.Accounts.GetMonthlyExpensesAccount(IndividualMonthlyExpensesAccountName)
-	individualCategories, _ := apiClient.GetCategories(individualBudget.Id)
+	if backend.budgetServiceOverride == nil && os.Getenv(DemoEnvironmentVariable) != "" {
+		backend.budgetServiceOverride = NewMemoryBudgetServiceFromConfig(config)
+	}
+
+	var budgets Budgets
+	if backend.budgetServiceOverride != nil {
+		diagnostics.pass(DiagnosticCheckAccessToken, "No access token needed by the %T budget service", backend.budgetServiceOverride)
+		backend.reportSetupProgress("Access token loaded")
+		backend.BudgetService = backend.budgetServiceOverride
+		backend.BudgetCache = &BudgetCache{Details: make(map[string]*CachedBudget)}
+		budgets = backend.checkYNAB()
+	} else {
+		budgets = backend.setupAPIClient(passphrase)
+	}
 
-	sharedMonthlyExpenses := MonthlyExpenses{
-		BudgetId:  sharedBudget.Id,
-		AccountId: sharedMonthlyExpensesAccount.Id,
-		Expenses:  make(map[string]*MonthlyExpense),
+	for _, budget := range budgets {
+		backend.CurrencyFormats[budget.Id] = budget.CurrencyFormat
 	}
 
-	individualMonthlyExpenses := MonthlyExpenses{
-		BudgetId:  individualBudget.Id,
-		AccountId: individualMonthlyExpensesAccount.Id,
-		Expenses:  make(map[string]*MonthlyExpense),
+	sharedBudget, sharedMonthlyExpensesAccount, sharedCategoryIds := BudgetSummary{}, Account{}, map[string]*string{}
+	if budgets != nil {
+		sharedBudget, sharedMonthlyExpensesAccount, sharedCategoryIds = backend.checkMonthlyExpensesBudget("Shared", budgets, config.Shared.Budget, config.Shared.Account)
 	}
 
-	for _, category := range sharedCategories.GetMonthlyExpensesCategories() {
-		categoryName := gomoji.RemoveEmojis(category.Name)
+	sharedMonthlyExpenses := backend.CombinedMonthlyExpenses.SharedMonthlyExpenses
+	sharedMonthlyExpenses.BudgetId = sharedBudget.Id
+	sharedMonthlyExpenses.AccountId = sharedMonthlyExpensesAccount.Id
 
-		sharedMonthlyExpenses.Expenses[categoryName] = &MonthlyExpense{
-			CategoryId: to.StringPtr(category.Id),
-			PayeeName:  to.StringPtr(GetSharedMonthlyExpensePayeeName(categoryName)),
-			Memo:       to.StringPtr(GetSharedMonthlyExpenseMemo(categoryName)),
+	for _, expense := range config.Categories.Expenses {
+		sharedMonthlyExpenses.Expenses[expense.Name] = &MonthlyExpense{
+			CategoryId: sharedCategoryIds[expense.Name],
+			PayeeName:  to.StringPtr(expense.Payee),
+			Memo:       to.StringPtr(GetSharedMonthlyExpenseMemo(expense.Memo)),
+			SplitRule:  SplitRuleFromConfig(expense.Split, config.Participants),
 		}
 	}
 
-	for _, category := range individualCategories.GetMonthlyExpensesCategories() {
-		categoryName := gomoji.RemoveEmojis(category.Name)
+	for _, participant := range config.Participants {
+		individualMonthlyExpenses := &MonthlyExpenses{
+			ParticipantName: participant.Name,
+			Expenses:        make(map[string]*MonthlyExpense),
+		}
+
+		individualCategoryIds := make(map[string]*string)
+
+		if participant.Budget != "" && budgets != nil {
+			var individualBudget BudgetSummary
+			var individualMonthlyExpensesAccount Account
+
+			individualBudget, individualMonthlyExpensesAccount, individualCategoryIds = backend.checkMonthlyExpensesBudget(
+				fmt.Sprintf("%s's", participant.Name), budgets, participant.Budget, participant.Account)
+
+			individualMonthlyExpenses.BudgetId = individualBudget.Id
+			individualMonthlyExpenses.AccountId = individualMonthlyExpensesAccount.Id
+		}
 
-		individualMonthlyExpenses.Expenses[categoryName] = &MonthlyExpense{
-			CategoryId: to.StringPtr(category.Id),
-			PayeeName:  to.StringPtr(GetIndividualMonthlyExpensePayeeName(SharedBudgetName)),
-			Memo:       to.StringPtr(GetIndividualMonthlyExpenseMemo()),
+		for _, expense := range config.Categories.Expenses {
+			individualMonthlyExpenses.Expenses[expense.Name] = &MonthlyExpense{
+				CategoryId: individualCategoryIds[expense.Name],
+				PayeeName:  to.StringPtr(GetIndividualMonthlyExpensePayeeName(config.Shared.Budget)),
+				Memo:       to.StringPtr(GetIndividualMonthlyExpenseMemo()),
+			}
 		}
+
+		backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses = append(backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses, individualMonthlyExpenses)
 	}
+}
 
-	return &Backend{
-		APIClient: &apiClient,
-		CombinedMonthlyExpenses: &CombinedMonthlyExpenses{
-			SharedMonthlyExpenses:     &sharedMonthlyExpenses,
-			IndividualMonthlyExpenses: &individualMonthlyExpenses,
-		},
+// setupAPIClient loads the YNAB access token and sets up the APIClient with it, returning the YNAB budgets fetched with it, or nil if they could not be fetched
+// Without an access token the monthly expenses can still be split, but no YNAB budgets, accounts or categories are fetched
+func (backend *Backend) setupAPIClient(passphrase string) Budgets {
+	diagnostics := backend.Diagnostics
+	config := backend.Config
+
+	if backend.AccessTokenStore == nil {
+		accessTokenStore, err := DefaultAccessTokenStore()
+		if err != nil {
+			backend.SetupError = err
+			diagnostics.fail(DiagnosticCheckAccessToken, err.Error(), fmt.Sprintf("Set the %s environment variable", AccessTokenEnvironmentVariable))
+			return nil
+		}
+		backend.AccessTokenStore = accessTokenStore
+	}
+
+	tokenSource, accessTokenSource, err := backend.AccessTokenStore.LoadTokenSource(passphrase, config.OAuth)
+	backend.AccessTokenStatus = AccessTokenStatus{
+		Configured:     err == nil || errors.Is(err, ErrVaultLocked) || errors.Is(err, ErrVaultPassphrase),
+		Source:         accessTokenSource,
+		Locked:         errors.Is(err, ErrVaultLocked) || errors.Is(err, ErrVaultPassphrase),
+		OAuthAvailable: config.OAuth.ClientId != "",
+	}
+	if err != nil {
+		backend.AccessTokenStatus.Error = err.Error()
+		backend.SetupError = err
+		diagnostics.fail(DiagnosticCheckAccessToken, err.Error(), getAccessTokenRemediation(err))
+		diagnostics.skip(DiagnosticCheckNetwork, "No access token to connect to YNAB with")
+		diagnostics.skip(DiagnosticCheckAuthorization, "No access token to connect to YNAB with")
+		return nil
+	}
+	diagnostics.pass(DiagnosticCheckAccessToken, "Access token found in the %s", accessTokenSource)
+	backend.reportSetupProgress("Access token loaded")
+
+	apiClient := &APIClient{Client: resty.New(), RateLimiter: backend.RateLimiter}
+	if err = apiClient.Configure(tokenSource, config.YNAB); err != nil {
+		backend.SetupError = err
+		diagnostics.fail(DiagnosticCheckNetwork, err.Error(), "Fix the ynab section of the configuration, e.g. the path of the CA bundle")
+		diagnostics.skip(DiagnosticCheckAuthorization, "Cannot connect to YNAB")
+		return nil
+	}
+	backend.BudgetService = apiClient
+
+	backend.loadBudgetCache()
+
+	return backend.checkYNAB()
+}
+
+// loadBudgetCache loads the YNAB budget cache, starting from an empty one if it cannot be read, as it is synced with YNAB anyway
+func (backend *Backend) loadBudgetCache() {
+	cachePath, err := BudgetCachePath()
+	if err == nil {
+		backend.BudgetCache, err = LoadBudgetCache(cachePath)
+	} else {
+		backend.BudgetCache = &BudgetCache{Details: make(map[string]*CachedBudget)}
+	}
+
+	if err != nil {
+		backend.logErrorf("Error loading the YNAB cache: %v", err)
 	}
 }
 
-// Startup sets the backend context and registers an event handler to listen for the "sharedMonthlyExpensesInput" event
-// When this event occurs the individual share for each monthly expense category is calculated and then the "sharedMonthlyExpensesSplit" event is emitted
+// loadUndoLog loads the undo log, starting from an empty one if it cannot be read, as only undoing the last import depends on it
+func (backend *Backend) loadUndoLog() {
+	undoLogPath, err := UndoLogPath()
+	if err != nil {
+		backend.UndoLog = nil
+		backend.logErrorf("Error locating the undo log: %v", err)
+		return
+	}
+
+	if backend.UndoLog, err = LoadUndoLog(undoLogPath); err != nil {
+		backend.UndoLog = &UndoLog{Path: undoLogPath}
+		backend.logErrorf("Error loading the undo log: %v", err)
+	}
+}
+
+// loadImportAttempts loads the attempt of the import ids of each month, starting from none if it cannot be read, as the import ids of the first attempt are only reused if an import was deleted before
+func (backend *Backend) loadImportAttempts() {
+	importAttemptsPath, err := ImportAttemptsPath()
+	if err != nil {
+		backend.ImportAttempts = nil
+		backend.logErrorf("Error locating the import attempts: %v", err)
+		return
+	}
+
+	if backend.ImportAttempts, err = LoadImportAttempts(importAttemptsPath); err != nil {
+		backend.ImportAttempts = &ImportAttempts{Path: importAttemptsPath, Months: make(map[string]int)}
+		backend.logErrorf("Error loading the import attempts: %v", err)
+	}
+}
+
+// loadHistory locates the history, which is only opened when a split or an import is recorded or the history is read
+func (backend *Backend) loadHistory() {
+	historyPath, err := HistoryPath()
+	if err != nil {
+		backend.History = nil
+		backend.logErrorf("Error locating the history: %v", err)
+		return
+	}
+
+	backend.History = &History{Path: historyPath}
+}
+
+// checkYNAB checks that YNAB is reachable and accepts the access token while syncing the YNAB budget cache, returning the YNAB budgets, or nil if they could not be fetched
+// When YNAB cannot be reached but budgets were cached before, the cached budgets are returned and the network check only warns about them being stale
+func (backend *Backend) checkYNAB() Budgets {
+	diagnostics := backend.Diagnostics
+	cache := backend.BudgetCache
+
+	err := cache.Sync(backend.requestContext(), backend.BudgetService, backend.Config.GetBudgetNames(), func(budgetName string) {
+		backend.reportSetupProgress(fmt.Sprintf("Budget %s synced", budgetName))
+	})
+	if err == nil || cache.Stale {
+		if saveErr := cache.Save(); saveErr != nil {
+			backend.logErrorf("Error saving the YNAB cache: %v", saveErr)
+		}
+	}
+
+	switch {
+	case err == nil:
+		diagnostics.pass(DiagnosticCheckNetwork, "YNAB reached")
+		diagnostics.pass(DiagnosticCheckAuthorization, "YNAB accepted the access token")
+		diagnostics.pass(DiagnosticCheckBudgets, "%d budgets synced", len(cache.Budgets))
+		return cache.GetBudgets()
+	case cache.Stale:
+		diagnostics.warn(DiagnosticCheckNetwork, fmt.Sprintf("YNAB could not be synced, so its budgets, accounts and categories are as of %s: %v", cache.SyncedAt.Local().Format(time.DateTime), err),
+			"The monthly expenses can still be split and planned. Check the internet connection, then restart the application to sync with YNAB")
+		diagnostics.skip(DiagnosticCheckAuthorization, "YNAB could not be synced")
+		diagnostics.pass(DiagnosticCheckBudgets, "%d budgets loaded from the cache", len(cache.Budgets))
+		return cache.GetBudgets()
+	case errors.Is(err, ErrCanceled):
+		diagnostics.fail(DiagnosticCheckNetwork, "Syncing with YNAB was canceled", "Restart the application to sync with YNAB")
+		diagnostics.skip(DiagnosticCheckAuthorization, "Syncing with YNAB was canceled")
+		return nil
+	case isNetworkError(err):
+		diagnostics.fail(DiagnosticCheckNetwork, fmt.Sprintf("YNAB could not be reached: %v", err), "Check the internet connection, then restart the application")
+		diagnostics.skip(DiagnosticCheckAuthorization, "YNAB could not be reached")
+		return nil
+	case isTLSError(err):
+		diagnostics.fail(DiagnosticCheckNetwork, fmt.Sprintf("No secure connection could be established with YNAB: %v", err),
+			"Check the proxy and the base URL declared under ynab in the configuration, and the system's trusted certificates")
+		diagnostics.skip(DiagnosticCheckAuthorization, "No secure connection could be established with YNAB")
+		return nil
+	}
+
+	diagnostics.pass(DiagnosticCheckNetwork, "YNAB reached")
+
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		diagnostics.fail(DiagnosticCheckAuthorization, fmt.Sprintf("YNAB rejected the access token: %v", err),
+			"Create a new YNAB Personal Access Token under Developer Settings in YNAB and enter it again, or sign in with YNAB again")
+	case errors.Is(err, ErrRateLimited):
+		diagnostics.fail(DiagnosticCheckAuthorization, fmt.Sprintf("The YNAB API rate limit was reached: %v", err),
+			"Wait for up to an hour, then restart the application")
+	default:
+		diagnostics.fail(DiagnosticCheckAuthorization, fmt.Sprintf("Fetching the YNAB budgets failed: %v", err), "Try again later")
+	}
+
+	return nil
+}
+
+// getCategoryIdsByName maps the name of each YNAB category, without emojis, to its id
+func getCategoryIdsByName(categories []Category) map[string]*string {
+	categoryIds := make(map[string]*string, len(categories))
+
+	for _, category := range categories {
+		categoryIds[strings.TrimSpace(gomoji.RemoveEmojis(category.Name))] = to.StringPtr(category.Id)
+	}
+
+	return categoryIds
+}
+
+// Startup sets the backend context, sets up the backend in the background and registers an event handler to listen for the "sharedMonthlyExpensesInput" event
+// While the backend is set up the "setupProgress" event is emitted after each step, and once it is set up the queued imports are submitted until the application is closed
+// When the "sharedMonthlyExpensesInput" event occurs the individual share for each monthly expense category is calculated and then the "sharedMonthlyExpensesSplit" event is emitted
+// If the shared monthly expenses cannot be split according to their split rules, the "sharedMonthlyExpensesSplitFailed" event is emitted instead
 func (backend *Backend) Startup(context context.Context) {
 	backend.Context = context
 
+	go func() {
+		backend.setup(os.Getenv(VaultPassphraseEnvironmentVariable))
+		backend.completeStartup(func() { backend.setupDone = true })
+		backend.startOutbox(context)
+	}()
+
 	runtime.EventsOn(context, "sharedMonthlyExpensesInput", func(args ...interface{}) {
+		backend.stateMutex.Lock()
+		defer backend.stateMutex.Unlock()
+
 		decoderConfig := &mapstructure.DecoderConfig{
 			DecodeHook: mapstructure.ComposeDecodeHookFunc(
 				StringToDecimalHookFunc(),
@@ -89,27 +423,829 @@ func (backend *Backend) Startup(context context.Context) {
 		decoder, _ := mapstructure.NewDecoder(decoderConfig)
 		decoder.Decode(args[0])
 
-		backend.CombinedMonthlyExpenses.SplitSharedMonthlyExpenses()
+		if err := backend.CombinedMonthlyExpenses.SplitSharedMonthlyExpenses(); err != nil {
+			runtime.EventsEmit(context, "sharedMonthlyExpensesSplitFailed", err.Error())
+			return
+		}
+		backend.recordSplit()
 
 		runtime.EventsEmit(context, "sharedMonthlyExpensesSplit", backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses)
 	})
 }
 
-// DomReady emits the "backendSetupComplete" event indicating if both the shared and individual monthly expenses are valid as that is a requirement for the application
-func (backend *Backend) DomReady(context context.Context) {
-	runtime.EventsEmit(context, "backendSetupComplete",
-		backend.CombinedMonthlyExpenses.SharedMonthlyExpenses.IsValid() &&
-			backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses.IsValid(),
-	)
+// DomReady emits the "backendSetupComplete" event with the startup diagnostics, once the backend is set up as well
+func (backend *Backend) DomReady(_ context.Context) {
+	backend.completeStartup(func() { backend.domReady = true })
+}
+
+// completeStartup records that the backend is set up or that the DOM is ready, and once both are, emits the "backendSetupComplete" event with the startup diagnostics
+// The diagnostics tell if the application is ready to import the monthly expenses and what to do about every failed check otherwise, and every failed check is logged as well
+func (backend *Backend) completeStartup(record func()) {
+	backend.startupMutex.Lock()
+	record()
+	complete := backend.setupDone && backend.domReady
+	backend.startupMutex.Unlock()
+
+	if !complete {
+		return
+	}
+
+	for _, check := range backend.GetDiagnostics().GetFailedChecks() {
+		backend.logErrorf("%s: %s", check.Name, check.Message)
+	}
+
+	backend.emitSetupComplete()
+}
+
+// Shutdown aborts the YNAB API requests in flight when the application is closed
+func (backend *Backend) Shutdown(_ context.Context) {
+	backend.CancelRequests()
+}
+
+// startOutbox submits the queued imports in the background, as RunOutbox does, until stopped with stopOutboxAndWait or the given context is done
+// The context is remembered, so that restartOutbox can start it again with the same one
+func (backend *Backend) startOutbox(ctx context.Context) {
+	backend.outboxMutex.Lock()
+	defer backend.outboxMutex.Unlock()
+
+	if backend.stopOutbox != nil {
+		return
+	}
+
+	backend.outboxContext = ctx
+	outboxContext, stopOutbox := context.WithCancel(ctx)
+	outboxDone := make(chan struct{})
+	backend.stopOutbox, backend.outboxDone = stopOutbox, outboxDone
+
+	go func() {
+		defer close(outboxDone)
+		backend.RunOutbox(outboxContext)
+	}()
+}
+
+// stopOutboxAndWait stops submitting the queued imports in the background, waiting for a submission in progress to finish, and returns the context it was started with, if it was started
+func (backend *Backend) stopOutboxAndWait() context.Context {
+	backend.outboxMutex.Lock()
+	defer backend.outboxMutex.Unlock()
+
+	if backend.stopOutbox == nil {
+		return nil
+	}
+
+	backend.stopOutbox()
+	<-backend.outboxDone
+	backend.stopOutbox, backend.outboxDone = nil, nil
+
+	return backend.outboxContext
+}
+
+// setupAgain sets up the backend again, e.g. with a new access token
+// The queued imports are not submitted in the background meanwhile, so that they are never submitted with the state being replaced
+func (backend *Backend) setupAgain(passphrase string) {
+	outboxContext := backend.stopOutboxAndWait()
+
+	backend.setup(passphrase)
+
+	if outboxContext != nil {
+		backend.startOutbox(outboxContext)
+	}
+}
+
+// requestContext returns the context of the YNAB API requests, derived from the backend context, if any, until CancelRequests cancels it
+func (backend *Backend) requestContext() context.Context {
+	backend.requestsMutex.Lock()
+	defer backend.requestsMutex.Unlock()
+
+	if backend.requestsContext == nil {
+		parent := backend.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		backend.requestsContext, backend.cancelRequests = context.WithCancel(parent)
+	}
+
+	return backend.requestsContext
+}
+
+// CancelRequests aborts the YNAB API requests in flight, e.g. an import or a sync the user gave up on, which fail with ErrCanceled
+// Requests sent afterwards are not affected
+func (backend *Backend) CancelRequests() {
+	backend.requestsMutex.Lock()
+	defer backend.requestsMutex.Unlock()
+
+	if backend.cancelRequests != nil {
+		backend.cancelRequests()
+	}
+
+	backend.requestsContext, backend.cancelRequests = nil, nil
+}
+
+// emitSetupComplete emits the "backendSetupComplete" event with the startup diagnostics
+func (backend *Backend) emitSetupComplete() {
+	if backend.Context == nil {
+		return
+	}
+
+	runtime.EventsEmit(backend.Context, "backendSetupComplete", backend.GetDiagnostics())
+}
+
+// GetDiagnostics returns the report of the checks run while setting up the backend
+func (backend *Backend) GetDiagnostics() *Diagnostics {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	return backend.Diagnostics
+}
+
+// IsSetupValid checks if the shared monthly expenses and the individual monthly expenses of every participant declaring a YNAB budget are valid
+func (backend *Backend) IsSetupValid() bool {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	return backend.isSetupValid()
+}
+
+// isSetupValid checks if the setup is valid, as IsSetupValid does, while stateMutex is held
+func (backend *Backend) isSetupValid() bool {
+	if backend.Config == nil || !backend.CombinedMonthlyExpenses.SharedMonthlyExpenses.IsValid() {
+		return false
+	}
+
+	for index, participant := range backend.Config.Participants {
+		if participant.Budget != "" && !backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses[index].IsValid() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetAccessTokenStatus returns where the YNAB Personal Access Token is configured, if anywhere, and whether the vault holding it still has to be unlocked
+func (backend *Backend) GetAccessTokenStatus() AccessTokenStatus {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	return backend.AccessTokenStatus
+}
+
+// SaveAccessToken validates a YNAB Personal Access Token against the YNAB API and saves it, encrypted in the vault if a passphrase is given
+// Once saved, the backend is set up again with the new access token and the "backendSetupComplete" event is emitted
+func (backend *Backend) SaveAccessToken(accessToken string, passphrase string) error {
+	backend.stateMutex.RLock()
+	accessTokenStatus, accessTokenStore := backend.AccessTokenStatus, backend.AccessTokenStore
+	var ynabConfig YNABConfig
+	if backend.Config != nil {
+		ynabConfig = backend.Config.YNAB
+	}
+	backend.stateMutex.RUnlock()
+
+	switch {
+	case accessTokenStatus.Source == AccessTokenSourceEnvironment:
+		return fmt.Errorf("the access token is set in the %s environment variable, which takes precedence over a saved one", AccessTokenEnvironmentVariable)
+	case accessTokenStore == nil:
+		return ErrNoAccessTokenStore
+	}
+
+	var apiClient APIClient
+	apiClient.Client = resty.New()
+	apiClient.RateLimiter = backend.RateLimiter
+	if err := apiClient.Configure(StaticTokenSource(strings.TrimSpace(accessToken)), ynabConfig); err != nil {
+		return fmt.Errorf("validating access token: %w", err)
+	}
+
+	if _, err := apiClient.GetUser(backend.requestContext()); err != nil {
+		return fmt.Errorf("validating access token: %w", err)
+	}
+
+	if err := accessTokenStore.Save(accessToken, passphrase); err != nil {
+		return err
+	}
+
+	backend.setupAgain(passphrase)
+	backend.emitSetupComplete()
+
+	return nil
+}
+
+// AuthorizeWithYNAB authorizes a YNAB login with the OAuth application declared in the household configuration, opening the authorization page in the browser
+// Once authorized, the OAuth tokens are saved, the backend is set up again with them and the "backendSetupComplete" event is emitted
+func (backend *Backend) AuthorizeWithYNAB() error {
+	backend.stateMutex.RLock()
+	config, accessTokenStore := backend.Config, backend.AccessTokenStore
+	backend.stateMutex.RUnlock()
+
+	switch {
+	case config == nil || config.OAuth.ClientId == "":
+		return errors.New("no YNAB OAuth application is declared in the configuration")
+	case accessTokenStore == nil:
+		return ErrNoAccessTokenStore
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), OAuthAuthorizationTimeout)
+	defer cancel()
+
+	token, err := NewOAuthClient(config.OAuth).Authorize(ctx, func(authorizationURL string) error {
+		runtime.BrowserOpenURL(backend.Context, authorizationURL)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = SaveOAuthToken(accessTokenStore.OAuthTokenFilePath, token); err != nil {
+		return err
+	}
+
+	backend.setupAgain("")
+	backend.emitSetupComplete()
+
+	return nil
+}
+
+// UnlockAccessTokenVault unlocks the vault holding the YNAB Personal Access Token with its passphrase
+// Once unlocked, the backend is set up again with the access token and the "backendSetupComplete" event is emitted
+func (backend *Backend) UnlockAccessTokenVault(passphrase string) error {
+	backend.setupAgain(passphrase)
+
+	if accessTokenStatus := backend.GetAccessTokenStatus(); accessTokenStatus.Locked {
+		return errors.New(accessTokenStatus.Error)
+	}
+
+	backend.emitSetupComplete()
+
+	return nil
+}
+
+// GetRateLimitStatus returns how many YNAB API requests may still be sent within the YNAB API rate limit
+func (backend *Backend) GetRateLimitStatus() RateLimitStatus {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.RateLimiter == nil {
+		return RateLimitStatus{Limit: YNABRateLimit, Remaining: YNABRateLimit}
+	}
+
+	return backend.RateLimiter.GetStatus()
+}
+
+// GetParticipantNames returns the names of the participants in the order declared in the household configuration
+func (backend *Backend) GetParticipantNames() []string {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.Config == nil {
+		return []string{}
+	}
+
+	return backend.Config.GetParticipantNames()
+}
+
+// GetCategoryNames returns the names of the monthly expenses categories in the order declared in the household configuration
+func (backend *Backend) GetCategoryNames() []string {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.Config == nil {
+		return []string{}
+	}
+
+	return backend.Config.GetCategoryNames()
 }
 
-// GetSharedMonthlyExpenses returns the shared monthly expenses
+// GetSharedMonthlyExpenses returns the shared monthly expenses, without any category until the backend is set up
 func (backend *Backend) GetSharedMonthlyExpenses() *MonthlyExpenses {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
 	return backend.CombinedMonthlyExpenses.SharedMonthlyExpenses
 }
 
-// CreateMonthlyExpensesTransactions creates YNAB transactions for the shared and individual monthly expenses
-func (backend *Backend) CreateMonthlyExpensesTransactions(combinedMonthlyExpenses *CombinedMonthlyExpenses) bool {
-	return combinedMonthlyExpenses.CreateSharedMonthlyExpensesTransactions(*backend.APIClient) &&
-		combinedMonthlyExpenses.CreateIndividualMonthlyExpensesTransactions(*backend.APIClient)
+// SplitMonthlyExpenses sets the amount of each shared monthly expense category and splits them among the participants
+// An amount is required for every category declared in the household configuration, and only for those
+func (backend *Backend) SplitMonthlyExpenses(amounts map[string]decimal.Decimal) (*CombinedMonthlyExpenses, error) {
+	backend.stateMutex.Lock()
+	defer backend.stateMutex.Unlock()
+
+	sharedMonthlyExpenses := backend.CombinedMonthlyExpenses.SharedMonthlyExpenses
+
+	for categoryName := range amounts {
+		if _, ok := sharedMonthlyExpenses.Expenses[categoryName]; !ok {
+			return nil, fmt.Errorf("category %q is not declared in the configuration", categoryName)
+		}
+	}
+
+	for categoryName, monthlyExpense := range sharedMonthlyExpenses.Expenses {
+		amount, ok := amounts[categoryName]
+		if !ok {
+			return nil, fmt.Errorf("missing amount for category %q", categoryName)
+		}
+		monthlyExpense.Amount = amount
+	}
+
+	if err := backend.CombinedMonthlyExpenses.SplitSharedMonthlyExpenses(); err != nil {
+		return nil, err
+	}
+	backend.recordSplit()
+
+	return backend.CombinedMonthlyExpenses, nil
+}
+
+// GetImportPlan builds the YNAB transactions that importing the monthly expenses of the current month would create, without sending anything to YNAB
+// The monthly expenses are refused with ErrSplitMismatch unless their individual shares were split from their shared amounts, e.g. after changing an amount without splitting them again
+func (backend *Backend) GetImportPlan(combinedMonthlyExpenses *CombinedMonthlyExpenses) (ImportPlan, error) {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if err := backend.checkSplit(combinedMonthlyExpenses); err != nil {
+		return ImportPlan{}, err
+	}
+
+	month := time.Now().Format("2006-01")
+
+	importPlan := combinedMonthlyExpenses.GetImportPlan(month).WithAttempt(backend.getImportAttempt(month))
+	importPlan.FormatAmounts(backend.CurrencyFormats)
+
+	return importPlan, nil
+}
+
+// checkSplit checks that the individual shares of the monthly expenses were split from their shared amounts according to the participants and split rules declared in the configuration, while stateMutex is held
+func (backend *Backend) checkSplit(combinedMonthlyExpenses *CombinedMonthlyExpenses) error {
+	if combinedMonthlyExpenses == nil {
+		return fmt.Errorf("%w: no monthly expenses", ErrSplitMismatch)
+	}
+
+	participantNames := make([]string, 0, len(backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses))
+	for _, monthlyExpenses := range backend.CombinedMonthlyExpenses.IndividualMonthlyExpenses {
+		participantNames = append(participantNames, monthlyExpenses.ParticipantName)
+	}
+
+	splitRules := make(map[string]*SplitRule, len(backend.CombinedMonthlyExpenses.SharedMonthlyExpenses.Expenses))
+	for categoryName, monthlyExpense := range backend.CombinedMonthlyExpenses.SharedMonthlyExpenses.Expenses {
+		splitRules[categoryName] = monthlyExpense.SplitRule
+	}
+
+	return combinedMonthlyExpenses.CheckSplit(participantNames, splitRules)
+}
+
+// CreateMonthlyExpensesTransactions creates the YNAB transactions for the shared and individual monthly expenses of the current month
+// Transactions already created for the month are reported instead of being duplicated, so the import can safely be retried
+// Without a duplicate resolution, nothing is imported if this month's expenses appear to be already entered in YNAB by other means, e.g. by hand, and the possible duplicates are returned instead
+// The possible duplicates are left out when skipping them, deleted once the import succeeds when replacing them, or kept alongside the imported transactions when proceeding
+// Replacing is refused, without importing anything, if any of the existing transactions to delete was reconciled in YNAB
+// If the import fails in any budget, the transactions already created in the other budgets are rolled back, and the month moves on to the next attempt of its import ids so that importing it again creates them anew
+// If YNAB cannot be reached, the import is queued in the outbox instead, and submitted once YNAB is reachable again, unless the rollback left transactions behind, which must be deleted by hand first
+// If the import is canceled with CancelRequests, the transactions already created are rolled back and the import is not queued
+// Once the transactions are created, the rounding of the individual shares is recorded in the rounding ledger
+// Nothing is imported unless the individual shares were split from the shared amounts, as GetImportPlan checks
+func (backend *Backend) CreateMonthlyExpensesTransactions(combinedMonthlyExpenses *CombinedMonthlyExpenses, duplicateResolution string) ImportResult {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	switch {
+	case backend.BudgetService == nil:
+		return ImportResult{Error: fmt.Sprintf("not connected to YNAB: %v", backend.SetupError)}
+	case !IsValidDuplicateResolution(duplicateResolution):
+		return ImportResult{Error: fmt.Sprintf("unknown duplicate resolution %q", duplicateResolution)}
+	}
+	if err := backend.checkSplit(combinedMonthlyExpenses); err != nil {
+		return ImportResult{Error: err.Error(), Budgets: []BudgetImportResult{}}
+	}
+
+	month := time.Now().Format("2006-01")
+
+	importPlan := combinedMonthlyExpenses.GetImportPlan(month).WithAttempt(backend.getImportAttempt(month))
+
+	var possibleDuplicates []PossibleDuplicate
+	if duplicateResolution != DuplicateResolutionProceed {
+		var err error
+		possibleDuplicates, err = importPlan.FindPossibleDuplicates(backend.requestContext(), backend.BudgetService)
+
+		switch {
+		// Without a duplicate resolution, the import goes on when YNAB cannot be reached, so that it is queued like any other
+		case err != nil && duplicateResolution == "" && isUnreachable(err):
+			backend.logErrorf("finding possible duplicates: %v", err)
+		case err != nil:
+			backend.logErrorf("finding possible duplicates: %v", err)
+			return ImportResult{Error: fmt.Sprintf("finding possible duplicates: %v", err), Canceled: errors.Is(err, context.Canceled)}
+		case duplicateResolution == "" && len(possibleDuplicates) > 0:
+			formatPossibleDuplicateAmounts(possibleDuplicates, backend.CurrencyFormats)
+			return ImportResult{Error: ErrPossibleDuplicates.Error(), Budgets: []BudgetImportResult{}, PossibleDuplicates: possibleDuplicates}
+		}
+	}
+	// Replacing is refused up front, before importing anything, as reconciled transactions must not be deleted
+	if problems := getReplaceProblems(possibleDuplicates); duplicateResolution == DuplicateResolutionReplace && len(problems) > 0 {
+		return ImportResult{Error: fmt.Sprintf("%s: %s", ErrReplaceRefused.Error(), strings.Join(problems, ", ")), Budgets: []BudgetImportResult{}}
+	}
+	if duplicateResolution == DuplicateResolutionSkip {
+		importPlan = importPlan.WithoutPossibleDuplicates(possibleDuplicates)
+	}
+
+	importResult, err := importPlan.Execute(backend.requestContext(), backend.BudgetService)
+	switch {
+	case importResult.isFullyRolledBack():
+		importPlan = importPlan.WithAttempt(backend.nextImportAttempt(month, importPlan.Attempt))
+	case importResult.hasLeftoverTransactions():
+		// Queueing the import would create the transactions left behind once more, so they are left to be deleted by hand instead
+		importResult.Error = fmt.Sprintf("%s: %s", ErrRollbackIncomplete.Error(), importResult.Error)
+	}
+	if err != nil && isUnreachable(err) && !importResult.hasLeftoverTransactions() && backend.Outbox != nil {
+		if queueErr := backend.queueImport(combinedMonthlyExpenses, importPlan, duplicateResolution); queueErr != nil {
+			backend.logErrorf("queueing import: %v", queueErr)
+		} else {
+			importResult.Queued = true
+		}
+	}
+	if !importResult.Success {
+		backend.logErrorf("importing monthly expenses: %s", importResult.Error)
+		backend.recordImportHistory(combinedMonthlyExpenses, month, importResult)
+		return importResult
+	}
+
+	switch duplicateResolution {
+	case DuplicateResolutionSkip:
+		importResult.skipPossibleDuplicates(possibleDuplicates)
+	case DuplicateResolutionReplace:
+		// The import already succeeded, so the existing transactions left behind are only reported, as the imported ones are in place either way
+		if err := importResult.replacePossibleDuplicates(context.WithoutCancel(backend.requestContext()), backend.BudgetService, possibleDuplicates); err != nil {
+			backend.logErrorf("replacing possible duplicates: %v", err)
+		}
+	}
+
+	backend.recordImport(month, importResult)
+	backend.recordImportHistory(combinedMonthlyExpenses, month, importResult)
+
+	if err := backend.recordRounding(combinedMonthlyExpenses, month); err != nil {
+		backend.logErrorf("recording rounding: %v", err)
+	}
+
+	return importResult
+}
+
+// getImportAttempt returns the attempt of the import ids of a month
+func (backend *Backend) getImportAttempt(month string) int {
+	if backend.ImportAttempts == nil {
+		return 0
+	}
+
+	return backend.ImportAttempts.Get(month)
+}
+
+// nextImportAttempt moves a month on from the given attempt of its import ids once the transactions of an import of the month were deleted, and returns the next one
+func (backend *Backend) nextImportAttempt(month string, attempt int) int {
+	if backend.ImportAttempts == nil {
+		return attempt + 1
+	}
+
+	attempt, err := backend.ImportAttempts.Next(month)
+	if err != nil {
+		backend.logErrorf("saving import attempts: %v", err)
+	}
+
+	return attempt
+}
+
+// advanceImportAttempt moves a month on to the attempt of the import ids of a queued import of the month, unless it is past it already
+func (backend *Backend) advanceImportAttempt(month string, attempt int) {
+	if backend.ImportAttempts == nil {
+		return
+	}
+
+	if err := backend.ImportAttempts.Advance(month, attempt); err != nil {
+		backend.logErrorf("saving import attempts: %v", err)
+	}
+}
+
+// recordImport records the transactions created by a successful import of a month in the undo log, so that UndoLastImport can delete them
+func (backend *Backend) recordImport(month string, importResult ImportResult) {
+	if backend.UndoLog == nil {
+		return
+	}
+
+	if err := backend.UndoLog.Record(month, importResult); err != nil {
+		backend.logErrorf("recording import: %v", err)
+	}
+}
+
+// GetLastImport returns the transactions created by the last import, which UndoLastImport deletes, or nil if there is no import to undo
+func (backend *Backend) GetLastImport() *ImportRecord {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	return backend.getLastImport()
+}
+
+// getLastImport returns the transactions created by the last import, as GetLastImport does, while stateMutex is held
+func (backend *Backend) getLastImport() *ImportRecord {
+	if backend.UndoLog == nil {
+		return nil
+	}
+
+	return backend.UndoLog.GetLastImport()
+}
+
+// UndoLastImport deletes the transactions created by the last import from every YNAB budget, and discards the rounding recorded for its month
+// Undoing is refused, without deleting anything, if any of the transactions was reconciled or edited in YNAB since
+// Once deleted, the month moves on to the next attempt of its import ids, so that importing it again creates the transactions anew
+// Transactions that could not be deleted stay in the undo log, so that undoing the import can be tried again
+func (backend *Backend) UndoLastImport() UndoResult {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	undoResult := UndoResult{Problems: []string{}, Budgets: []BudgetImportResult{}}
+
+	lastImport := backend.getLastImport()
+	switch {
+	case backend.BudgetService == nil:
+		undoResult.Error = fmt.Sprintf("not connected to YNAB: %v", backend.SetupError)
+		return undoResult
+	case lastImport == nil:
+		undoResult.Error = ErrNothingToUndo.Error()
+		return undoResult
+	}
+
+	undoResult, err := lastImport.Undo(backend.requestContext(), backend.BudgetService)
+
+	if forgetErr := backend.UndoLog.Forget(undoResult.GetDeletedTransactionIds()); forgetErr != nil {
+		backend.logErrorf("saving undo log: %v", forgetErr)
+	}
+	if len(undoResult.GetDeletedTransactionIds()) > 0 {
+		backend.nextImportAttempt(lastImport.Month, backend.getImportAttempt(lastImport.Month))
+	}
+
+	if err != nil {
+		backend.logErrorf("undoing import: %s", undoResult.Error)
+		return undoResult
+	}
+
+	if backend.RoundingLedger != nil {
+		if err = backend.RoundingLedger.Discard(lastImport.Month); err != nil {
+			backend.logErrorf("discarding rounding: %v", err)
+		}
+	}
+
+	return undoResult
+}
+
+// queueImport queues an import plan in the outbox, along with the duplicate resolution to submit it with and the rounding of the individual shares to record once it is submitted
+func (backend *Backend) queueImport(combinedMonthlyExpenses *CombinedMonthlyExpenses, importPlan ImportPlan, duplicateResolution string) error {
+	roundingEntries, err := combinedMonthlyExpenses.GetRoundingLedgerEntries(importPlan.Month)
+	if err != nil {
+		return err
+	}
+
+	if err = backend.Outbox.Enqueue(QueuedImport{
+		Month:               importPlan.Month,
+		Plan:                importPlan,
+		DuplicateResolution: duplicateResolution,
+		RoundingEntries:     roundingEntries,
+		QueuedAt:            time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	backend.emitOutboxChanged(nil)
+
+	return nil
+}
+
+// GetQueuedImports returns the imports in the outbox, waiting for YNAB to be reachable or rejected by it
+func (backend *Backend) GetQueuedImports() []QueuedImport {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	return backend.getQueuedImports()
+}
+
+// getQueuedImports returns the imports in the outbox, as GetQueuedImports does, while stateMutex is held
+func (backend *Backend) getQueuedImports() []QueuedImport {
+	if backend.Outbox == nil {
+		return []QueuedImport{}
+	}
+
+	return backend.Outbox.GetImports()
+}
+
+// SubmitQueuedImports submits the imports queued in the outbox, recording the rounding of the ones submitted, and returns the imports left in the outbox
+// Nothing is submitted without an access token, as YNAB would reject every import
+func (backend *Backend) SubmitQueuedImports() []QueuedImport {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.Outbox == nil || backend.BudgetService == nil || backend.SetupError != nil {
+		return backend.getQueuedImports()
+	}
+
+	submittedImports, err := backend.Outbox.Submit(backend.requestContext(), backend.BudgetService, backend.CurrencyFormats)
+	if err != nil {
+		backend.logErrorf("saving outbox: %v", err)
+	}
+
+	for _, queuedImport := range append(backend.Outbox.GetImports(), submittedImports...) {
+		backend.advanceImportAttempt(queuedImport.Month, queuedImport.Plan.Attempt)
+	}
+
+	for _, submittedImport := range submittedImports {
+		backend.recordImport(submittedImport.Month, *submittedImport.Result)
+		backend.recordHistory(newHistoryRecord(HistoryRecordKindImport, submittedImport.Month, submittedImport.RoundingEntries, submittedImport.Result))
+
+		if backend.RoundingLedger == nil {
+			continue
+		}
+		if err = backend.RoundingLedger.Record(submittedImport.RoundingEntries); err != nil {
+			backend.logErrorf("recording rounding: %v", err)
+		}
+	}
+
+	backend.emitOutboxChanged(submittedImports)
+
+	return backend.Outbox.GetImports()
+}
+
+// DiscardQueuedImport removes the import of a month (formatted as YYYY-MM) from the outbox without submitting it
+func (backend *Backend) DiscardQueuedImport(month string) error {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.Outbox == nil {
+		return nil
+	}
+
+	if err := backend.Outbox.Discard(month); err != nil {
+		return err
+	}
+
+	backend.emitOutboxChanged(nil)
+
+	return nil
+}
+
+// ResolveQueuedImport queues the import of a month (formatted as YYYY-MM) whose expenses appeared to be already entered in YNAB when submitting it again, to be submitted with the given duplicate resolution
+func (backend *Backend) ResolveQueuedImport(month string, duplicateResolution string) error {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	switch {
+	case duplicateResolution == "" || !IsValidDuplicateResolution(duplicateResolution):
+		return fmt.Errorf("unknown duplicate resolution %q", duplicateResolution)
+	case backend.Outbox == nil:
+		return fmt.Errorf("%w for %s", ErrNothingToResolve, month)
+	}
+
+	if err := backend.Outbox.Resolve(month, duplicateResolution); err != nil {
+		return err
+	}
+
+	backend.emitOutboxChanged(nil)
+
+	return nil
+}
+
+// RunOutbox submits the queued imports right away and then every OutboxRetryInterval, until the context is done
+func (backend *Backend) RunOutbox(ctx context.Context) {
+	ticker := time.NewTicker(OutboxRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		if backend.hasQueuedImports() {
+			backend.SubmitQueuedImports()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hasQueuedImports checks if any import is queued in the outbox
+func (backend *Backend) hasQueuedImports() bool {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	return backend.Outbox != nil && backend.Outbox.HasQueuedImports()
+}
+
+// emitOutboxChanged emits the "outboxChanged" event with the imports left in the outbox and the ones just submitted, if any, while stateMutex is held
+func (backend *Backend) emitOutboxChanged(submittedImports []QueuedImport) {
+	if backend.Context == nil {
+		return
+	}
+
+	runtime.EventsEmit(backend.Context, "outboxChanged", backend.getQueuedImports(), submittedImports)
+}
+
+// logErrorf logs an error to the Wails log, or to the standard logger when running without the Wails application, e.g. from the CLI
+func (backend *Backend) logErrorf(format string, args ...interface{}) {
+	if backend.Context == nil {
+		log.Printf(format, args...)
+		return
+	}
+
+	runtime.LogErrorf(backend.Context, format, args...)
+}
+
+// recordRounding records the rounding of the individual shares of a month in the rounding ledger
+func (backend *Backend) recordRounding(combinedMonthlyExpenses *CombinedMonthlyExpenses, month string) error {
+	if backend.RoundingLedger == nil {
+		return nil
+	}
+
+	entries, err := combinedMonthlyExpenses.GetRoundingLedgerEntries(month)
+	if err != nil {
+		return err
+	}
+
+	return backend.RoundingLedger.Record(entries)
+}
+
+// recordSplit records the split of the shared monthly expenses of the current month in the history
+func (backend *Backend) recordSplit() {
+	if backend.History == nil {
+		return
+	}
+
+	month := time.Now().Format("2006-01")
+
+	roundingEntries, err := backend.CombinedMonthlyExpenses.GetRoundingLedgerEntries(month)
+	if err != nil {
+		backend.logErrorf("recording split: %v", err)
+		return
+	}
+
+	backend.recordHistory(newHistoryRecord(HistoryRecordKindSplit, month, roundingEntries, nil))
+}
+
+// recordImportHistory records an import of the monthly expenses of a month in the history, whether it succeeded or not
+func (backend *Backend) recordImportHistory(combinedMonthlyExpenses *CombinedMonthlyExpenses, month string, importResult ImportResult) {
+	if backend.History == nil {
+		return
+	}
+
+	roundingEntries, err := combinedMonthlyExpenses.GetRoundingLedgerEntries(month)
+	if err != nil {
+		backend.logErrorf("recording import history: %v", err)
+		return
+	}
+
+	backend.recordHistory(newHistoryRecord(HistoryRecordKindImport, month, roundingEntries, &importResult))
+}
+
+// recordHistory adds a record to the history, only logging if it cannot be recorded, as the split or import itself went through regardless
+func (backend *Backend) recordHistory(record HistoryRecord) {
+	if backend.History == nil {
+		return
+	}
+
+	if _, err := backend.History.Record(record); err != nil {
+		backend.logErrorf("%v", err)
+	}
+}
+
+// GetHistoryMonths returns the months with splits or imports recorded in the history, latest first
+func (backend *Backend) GetHistoryMonths() ([]string, error) {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.History == nil {
+		return []string{}, nil
+	}
+
+	return backend.History.GetMonths()
+}
+
+// GetHistory returns the splits and imports recorded in the history for a month (formatted as YYYY-MM) in the order they were recorded, or for every month if none is given
+func (backend *Backend) GetHistory(month string) ([]HistoryRecord, error) {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.History == nil {
+		return []HistoryRecord{}, nil
+	}
+
+	return backend.History.GetRecords(month)
+}
+
+// GetRoundingBalances returns the cumulative rounding in each participant's favour recorded in the rounding ledger
+func (backend *Backend) GetRoundingBalances() map[string]decimal.Decimal {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.RoundingLedger == nil {
+		return map[string]decimal.Decimal{}
+	}
+
+	return backend.RoundingLedger.GetBalances()
+}
+
+// GetRoundingLedgerEntries returns the rounding recorded in the rounding ledger for a month (formatted as YYYY-MM), or for every month if none is given
+func (backend *Backend) GetRoundingLedgerEntries(month string) []RoundingLedgerEntry {
+	backend.stateMutex.RLock()
+	defer backend.stateMutex.RUnlock()
+
+	if backend.RoundingLedger == nil {
+		return []RoundingLedgerEntry{}
+	}
+
+	return backend.RoundingLedger.GetEntries(month)
 }
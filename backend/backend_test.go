@@ -0,0 +1,527 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"ynab-monthly-expenses-manager/backend/ynabtest"
+)
+
+const testAccessToken string = "test-access-token"
+
+const endToEndConfig = `
+version: 1
+shared:
+  budget: "Casa"
+  account: "Millennium bcp"
+participants:
+  - name: "Magui"
+    budget: "Magui"
+    account: "CGD"
+  - name: "Jão"
+categories:
+  group: "Obligatory Monthly Expenses"
+  expenses:
+    - name: "Electricity"
+      payee: "EDP"
+    - name: "Water"
+      payee: "EPAL"
+`
+
+// fakeHousehold holds the fake YNAB API and the budgets declared in endToEndConfig
+type fakeHousehold struct {
+	ynab         *ynabtest.Server
+	sharedBudget *ynabtest.Budget
+	maguiBudget  *ynabtest.Budget
+}
+
+// setupApplicationDirectory points the application directory to a temporary one, holding endToEndConfig as the configuration
+func setupApplicationDirectory(t *testing.T) {
+	applicationDirectory := t.TempDir()
+	for _, variable := range []string{"HOME", "XDG_CONFIG_HOME", "AppData"} {
+		t.Setenv(variable, applicationDirectory)
+	}
+
+	configPath := filepath.Join(applicationDirectory, "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte(endToEndConfig), 0600))
+
+	t.Setenv(ConfigPathEnvironmentVariable, configPath)
+}
+
+// setupFakeHousehold starts a fake YNAB API with the budgets, accounts and categories declared in endToEndConfig, and points the application directory, configuration and YNAB API to temporary ones
+func setupFakeHousehold(t *testing.T) *fakeHousehold {
+	setupApplicationDirectory(t)
+
+	household := &fakeHousehold{ynab: ynabtest.NewServer(t, testAccessToken)}
+
+	household.sharedBudget = household.ynab.AddBudget("🏠 Casa")
+	household.sharedBudget.AddAccount("Millennium bcp")
+	household.maguiBudget = household.ynab.AddBudget("Magui")
+	household.maguiBudget.AddAccount("CGD")
+
+	for _, budget := range []*ynabtest.Budget{household.sharedBudget, household.maguiBudget} {
+		budget.AddCategory("Obligatory Monthly Expenses", "⚡ Electricity")
+		budget.AddCategory("Obligatory Monthly Expenses", "💧 Water")
+	}
+
+	t.Setenv(AccessTokenEnvironmentVariable, testAccessToken)
+	t.Setenv(YNABBaseURLEnvironmentVariable, household.ynab.BaseURL())
+
+	return household
+}
+
+// splitFakeMonthlyExpenses splits the shared monthly expenses declared in endToEndConfig
+func splitFakeMonthlyExpenses(t *testing.T, backend *Backend) *CombinedMonthlyExpenses {
+	combinedMonthlyExpenses, err := backend.SplitMonthlyExpenses(map[string]decimal.Decimal{
+		"Electricity": decimal.RequireFromString("130.51"),
+		"Water":       decimal.RequireFromString("60.25"),
+	})
+	assert.NoError(t, err)
+
+	return combinedMonthlyExpenses
+}
+
+func TestSetupSplitAndImport(t *testing.T) {
+	household := setupFakeHousehold(t)
+
+	backend := SetupBackend()
+
+	assert.NoError(t, backend.SetupError)
+	assert.True(t, backend.Diagnostics.Ready, "Expected every check to pass, but got %v", backend.Diagnostics.GetFailedChecks())
+	assert.Equal(t, SetupProgress{Message: "Setup complete", Completed: 7, Total: 7}, backend.GetSetupProgress())
+	assert.Equal(t, "€", backend.CurrencyFormats[household.sharedBudget.Id].CurrencySymbol)
+
+	importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+
+	assert.True(t, importResult.Success, importResult.Error)
+	assert.Len(t, household.sharedBudget.GetTransactions(), 4, "Expected a transaction for each category and for each participant's share")
+	assert.Len(t, household.maguiBudget.GetTransactions(), 1, "Expected a transaction for Magui's share in her budget")
+	assert.Equal(t, int64(-95380), household.maguiBudget.GetTransactions()[0].Amount)
+	assert.NotEmpty(t, backend.GetRoundingLedgerEntries(""), "Expected the rounding to be recorded")
+
+	importResult = backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+
+	assert.True(t, importResult.Success, importResult.Error)
+	assert.Len(t, importResult.Budgets[0].AlreadyPresent, 4, "Expected importing again not to duplicate the transactions")
+	assert.Len(t, household.sharedBudget.GetTransactions(), 4)
+
+	// Importing modifies both budgets, so they are synced again before checking that only modified budgets are
+	SetupBackend()
+	household.sharedBudget.AddCategory("Obligatory Monthly Expenses", "Internet")
+	requestsBefore := len(household.ynab.GetRequests())
+
+	backend = SetupBackend()
+
+	assert.True(t, backend.Diagnostics.Ready)
+	assert.Contains(t, household.ynab.GetRequests()[requestsBefore:], "GET budgets/"+household.sharedBudget.Id+"/categories",
+		"Expected the modified budget to be synced again")
+	assert.NotContains(t, household.ynab.GetRequests()[requestsBefore:], "GET budgets/"+household.maguiBudget.Id+"/categories",
+		"Expected the unmodified budget not to be synced again")
+}
+
+func TestSetupAgainWhileInUse(t *testing.T) {
+	setupApplicationDirectory(t)
+
+	config, err := LoadConfig(ConfigPath())
+	assert.NoError(t, err)
+
+	backend := SetupBackendWithBudgetService(NewMemoryBudgetServiceFromConfig(config))
+	assert.True(t, backend.Diagnostics.Ready)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend.startOutbox(ctx)
+
+	var waitGroup sync.WaitGroup
+	run := func(operation func()) {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for attempt := 0; attempt < 5; attempt++ {
+				operation()
+			}
+		}()
+	}
+
+	run(func() { backend.setupAgain("") })
+	run(func() {
+		importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), DuplicateResolutionProceed)
+		assert.True(t, importResult.Success, importResult.Error)
+	})
+	run(func() {
+		assert.Equal(t, []string{"Electricity", "Water"}, backend.GetCategoryNames())
+		backend.GetQueuedImports()
+		backend.GetRoundingBalances()
+	})
+	waitGroup.Wait()
+
+	assert.NotNil(t, backend.stopOutboxAndWait(), "Expected the outbox to be started again after every setup")
+}
+
+func TestImportFailures(t *testing.T) {
+	testCases := map[string]struct {
+		failBudget               string
+		statusCode               int
+		expectedQueued           bool
+		expectedRolledBack       bool
+		expectedSharedAfterRetry int
+	}{
+		"individual budget rejects the transactions - shared budget rolled back": {
+			failBudget:         "Magui",
+			statusCode:         http.StatusBadRequest,
+			expectedRolledBack: true,
+		},
+		"YNAB unavailable - import queued and submitted later": {
+			failBudget:               "Casa",
+			statusCode:               http.StatusServiceUnavailable,
+			expectedQueued:           true,
+			expectedSharedAfterRetry: 4,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			household := setupFakeHousehold(t)
+
+			backend := SetupBackend()
+			assert.True(t, backend.Diagnostics.Ready)
+			backend.BudgetService.(*APIClient).SetRetryCount(0)
+
+			failBudget := household.sharedBudget
+			if testCase.failBudget == "Magui" {
+				failBudget = household.maguiBudget
+			}
+			household.ynab.Fail(http.MethodPost, "budgets/"+failBudget.Id+"/transactions", testCase.statusCode, 1)
+
+			importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+
+			assert.False(t, importResult.Success)
+			assert.Equal(t, testCase.expectedQueued, importResult.Queued)
+			assert.Equal(t, testCase.expectedRolledBack, importResult.RolledBack)
+			assert.Empty(t, household.sharedBudget.GetTransactions(), "Expected no transactions to be left in YNAB")
+			assert.Empty(t, household.maguiBudget.GetTransactions())
+
+			backend.SubmitQueuedImports()
+
+			assert.Len(t, household.sharedBudget.GetTransactions(), testCase.expectedSharedAfterRetry)
+			assert.Empty(t, backend.GetQueuedImports(), "Expected the queued import to be submitted")
+		})
+	}
+}
+
+func TestImportAgainAfterDeletingTransactions(t *testing.T) {
+	testCases := map[string]struct {
+		importAgain     func(t *testing.T, household *fakeHousehold, backend *Backend)
+		expectedAttempt int
+	}{
+		"import rolled back - retried": {
+			importAgain: func(t *testing.T, household *fakeHousehold, backend *Backend) {
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.maguiBudget.Id+"/transactions", http.StatusBadRequest, 1)
+				assert.True(t, backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "").RolledBack)
+
+				importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+				assert.True(t, importResult.Success, importResult.Error)
+			},
+			expectedAttempt: 1,
+		},
+		"import rolled back and queued - submitted later": {
+			importAgain: func(t *testing.T, household *fakeHousehold, backend *Backend) {
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.maguiBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+				assert.True(t, backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "").Queued)
+
+				assert.Empty(t, backend.SubmitQueuedImports())
+			},
+			expectedAttempt: 1,
+		},
+		"queued import rolled back when submitted - submitted again": {
+			importAgain: func(t *testing.T, household *fakeHousehold, backend *Backend) {
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.sharedBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+				assert.True(t, backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "").Queued)
+
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.maguiBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+				assert.Len(t, backend.SubmitQueuedImports(), 1, "Expected the rolled back import to stay queued")
+
+				assert.Empty(t, backend.SubmitQueuedImports())
+			},
+			expectedAttempt: 1,
+		},
+		"import undone - imported again": {
+			importAgain: func(t *testing.T, household *fakeHousehold, backend *Backend) {
+				importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+				assert.True(t, importResult.Success, importResult.Error)
+				assert.True(t, backend.UndoLastImport().Success)
+
+				importResult = backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+				assert.True(t, importResult.Success, importResult.Error)
+				assert.Empty(t, importResult.Budgets[0].AlreadyPresent, "Expected the undone transactions to be created again")
+			},
+			expectedAttempt: 1,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			household := setupFakeHousehold(t)
+
+			backend := SetupBackend()
+			assert.True(t, backend.Diagnostics.Ready)
+			backend.BudgetService.(*APIClient).SetRetryCount(0)
+
+			testCase.importAgain(t, household, backend)
+
+			assert.Len(t, household.sharedBudget.GetTransactions(), 4, "Expected the transactions to be created again despite YNAB keeping the import ids of the deleted ones")
+			assert.Len(t, household.maguiBudget.GetTransactions(), 1)
+			assert.Equal(t, testCase.expectedAttempt, backend.ImportAttempts.Get(time.Now().Format("2006-01")))
+			for _, transaction := range household.sharedBudget.GetTransactions() {
+				assert.True(t, strings.HasSuffix(*transaction.ImportId, fmt.Sprintf(":%d", testCase.expectedAttempt)), "Expected import id %s to be of the next attempt", *transaction.ImportId)
+			}
+		})
+	}
+}
+
+func TestImportWithIncompleteRollback(t *testing.T) {
+	testCases := map[string]struct {
+		importMonthlyExpenses func(t *testing.T, household *fakeHousehold, backend *Backend) string
+	}{
+		"import - not queued": {
+			importMonthlyExpenses: func(t *testing.T, household *fakeHousehold, backend *Backend) string {
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.maguiBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+				household.ynab.Fail(http.MethodDelete, "budgets/"+household.sharedBudget.Id+"/transactions", http.StatusInternalServerError, 1)
+
+				importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+				assert.False(t, importResult.Queued, "Expected the import not to be queued, as submitting it would duplicate the transactions left behind")
+				assert.Empty(t, backend.GetQueuedImports())
+
+				return importResult.Error
+			},
+		},
+		"queued import submitted - marked as failed": {
+			importMonthlyExpenses: func(t *testing.T, household *fakeHousehold, backend *Backend) string {
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.sharedBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+				assert.True(t, backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "").Queued)
+
+				household.ynab.Fail(http.MethodPost, "budgets/"+household.maguiBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+				household.ynab.Fail(http.MethodDelete, "budgets/"+household.sharedBudget.Id+"/transactions", http.StatusInternalServerError, 1)
+				queuedImports := backend.SubmitQueuedImports()
+				assert.Len(t, queuedImports, 1)
+				assert.Equal(t, QueuedImportStatusFailed, queuedImports[0].Status)
+				assert.Equal(t, 0, queuedImports[0].Plan.Attempt)
+
+				// The failed import is never submitted again by itself
+				assert.Equal(t, queuedImports, backend.SubmitQueuedImports())
+
+				return queuedImports[0].Error
+			},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			household := setupFakeHousehold(t)
+
+			backend := SetupBackend()
+			assert.True(t, backend.Diagnostics.Ready)
+			backend.BudgetService.(*APIClient).SetRetryCount(0)
+
+			importError := testCase.importMonthlyExpenses(t, household, backend)
+
+			assert.True(t, strings.HasPrefix(importError, ErrRollbackIncomplete.Error()), importError)
+			assert.Len(t, household.sharedBudget.GetTransactions(), 1, "Expected the transaction that could not be deleted to be left behind")
+			assert.Empty(t, household.maguiBudget.GetTransactions())
+			assert.Equal(t, 0, backend.ImportAttempts.Get(time.Now().Format("2006-01")), "Expected the import ids not to move on while transactions are left behind")
+		})
+	}
+}
+
+func TestImportRefusesStaleSplit(t *testing.T) {
+	testCases := map[string]struct {
+		change        func(combinedMonthlyExpenses *CombinedMonthlyExpenses)
+		expectedError string
+	}{
+		"split unchanged - imported": {
+			change: func(combinedMonthlyExpenses *CombinedMonthlyExpenses) {},
+		},
+		"amount changed after splitting - refused": {
+			change: func(combinedMonthlyExpenses *CombinedMonthlyExpenses) {
+				combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses["Electricity"].Amount = decimal.RequireFromString("200")
+			},
+			expectedError: "the share of Magui of Electricity is 65.26 instead of 100.00",
+		},
+		"share changed - refused": {
+			change: func(combinedMonthlyExpenses *CombinedMonthlyExpenses) {
+				combinedMonthlyExpenses.IndividualMonthlyExpenses[0].Expenses["Water"].Amount = decimal.RequireFromString("30.13")
+			},
+			expectedError: "the shares of Water add up to 60.26 instead of 60.25",
+		},
+		"participant left out - refused": {
+			change: func(combinedMonthlyExpenses *CombinedMonthlyExpenses) {
+				combinedMonthlyExpenses.IndividualMonthlyExpenses = combinedMonthlyExpenses.IndividualMonthlyExpenses[:1]
+			},
+			expectedError: "the participants are Magui instead of Magui, Jão",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			household := setupFakeHousehold(t)
+
+			backend := SetupBackend()
+			assert.True(t, backend.Diagnostics.Ready)
+
+			// The monthly expenses come back from the frontend as a copy of the split
+			data, err := json.Marshal(splitFakeMonthlyExpenses(t, backend))
+			assert.NoError(t, err)
+			combinedMonthlyExpenses := &CombinedMonthlyExpenses{}
+			assert.NoError(t, json.Unmarshal(data, combinedMonthlyExpenses))
+
+			testCase.change(combinedMonthlyExpenses)
+
+			_, err = backend.GetImportPlan(combinedMonthlyExpenses)
+			importResult := backend.CreateMonthlyExpensesTransactions(combinedMonthlyExpenses, "")
+
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+				assert.True(t, importResult.Success, importResult.Error)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrSplitMismatch)
+			assert.ErrorContains(t, err, testCase.expectedError)
+			assert.False(t, importResult.Success)
+			assert.Contains(t, importResult.Error, testCase.expectedError)
+			assert.Empty(t, household.sharedBudget.GetTransactions(), "Expected nothing to be imported")
+			assert.Empty(t, backend.GetRoundingLedgerEntries(time.Now().Format("2006-01")), "Expected no rounding to be recorded")
+		})
+	}
+}
+
+func TestSetupReportsMissingCategories(t *testing.T) {
+	household := setupFakeHousehold(t)
+	household.maguiBudget.DeleteCategory("💧 Water")
+	household.sharedBudget.CloseAccount("Millennium bcp")
+
+	backend := SetupBackend()
+
+	var failedCheckNames []string
+	for _, check := range backend.Diagnostics.GetFailedChecks() {
+		failedCheckNames = append(failedCheckNames, check.Name)
+	}
+
+	assert.False(t, backend.Diagnostics.Ready)
+	assert.Equal(t, []string{"Shared account", "Magui's categories"}, failedCheckNames)
+}
+
+func TestUndoLastImport(t *testing.T) {
+	testCases := map[string]struct {
+		change                  SaveTransactionWithId
+		expectedSuccess         bool
+		expectedProblem         string
+		expectedSharedRemaining int
+	}{
+		"untouched import - transactions deleted from both budgets": {
+			expectedSuccess: true,
+		},
+		"cleared and approved transaction - transactions deleted from both budgets": {
+			change:          SaveTransactionWithId{Cleared: to.StringPtr("cleared"), Approved: to.BoolPtr(true)},
+			expectedSuccess: true,
+		},
+		"reconciled transaction - undo refused": {
+			change:                  SaveTransactionWithId{Cleared: to.StringPtr("reconciled")},
+			expectedProblem:         "was reconciled",
+			expectedSharedRemaining: 4,
+		},
+		"edited transaction - undo refused": {
+			change:                  SaveTransactionWithId{Amount: to.Int64Ptr(-1000)},
+			expectedProblem:         "was edited",
+			expectedSharedRemaining: 4,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			household := setupFakeHousehold(t)
+
+			backend := SetupBackend()
+			assert.True(t, backend.Diagnostics.Ready)
+
+			importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+			assert.True(t, importResult.Success, importResult.Error)
+			assert.NotNil(t, backend.GetLastImport())
+
+			if testCase.change != (SaveTransactionWithId{}) {
+				testCase.change.Id = household.sharedBudget.GetTransactions()[0].Id
+				_, err := backend.BudgetService.UpdateTransactions(context.Background(), household.sharedBudget.Id, []SaveTransactionWithId{testCase.change})
+				assert.NoError(t, err)
+			}
+
+			// The undo log is persisted, so the import can be undone after restarting as well
+			backend = SetupBackend()
+			undoResult := backend.UndoLastImport()
+
+			assert.Equal(t, testCase.expectedSuccess, undoResult.Success, undoResult.Error)
+			assert.Len(t, household.sharedBudget.GetTransactions(), testCase.expectedSharedRemaining)
+			if testCase.expectedSuccess {
+				assert.Empty(t, household.maguiBudget.GetTransactions())
+				assert.Nil(t, backend.GetLastImport(), "Expected nothing left to undo")
+				assert.Empty(t, backend.GetRoundingLedgerEntries(undoResult.Month), "Expected the rounding of the undone month to be discarded")
+				return
+			}
+
+			assert.True(t, undoResult.Refused)
+			assert.Len(t, undoResult.Problems, 1)
+			assert.Contains(t, undoResult.Problems[0], testCase.expectedProblem)
+			assert.Len(t, household.maguiBudget.GetTransactions(), 1, "Expected nothing to be deleted when undoing is refused")
+			assert.NotNil(t, backend.GetLastImport())
+		})
+	}
+}
+
+func TestHistoryOfSplitsAndImports(t *testing.T) {
+	household := setupFakeHousehold(t)
+
+	backend := SetupBackend()
+	assert.True(t, backend.Diagnostics.Ready)
+
+	importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+	assert.True(t, importResult.Success, importResult.Error)
+
+	month := time.Now().Format("2006-01")
+
+	months, err := backend.GetHistoryMonths()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{month}, months)
+
+	records, err := backend.GetHistory(month)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2, "Expected the split and the import to be recorded")
+
+	split, imported := records[0], records[1]
+	assert.Equal(t, HistoryRecordKindSplit, split.Kind)
+	assert.Nil(t, split.ImportResult)
+	assert.Equal(t, HistoryRecordKindImport, imported.Kind)
+	assert.Equal(t, "130.51", imported.Amounts["Electricity"].String())
+	assert.True(t, imported.Shares["Magui"]["Electricity"].Add(imported.Shares["Jão"]["Electricity"]).Equal(decimal.RequireFromString("130.51")))
+	assert.Len(t, imported.Rounding, 4)
+
+	var createdTransactionIds []string
+	for _, budgetImportResult := range imported.ImportResult.Budgets {
+		createdTransactionIds = append(createdTransactionIds, budgetImportResult.CreatedTransactionIds...)
+	}
+	var transactionIds []string
+	for _, transaction := range append(household.sharedBudget.GetTransactions(), household.maguiBudget.GetTransactions()...) {
+		transactionIds = append(transactionIds, transaction.Id)
+	}
+	assert.ElementsMatch(t, transactionIds, createdTransactionIds)
+}
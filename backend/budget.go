@@ -1,6 +1,8 @@
 package backend
 
 import (
+	"context"
+
 	"github.com/forPelevin/gomoji"
 )
 
@@ -39,15 +41,9 @@ type CurrencyFormat struct {
 // Budgets represents a collection of YNAB budgets
 type Budgets []BudgetSummary
 
-// SharedBudgetName is the predefined name of the shared YNAB budget
-const SharedBudgetName string = "Casa Reis-Pereira"
-
-// IndividualBudgetName is the predefined name of the individual YNAB budget
-const IndividualBudgetName string = "Magui"
-
-// GetBudgets fetches the list of YNAB budgets
+// GetBudgets fetches the list of YNAB budgets, without their accounts, which are fetched per budget with GetAccounts
 // GET https://api.ynab.com/v1/budgets
-func (client *APIClient) GetBudgets() (Budgets, error) {
+func (client *APIClient) GetBudgets(ctx context.Context) (Budgets, error) {
 	budgetsResponse := struct {
 		Data struct {
 			Budgets       Budgets       `json:"budgets"`
@@ -56,9 +52,7 @@ func (client *APIClient) GetBudgets() (Budgets, error) {
 	}{}
 
 	response, err := client.Client.R().
-		SetQueryParams(map[string]string{
-			"include_accounts": "true",
-		}).
+		SetContext(ctx).
 		SetResult(&budgetsResponse).
 		Get("budgets")
 
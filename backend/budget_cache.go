@@ -0,0 +1,325 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BudgetCacheFileName is the name of the file, in the application directory, where the YNAB budgets are cached
+const BudgetCacheFileName string = "ynab_cache.json"
+
+// BudgetSyncConcurrency is the maximum number of YNAB budgets synced at the same time
+const BudgetSyncConcurrency int = 4
+
+// CachedBudget represents the cached accounts, categories and payees of a YNAB budget
+// The server knowledge of each is kept to fetch only what changed since, and the last modification of the budget to fetch nothing when it did not change at all
+type CachedBudget struct {
+	LastModifiedOn            string                       `json:"last_modified_on"`
+	Accounts                  Accounts                     `json:"accounts"`
+	AccountsServerKnowledge   int64                        `json:"accounts_server_knowledge"`
+	CategoryGroups            CategoryGroupsWithCategories `json:"category_groups"`
+	CategoriesServerKnowledge int64                        `json:"categories_server_knowledge"`
+	Payees                    Payees                       `json:"payees"`
+	PayeesServerKnowledge     int64                        `json:"payees_server_knowledge"`
+}
+
+// BudgetCache represents the local copy of the YNAB budgets, and of the accounts, categories and payees of the budgets declared in the configuration
+// The copy is stale when YNAB could not be reached on the last sync, in which case the data is as of when it was last synced
+type BudgetCache struct {
+	Path     string                   `json:"-"`
+	Budgets  Budgets                  `json:"budgets"`
+	Details  map[string]*CachedBudget `json:"details"`
+	SyncedAt time.Time                `json:"synced_at"`
+	Stale    bool                     `json:"-"`
+	mutex    sync.Mutex
+}
+
+// BudgetCachePath returns the location of the YNAB budget cache file
+func BudgetCachePath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, BudgetCacheFileName), nil
+}
+
+// LoadBudgetCache reads the YNAB budget cache persisted at the given location, returning an empty cache if it does not exist yet
+func LoadBudgetCache(cachePath string) (*BudgetCache, error) {
+	cache := &BudgetCache{Path: cachePath, Details: make(map[string]*CachedBudget)}
+
+	data, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return cache, fmt.Errorf("reading YNAB cache: %w", err)
+	}
+
+	if err = json.Unmarshal(data, cache); err != nil {
+		return &BudgetCache{Path: cachePath, Details: make(map[string]*CachedBudget)}, fmt.Errorf("decoding YNAB cache %s: %w", cachePath, err)
+	}
+
+	if cache.Details == nil {
+		cache.Details = make(map[string]*CachedBudget)
+	}
+
+	return cache, nil
+}
+
+// Sync fetches the YNAB budgets, and what changed since the last sync in the accounts, categories and payees of the budgets with the given names
+// Budgets not modified since the last sync are not fetched again, and the others are fetched concurrently, at most BudgetSyncConcurrency at a time
+// The name of each budget is passed to onBudgetSynced, if given, as soon as it is synced, e.g. to report the progress
+// If YNAB cannot be reached, fails transiently or the sync is canceled, the cache keeps its data and is flagged as stale, as long as it has any
+func (cache *BudgetCache) Sync(ctx context.Context, budgetService BudgetService, budgetNames []string, onBudgetSynced func(budgetName string)) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	budgets, err := budgetService.GetBudgets(ctx)
+	if err != nil {
+		return cache.fallBack(err)
+	}
+	cache.Budgets = budgets
+
+	// Once a budget fails to sync, the others are canceled, as the cache falls back as a whole anyway
+	syncContext, cancelSync := context.WithCancel(ctx)
+	defer cancelSync()
+
+	var waitGroup sync.WaitGroup
+	var syncErrors []error
+	var syncErrorsMutex sync.Mutex
+	semaphore := make(chan struct{}, BudgetSyncConcurrency)
+	scheduledBudgetIds := make(map[string]bool)
+
+	for _, budgetName := range budgetNames {
+		budget := budgets.GetBudget(budgetName)
+		if budget.Id == "" || scheduledBudgetIds[budget.Id] {
+			continue
+		}
+		scheduledBudgetIds[budget.Id] = true
+
+		cachedBudget, ok := cache.Details[budget.Id]
+		if ok && cachedBudget.LastModifiedOn != "" && cachedBudget.LastModifiedOn == budget.LastModifiedOn {
+			if onBudgetSynced != nil {
+				onBudgetSynced(budgetName)
+			}
+			continue
+		}
+		if !ok {
+			cachedBudget = &CachedBudget{}
+			cache.Details[budget.Id] = cachedBudget
+		}
+
+		waitGroup.Add(1)
+		go func(budgetName string, budget BudgetSummary, cachedBudget *CachedBudget) {
+			defer waitGroup.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if err := cachedBudget.sync(syncContext, budgetService, budget.Id); err != nil {
+				syncErrorsMutex.Lock()
+				syncErrors = append(syncErrors, err)
+				syncErrorsMutex.Unlock()
+				cancelSync()
+				return
+			}
+			cachedBudget.LastModifiedOn = budget.LastModifiedOn
+
+			if onBudgetSynced != nil {
+				onBudgetSynced(budgetName)
+			}
+		}(budgetName, budget, cachedBudget)
+	}
+
+	waitGroup.Wait()
+
+	if len(syncErrors) > 0 {
+		return cache.fallBack(syncErrors[0])
+	}
+
+	cache.SyncedAt = time.Now()
+	cache.Stale = false
+
+	return nil
+}
+
+// fallBack flags the cache as stale if it has data to fall back on and the sync failed in a way that does not invalidate it
+func (cache *BudgetCache) fallBack(err error) error {
+	if len(cache.Budgets) > 0 && (isUnreachable(err) || errors.Is(err, ErrCanceled)) {
+		cache.Stale = true
+	}
+
+	return err
+}
+
+// sync fetches what changed since the last sync in the accounts, categories and payees of a YNAB budget, and merges it into the cached ones
+func (cachedBudget *CachedBudget) sync(ctx context.Context, budgetService BudgetService, budgetId string) error {
+	accounts, accountsServerKnowledge, err := budgetService.GetAccounts(ctx, budgetId, cachedBudget.AccountsServerKnowledge)
+	if err != nil {
+		return err
+	}
+
+	categoryGroups, categoriesServerKnowledge, err := budgetService.GetCategoriesSince(ctx, budgetId, cachedBudget.CategoriesServerKnowledge)
+	if err != nil {
+		return err
+	}
+
+	payees, payeesServerKnowledge, err := budgetService.GetPayees(ctx, budgetId, cachedBudget.PayeesServerKnowledge)
+	if err != nil {
+		return err
+	}
+
+	cachedBudget.Accounts = mergeById(cachedBudget.Accounts, accounts, func(account Account) (string, bool) {
+		return account.Id, account.Deleted
+	})
+	cachedBudget.AccountsServerKnowledge = accountsServerKnowledge
+
+	cachedBudget.CategoryGroups = mergeCategoryGroups(cachedBudget.CategoryGroups, categoryGroups)
+	cachedBudget.CategoriesServerKnowledge = categoriesServerKnowledge
+
+	cachedBudget.Payees = mergeById(cachedBudget.Payees, payees, func(payee Payee) (string, bool) {
+		return payee.Id, payee.Deleted
+	})
+	cachedBudget.PayeesServerKnowledge = payeesServerKnowledge
+
+	return nil
+}
+
+// mergeById merges the entities changed since the last sync into the cached ones, replacing those with the same id and removing the deleted ones
+func mergeById[T any](cached []T, changed []T, identify func(T) (string, bool)) []T {
+	indexesById := make(map[string]int, len(cached))
+	for index, entity := range cached {
+		id, _ := identify(entity)
+		indexesById[id] = index
+	}
+
+	merged := append([]T{}, cached...)
+	deletedIds := make(map[string]struct{})
+
+	for _, entity := range changed {
+		id, deleted := identify(entity)
+		if deleted {
+			deletedIds[id] = struct{}{}
+			continue
+		}
+
+		if index, ok := indexesById[id]; ok {
+			merged[index] = entity
+		} else {
+			indexesById[id] = len(merged)
+			merged = append(merged, entity)
+		}
+	}
+
+	remaining := merged[:0]
+	for _, entity := range merged {
+		id, _ := identify(entity)
+		if _, deleted := deletedIds[id]; !deleted {
+			remaining = append(remaining, entity)
+		}
+	}
+
+	return remaining
+}
+
+// mergeCategoryGroups merges the category groups changed since the last sync into the cached ones
+// A changed category group only holds its changed categories, which are merged into the cached categories of the group
+func mergeCategoryGroups(cached CategoryGroupsWithCategories, changed CategoryGroupsWithCategories) CategoryGroupsWithCategories {
+	cachedCategories := make(map[string][]Category, len(cached))
+	for _, categoryGroup := range cached {
+		cachedCategories[categoryGroup.Id] = categoryGroup.Categories
+	}
+
+	for index, categoryGroup := range changed {
+		changed[index].Categories = mergeById(cachedCategories[categoryGroup.Id], categoryGroup.Categories, func(category Category) (string, bool) {
+			return category.Id, category.Deleted
+		})
+	}
+
+	return mergeById(cached, changed, func(categoryGroup CategoryGroupWithCategories) (string, bool) {
+		return categoryGroup.Id, categoryGroup.Deleted
+	})
+}
+
+// Save persists the YNAB budget cache
+// A cache without a path is kept in memory only, e.g. for a budget service other than the YNAB API
+func (cache *BudgetCache) Save() error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.Path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(cache.Path), 0700); err != nil {
+		return fmt.Errorf("creating YNAB cache directory: %w", err)
+	}
+
+	if err = writeFileAtomically(cache.Path, data, 0600); err != nil {
+		return fmt.Errorf("writing YNAB cache: %w", err)
+	}
+
+	return nil
+}
+
+// GetBudgets returns the cached YNAB budgets, with the cached accounts of the budgets declared in the configuration
+func (cache *BudgetCache) GetBudgets() Budgets {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	budgets := make(Budgets, len(cache.Budgets))
+	for index, budget := range cache.Budgets {
+		budgets[index] = budget
+		if cachedBudget, ok := cache.Details[budget.Id]; ok {
+			budgets[index].Accounts = cachedBudget.Accounts
+		}
+	}
+
+	return budgets
+}
+
+// GetCategories returns the cached YNAB categories of a YNAB budget
+func (cache *BudgetCache) GetCategories(budgetId string) CategoryGroupsWithCategories {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cachedBudget, ok := cache.Details[budgetId]; ok {
+		return cachedBudget.CategoryGroups
+	}
+
+	return nil
+}
+
+// GetPayees returns the cached YNAB payees of a YNAB budget
+func (cache *BudgetCache) GetPayees(budgetId string) Payees {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cachedBudget, ok := cache.Details[budgetId]; ok {
+		return cachedBudget.Payees
+	}
+
+	return nil
+}
+
+// getDeltaQueryParams returns the query parameters of a YNAB API delta request, asking only for what changed since the given server knowledge, or for everything if it is 0
+func getDeltaQueryParams(lastKnowledgeOfServer int64) map[string]string {
+	if lastKnowledgeOfServer == 0 {
+		return map[string]string{}
+	}
+
+	return map[string]string{"last_knowledge_of_server": strconv.FormatInt(lastKnowledgeOfServer, 10)}
+}
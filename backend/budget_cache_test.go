@@ -0,0 +1,149 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetCacheSync(t *testing.T) {
+	lastModifiedOn := "2024-02-01T10:00:00Z"
+	var requestedPaths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		lastKnowledgeOfServer := request.URL.Query().Get("last_knowledge_of_server")
+		requestedPaths = append(requestedPaths, fmt.Sprintf("%s?%s", request.URL.Path, lastKnowledgeOfServer))
+		writer.Header().Set("Content-Type", "application/json")
+
+		switch request.URL.Path {
+		case "/budgets":
+			_, _ = fmt.Fprintf(writer, `{"data": {"budgets": [{"id": "shared", "name": "Casa", "last_modified_on": %q}, {"id": "other", "name": "Other"}]}}`, lastModifiedOn)
+		case "/budgets/shared/accounts":
+			_, _ = writer.Write([]byte(`{"data": {"accounts": [{"id": "account", "name": "Millennium bcp"}], "server_knowledge": 10}}`))
+		case "/budgets/shared/payees":
+			_, _ = writer.Write([]byte(`{"data": {"payees": [{"id": "payee", "name": "EPAL"}], "server_knowledge": 10}}`))
+		case "/budgets/shared/categories":
+			if lastKnowledgeOfServer == "" {
+				_, _ = writer.Write([]byte(`{"data": {"category_groups": [{"id": "group", "name": "Monthly", "categories": [
+					{"id": "water", "name": "Water"}, {"id": "electricity", "name": "Electricity"}
+				]}], "server_knowledge": 10}}`))
+				return
+			}
+			_, _ = writer.Write([]byte(`{"data": {"category_groups": [{"id": "group", "name": "Monthly", "categories": [
+				{"id": "electricity", "name": "Electricity", "deleted": true}, {"id": "internet", "name": "Internet"}
+			]}], "server_knowledge": 11}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := &APIClient{Client: resty.New().SetBaseURL(server.URL)}
+	cachePath := filepath.Join(t.TempDir(), BudgetCacheFileName)
+
+	cache, err := LoadBudgetCache(cachePath)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Sync(context.Background(), client, []string{"Casa"}, nil))
+	assert.Equal(t, []string{"/budgets?", "/budgets/shared/accounts?", "/budgets/shared/categories?", "/budgets/shared/payees?"}, requestedPaths,
+		"Expected every account, category and payee of the declared budget to be fetched on the first sync")
+	budgets := cache.GetBudgets()
+	assert.Equal(t, "account", budgets.GetBudget("Casa").Accounts[0].Id)
+	assert.NoError(t, cache.Save())
+
+	requestedPaths = nil
+	cache, err = LoadBudgetCache(cachePath)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Sync(context.Background(), client, []string{"Casa"}, nil))
+	assert.Equal(t, []string{"/budgets?"}, requestedPaths, "Expected an unmodified budget not to be fetched again")
+
+	requestedPaths = nil
+	lastModifiedOn = "2024-02-02T10:00:00Z"
+
+	assert.NoError(t, cache.Sync(context.Background(), client, []string{"Casa"}, nil))
+	assert.Equal(t, []string{"/budgets?", "/budgets/shared/accounts?10", "/budgets/shared/categories?10", "/budgets/shared/payees?10"}, requestedPaths,
+		"Expected only the changes since the last sync to be fetched")
+
+	var categoryIds []string
+	for _, category := range cache.GetCategories("shared")[0].Categories {
+		categoryIds = append(categoryIds, category.Id)
+	}
+	assert.Equal(t, []string{"water", "internet"}, categoryIds, "Expected the changed categories to be merged into the cached ones")
+	assert.Len(t, cache.GetPayees("shared"), 1)
+	assert.False(t, cache.Stale)
+
+	server.Close()
+
+	assert.Error(t, cache.Sync(context.Background(), client, []string{"Casa"}, nil))
+	assert.True(t, cache.Stale, "Expected the cache to be flagged as stale when YNAB cannot be reached")
+	budgets = cache.GetBudgets()
+	assert.Equal(t, "Casa", budgets.GetBudget("Casa").Name, "Expected the cached budgets to be kept")
+}
+
+func TestBudgetCacheSyncWithoutCachedBudgets(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	cache := &BudgetCache{Details: map[string]*CachedBudget{}}
+
+	assert.Error(t, cache.Sync(context.Background(), &APIClient{Client: resty.New().SetBaseURL(server.URL)}, []string{"Casa"}, nil))
+	assert.False(t, cache.Stale, "Expected an empty cache not to be used as stale data")
+}
+
+func TestBudgetCacheSyncIsConcurrent(t *testing.T) {
+	budgetNames := []string{"Casa", "Magui", "Jão", "Joana", "Pedro", "Rita"}
+
+	var inFlight, maxInFlight int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+
+		if request.URL.Path == "/budgets" {
+			var budgets []string
+			for _, budgetName := range budgetNames {
+				budgets = append(budgets, fmt.Sprintf(`{"id": %q, "name": %q, "last_modified_on": "2024-02-01T10:00:00Z"}`, budgetName, budgetName))
+			}
+			_, _ = fmt.Fprintf(writer, `{"data": {"budgets": [%s]}}`, strings.Join(budgets, ", "))
+			return
+		}
+
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			previous := atomic.LoadInt32(&maxInFlight)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxInFlight, previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		_, _ = writer.Write([]byte(`{"data": {"server_knowledge": 1}}`))
+	}))
+	defer server.Close()
+
+	var syncedBudgetNames []string
+	var syncedBudgetNamesMutex sync.Mutex
+
+	cache := &BudgetCache{Details: map[string]*CachedBudget{}}
+
+	err := cache.Sync(context.Background(), &APIClient{Client: resty.New().SetBaseURL(server.URL)}, budgetNames, func(budgetName string) {
+		syncedBudgetNamesMutex.Lock()
+		defer syncedBudgetNamesMutex.Unlock()
+		syncedBudgetNames = append(syncedBudgetNames, budgetName)
+	})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, budgetNames, syncedBudgetNames, "Expected every budget to be reported as synced")
+	assert.Greater(t, maxInFlight, int32(1), "Expected the budgets to be synced concurrently")
+	assert.LessOrEqual(t, maxInFlight, int32(BudgetSyncConcurrency), "Expected at most %d budgets to be synced at the same time", BudgetSyncConcurrency)
+	assert.Len(t, cache.Details, len(budgetNames))
+}
@@ -0,0 +1,31 @@
+package backend
+
+import "context"
+
+// BudgetService represents the YNAB operations the application relies on, which the APIClient sends to the YNAB API
+// Depending on it rather than on the APIClient allows the import to run against another implementation, e.g. the MemoryBudgetService in tests and demos
+type BudgetService interface {
+	// GetUser fetches the YNAB user the access token belongs to
+	GetUser(ctx context.Context) (User, error)
+	// GetBudgets fetches the list of YNAB budgets, without their accounts
+	GetBudgets(ctx context.Context) (Budgets, error)
+	// GetAccounts fetches the accounts of a budget changed since the given server knowledge, or every account if it is 0, along with the current server knowledge
+	GetAccounts(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (Accounts, int64, error)
+	// GetCategoriesSince fetches the category groups of a budget with their categories changed since the given server knowledge, or every category if it is 0, along with the current server knowledge
+	GetCategoriesSince(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (CategoryGroupsWithCategories, int64, error)
+	// GetPayees fetches the payees of a budget changed since the given server knowledge, or every payee if it is 0, along with the current server knowledge
+	GetPayees(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (Payees, int64, error)
+	// GetTransactions fetches the transactions of a budget matching the filter, along with the current server knowledge
+	GetTransactions(ctx context.Context, budgetId string, filter TransactionsFilter) (TransactionDetails, int64, error)
+	// GetTransaction fetches a transaction of a budget by id
+	GetTransaction(ctx context.Context, budgetId string, transactionId string) (TransactionDetail, error)
+	// CreateTransactions creates transactions in a budget, skipping the ones whose import id already exists in their account
+	CreateTransactions(ctx context.Context, budgetId string, transactions []SaveTransaction) (SaveTransactionsResponse, error)
+	// UpdateTransactions updates transactions of a budget, leaving the fields that are not set as they are
+	UpdateTransactions(ctx context.Context, budgetId string, transactions []SaveTransactionWithId) (SaveTransactionsResponse, error)
+	// DeleteTransaction deletes a transaction from a budget
+	DeleteTransaction(ctx context.Context, budgetId string, transactionId string) (TransactionDetail, error)
+}
+
+// The APIClient sends every operation to the YNAB API
+var _ BudgetService = (*APIClient)(nil)
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -56,7 +57,15 @@ type CategoryGroupsWithCategories []CategoryGroupWithCategories
 
 // GetCategories fetches the YNAB categories of a YNAB budget
 // GET https://api.ynab.com/v1/budgets/{budget_id}/categories
-func (client *APIClient) GetCategories(budgetId string) (CategoryGroupsWithCategories, error) {
+func (client *APIClient) GetCategories(ctx context.Context, budgetId string) (CategoryGroupsWithCategories, error) {
+	categoryGroups, _, err := client.GetCategoriesSince(ctx, budgetId, 0)
+
+	return categoryGroups, err
+}
+
+// GetCategoriesSince fetches the YNAB category groups of a YNAB budget with their categories changed since the given server knowledge, or every category if it is 0, along with the current server knowledge
+// GET https://api.ynab.com/v1/budgets/{budget_id}/categories
+func (client *APIClient) GetCategoriesSince(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (CategoryGroupsWithCategories, int64, error) {
 	categoriesResponse := struct {
 		Data struct {
 			CategoryGroups  CategoryGroupsWithCategories `json:"category_groups"`
@@ -65,24 +74,26 @@ func (client *APIClient) GetCategories(budgetId string) (CategoryGroupsWithCateg
 	}{}
 
 	response, err := client.Client.R().
+		SetContext(ctx).
+		SetQueryParams(getDeltaQueryParams(lastKnowledgeOfServer)).
 		SetResult(&categoriesResponse).
 		Get(fmt.Sprintf("budgets/%s/categories", budgetId))
 
 	if err = client.ValidateResponse(response, err); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return categoriesResponse.Data.CategoryGroups, nil
+	return categoriesResponse.Data.CategoryGroups, categoriesResponse.Data.ServerKnowledge, nil
 }
 
-// GetMonthlyExpensesCategories fetches the YNAB categories related to monthly expenses
-func (categoryGroups *CategoryGroupsWithCategories) GetMonthlyExpensesCategories() []Category {
+// GetMonthlyExpensesCategories fetches the YNAB categories related to monthly expenses, which belong to the given category group
+func (categoryGroups *CategoryGroupsWithCategories) GetMonthlyExpensesCategories(categoryGroupName string) []Category {
 	var monthlyExpensesCategories []Category
 
 	for _, categoryGroup := range *categoryGroups {
-		if strings.Contains(categoryGroup.Name, "Obligatory Monthly Expenses") {
+		if strings.Contains(categoryGroup.Name, categoryGroupName) {
 			for _, category := range categoryGroup.Categories {
-				if !category.Hidden && !strings.Contains(category.Name, "Bank Fees") {
+				if !category.Hidden && !category.Deleted {
 					monthlyExpensesCategories = append(monthlyExpensesCategories, category)
 				}
 			}
@@ -0,0 +1,524 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the version of the household configuration schema supported by the application
+const CurrentConfigVersion int = 1
+
+// ConfigPathEnvironmentVariable is the environment variable that overrides the location of the household configuration file
+const ConfigPathEnvironmentVariable string = "YNAB_MONTHLY_EXPENSES_CONFIG"
+
+// APIKeyEnvironmentVariable is the environment variable that overrides the API key of the local HTTP API server
+const APIKeyEnvironmentVariable string = "YNAB_MONTHLY_EXPENSES_API_KEY"
+
+// DefaultServerAddress is the address the local HTTP API server listens on, which is only reachable from the same machine
+const DefaultServerAddress string = "127.0.0.1:8787"
+
+// ApplicationDirectoryName is the name of the directory, under the user configuration directory, where the application stores its files
+const ApplicationDirectoryName string = "ynab-monthly-expenses-manager"
+
+// Config represents the household configuration, declaring the YNAB budgets, accounts, participants, categories, payees and memo rules
+type Config struct {
+	Version      int                 `yaml:"version" json:"version"`
+	Shared       SharedConfig        `yaml:"shared" json:"shared"`
+	Participants []ParticipantConfig `yaml:"participants" json:"participants"`
+	Categories   CategoriesConfig    `yaml:"categories" json:"categories"`
+	Rounding     RoundingConfig      `yaml:"rounding" json:"rounding"`
+	Server       ServerConfig        `yaml:"server" json:"server"`
+	OAuth        OAuthConfig         `yaml:"oauth" json:"oauth"`
+	YNAB         YNABConfig          `yaml:"ynab" json:"ynab"`
+}
+
+// SharedConfig represents the YNAB budget and account designated for the shared monthly expenses
+type SharedConfig struct {
+	Budget  string `yaml:"budget" json:"budget"`
+	Account string `yaml:"account" json:"account"`
+}
+
+// ParticipantConfig represents a member of the household and, optionally, the YNAB budget and account designated for their individual share
+// The income is only required by categories split in proportion to income
+type ParticipantConfig struct {
+	Name    string          `yaml:"name" json:"name"`
+	Budget  string          `yaml:"budget" json:"budget"`
+	Account string          `yaml:"account" json:"account"`
+	Income  decimal.Decimal `yaml:"income" json:"income"`
+}
+
+// CategoriesConfig represents the YNAB category group holding the monthly expenses categories and the expenses within it
+type CategoriesConfig struct {
+	Group    string          `yaml:"group" json:"group"`
+	Expenses []ExpenseConfig `yaml:"expenses" json:"expenses"`
+}
+
+// ExpenseConfig represents a monthly expense category with its payee, memo rule and split rule
+type ExpenseConfig struct {
+	Name  string      `yaml:"name" json:"name"`
+	Payee string      `yaml:"payee" json:"payee"`
+	Memo  MemoConfig  `yaml:"memo" json:"memo"`
+	Split SplitConfig `yaml:"split" json:"split"`
+}
+
+// MemoConfig represents the rule used to generate the memo of a monthly expense transaction
+type MemoConfig struct {
+	Rule          string               `yaml:"rule" json:"rule"`
+	Text          string               `yaml:"text" json:"text"`
+	BillingCycles []BillingCycleConfig `yaml:"billing_cycles" json:"billing_cycles"`
+}
+
+// SplitConfig represents the rule used to split a monthly expense among the participants, which defaults to an equal split
+// Percentages and fixed amounts are declared per participant name
+type SplitConfig struct {
+	Rule        string                     `yaml:"rule" json:"rule"`
+	Percentages map[string]decimal.Decimal `yaml:"percentages" json:"percentages"`
+	Amounts     map[string]decimal.Decimal `yaml:"amounts" json:"amounts"`
+}
+
+// RoundingConfig represents the strategy used to distribute the cents left over by rounding down the individual shares, which defaults to the rounding ledger
+// The payer is only required by the favour_payer strategy
+type RoundingConfig struct {
+	Strategy string `yaml:"strategy" json:"strategy"`
+	Payer    string `yaml:"payer" json:"payer"`
+}
+
+// ServerConfig represents the local HTTP API server, which listens on DefaultServerAddress unless another address is declared
+// The API key may instead be set in the environment variable named by APIKeyEnvironmentVariable, which takes precedence
+type ServerConfig struct {
+	Address string `yaml:"address" json:"address"`
+	APIKey  string `yaml:"api_key" json:"api_key"`
+}
+
+// OAuthConfig represents the YNAB OAuth application used to authorize with a YNAB login instead of a Personal Access Token
+// The authorization and token URLs default to YNAB's, and the redirect address is required with a fixed port, as YNAB only redirects to the redirect URI registered with the application
+type OAuthConfig struct {
+	ClientId        string `yaml:"client_id" json:"client_id"`
+	ClientSecret    string `yaml:"client_secret" json:"client_secret"`
+	AuthorizeURL    string `yaml:"authorize_url" json:"authorize_url"`
+	TokenURL        string `yaml:"token_url" json:"token_url"`
+	RedirectAddress string `yaml:"redirect_address" json:"redirect_address"`
+}
+
+// YNABConfig represents how the YNAB API is reached, e.g. through a corporate proxy or at a local stand-in server
+// Every field is optional: the base URL defaults to DefaultYNABBaseURL, or to the environment variable named by YNABBaseURLEnvironmentVariable, which takes precedence,
+// the proxy defaults to the HTTP_PROXY and HTTPS_PROXY environment variables and the CA bundle is trusted in addition to the system certificates
+type YNABConfig struct {
+	BaseURL        string `yaml:"base_url" json:"base_url"`
+	Timeout        string `yaml:"timeout" json:"timeout"`
+	ConnectTimeout string `yaml:"connect_timeout" json:"connect_timeout"`
+	Proxy          string `yaml:"proxy" json:"proxy"`
+	CABundle       string `yaml:"ca_bundle" json:"ca_bundle"`
+	UserAgent      string `yaml:"user_agent" json:"user_agent"`
+}
+
+// BillingCycleConfig represents a billing cycle running from a day of the past month to a day of the current month
+type BillingCycleConfig struct {
+	Start int `yaml:"start" json:"start"`
+	End   int `yaml:"end" json:"end"`
+}
+
+// Memo rules supported by MemoConfig
+const (
+	MemoRuleNone         string = "none"
+	MemoRuleText         string = "text"
+	MemoRuleCurrentMonth string = "current_month"
+	MemoRuleNextMonth    string = "next_month"
+	MemoRuleBillingCycle string = "billing_cycle"
+)
+
+// ConfigError represents a household configuration that failed schema validation, listing every problem found
+type ConfigError struct {
+	Path     string
+	Problems []string
+}
+
+// Error returns a message listing every problem found in the household configuration
+func (configError *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration %s:\n  - %s", configError.Path, strings.Join(configError.Problems, "\n  - "))
+}
+
+// ApplicationDirectory returns the directory where the application stores its files
+func ApplicationDirectory() (string, error) {
+	userConfigDirectory, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userConfigDirectory, ApplicationDirectoryName), nil
+}
+
+// writeFileAtomically replaces a file of the application directory with the given data, written to a temporary file and synced to disk first, so that a failed write or a crash never leaves the file half-written
+func writeFileAtomically(path string, data []byte, perm os.FileMode) error {
+	temporaryPath := path + ".tmp"
+
+	temporaryFile, err := os.OpenFile(temporaryPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = temporaryFile.Write(data)
+	if err == nil {
+		err = temporaryFile.Sync()
+	}
+	if closeErr := temporaryFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	return os.Rename(temporaryPath, path)
+}
+
+// ConfigPath returns the location of the household configuration file
+func ConfigPath() string {
+	if configPath := os.Getenv(ConfigPathEnvironmentVariable); configPath != "" {
+		return configPath
+	}
+
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "config.yaml"
+	}
+
+	return filepath.Join(applicationDirectory, "config.yaml")
+}
+
+// LoadConfig reads, decodes and validates the household configuration file, which may be written in YAML or JSON
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration: %w", err)
+	}
+
+	var config Config
+
+	if strings.EqualFold(filepath.Ext(configPath), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+	}
+
+	if err != nil {
+		return nil, &ConfigError{Path: configPath, Problems: []string{err.Error()}}
+	}
+
+	if err = config.Validate(); err != nil {
+		var configError *ConfigError
+		if errors.As(err, &configError) {
+			configError.Path = configPath
+		}
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Validate checks the household configuration against the schema, returning a ConfigError listing every problem found
+func (config *Config) Validate() error {
+	var problems []string
+
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case config.Version == 0:
+		addProblem("version: is required")
+	case config.Version != CurrentConfigVersion:
+		addProblem("version: %d is not supported, expected %d", config.Version, CurrentConfigVersion)
+	}
+
+	if config.Shared.Budget == "" {
+		addProblem("shared.budget: is required")
+	}
+	if config.Shared.Account == "" {
+		addProblem("shared.account: is required")
+	}
+
+	if len(config.Participants) < 2 {
+		addProblem("participants: at least 2 participants are required, got %d", len(config.Participants))
+	}
+
+	participantNames := make(map[string]bool)
+
+	for index, participant := range config.Participants {
+		if participant.Name == "" {
+			addProblem("participants[%d].name: is required", index)
+		} else if participantNames[participant.Name] {
+			addProblem("participants[%d].name: %q is declared more than once", index, participant.Name)
+		}
+		participantNames[participant.Name] = true
+
+		if (participant.Budget == "") != (participant.Account == "") {
+			addProblem("participants[%d]: budget and account must be declared together", index)
+		}
+
+		if participant.Income.IsNegative() {
+			addProblem("participants[%d].income: must not be negative", index)
+		}
+	}
+
+	if config.Categories.Group == "" {
+		addProblem("categories.group: is required")
+	}
+	if len(config.Categories.Expenses) == 0 {
+		addProblem("categories.expenses: at least 1 expense is required")
+	}
+
+	expenseNames := make(map[string]bool)
+
+	for index, expense := range config.Categories.Expenses {
+		if expense.Name == "" {
+			addProblem("categories.expenses[%d].name: is required", index)
+		} else if expenseNames[expense.Name] {
+			addProblem("categories.expenses[%d].name: %q is declared more than once", index, expense.Name)
+		}
+		expenseNames[expense.Name] = true
+
+		if expense.Payee == "" {
+			addProblem("categories.expenses[%d].payee: is required", index)
+		}
+
+		for _, problem := range expense.Memo.validate() {
+			addProblem("categories.expenses[%d].memo%s", index, problem)
+		}
+
+		for _, problem := range expense.Split.validate(config.Participants) {
+			addProblem("categories.expenses[%d].split%s", index, problem)
+		}
+	}
+
+	switch config.Rounding.Strategy {
+	case "", RoundingStrategyLedger, RoundingStrategyAlternating, RoundingStrategyLargestRemainder, RoundingStrategyBankers:
+	case RoundingStrategyFavourPayer:
+		if !participantNames[config.Rounding.Payer] {
+			addProblem("rounding.payer: %q is not a participant", config.Rounding.Payer)
+		}
+	default:
+		addProblem("rounding.strategy: %q is not one of %s, %s, %s, %s or %s", config.Rounding.Strategy,
+			RoundingStrategyLedger, RoundingStrategyAlternating, RoundingStrategyLargestRemainder, RoundingStrategyBankers, RoundingStrategyFavourPayer)
+	}
+
+	if config.Server.Address != "" {
+		if _, _, err := net.SplitHostPort(config.Server.Address); err != nil {
+			addProblem("server.address: %q must be formatted as <host>:<port>", config.Server.Address)
+		}
+	}
+
+	if (config.OAuth.ClientId == "") != (config.OAuth.ClientSecret == "") {
+		addProblem("oauth: client_id and client_secret must be declared together")
+	}
+	if config.OAuth.ClientId != "" || config.OAuth.RedirectAddress != "" {
+		host, port, err := net.SplitHostPort(config.OAuth.RedirectAddress)
+		portNumber, portErr := strconv.Atoi(port)
+		hostIP := net.ParseIP(host)
+
+		switch {
+		case config.OAuth.RedirectAddress == "":
+			addProblem("oauth.redirect_address: is required, matching the redirect URI registered with the YNAB OAuth application")
+		case err != nil:
+			addProblem("oauth.redirect_address: %q must be formatted as <host>:<port>", config.OAuth.RedirectAddress)
+		case host != "localhost" && (hostIP == nil || !hostIP.IsLoopback()):
+			addProblem("oauth.redirect_address: %q must be a loopback address, e.g. 127.0.0.1 or localhost, so that no other machine can reach the OAuth redirect", config.OAuth.RedirectAddress)
+		case portErr != nil || portNumber <= 0:
+			addProblem("oauth.redirect_address: %q must have a fixed port, matching the redirect URI registered with the YNAB OAuth application", config.OAuth.RedirectAddress)
+		}
+	}
+
+	for _, problem := range config.YNAB.validate() {
+		addProblem("ynab%s", problem)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+
+	return nil
+}
+
+// validate checks a memo rule, returning the problems found prefixed by the offending field
+func (memo *MemoConfig) validate() []string {
+	var problems []string
+
+	switch memo.Rule {
+	case "", MemoRuleNone, MemoRuleCurrentMonth, MemoRuleNextMonth:
+	case MemoRuleText:
+		if memo.Text == "" {
+			problems = append(problems, ".text: is required by the text rule")
+		}
+	case MemoRuleBillingCycle:
+		if len(memo.BillingCycles) == 0 {
+			problems = append(problems, ".billing_cycles: at least 1 billing cycle is required by the billing_cycle rule")
+		}
+		for index, billingCycle := range memo.BillingCycles {
+			if billingCycle.Start < 1 || billingCycle.Start > 31 {
+				problems = append(problems, fmt.Sprintf(".billing_cycles[%d].start: %d is not a day of the month", index, billingCycle.Start))
+			}
+			if billingCycle.End < 1 || billingCycle.End > 31 {
+				problems = append(problems, fmt.Sprintf(".billing_cycles[%d].end: %d is not a day of the month", index, billingCycle.End))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(".rule: %q is not one of %s, %s, %s, %s or %s",
+			memo.Rule, MemoRuleNone, MemoRuleText, MemoRuleCurrentMonth, MemoRuleNextMonth, MemoRuleBillingCycle))
+	}
+
+	return problems
+}
+
+// validate checks how the YNAB API is reached, returning the problems found prefixed by the offending field
+func (ynabConfig *YNABConfig) validate() []string {
+	var problems []string
+
+	validateURL := func(field string, value string, schemes ...string) {
+		if value == "" {
+			return
+		}
+		if parsedURL, err := url.Parse(value); err != nil || parsedURL.Host == "" || !slices.Contains(schemes, parsedURL.Scheme) {
+			problems = append(problems, fmt.Sprintf(".%s: %q must be an absolute URL with one of the schemes %s", field, value, strings.Join(schemes, ", ")))
+		}
+	}
+
+	validateDuration := func(field string, value string) {
+		if value == "" {
+			return
+		}
+		if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+			problems = append(problems, fmt.Sprintf(".%s: %q must be a positive duration, e.g. 30s", field, value))
+		}
+	}
+
+	validateURL("base_url", ynabConfig.BaseURL, "http", "https")
+	validateDuration("timeout", ynabConfig.Timeout)
+	validateDuration("connect_timeout", ynabConfig.ConnectTimeout)
+	validateURL("proxy", ynabConfig.Proxy, "http", "https", "socks5")
+
+	return problems
+}
+
+// validate checks a split rule against the participants, returning the problems found prefixed by the offending field
+func (split *SplitConfig) validate(participants []ParticipantConfig) []string {
+	var problems []string
+
+	participantNames := make(map[string]bool, len(participants))
+	for _, participant := range participants {
+		participantNames[participant.Name] = true
+	}
+
+	validateParticipantNames := func(field string, values map[string]decimal.Decimal) {
+		for participantName, value := range values {
+			if !participantNames[participantName] {
+				problems = append(problems, fmt.Sprintf(".%s: %q is not a participant", field, participantName))
+			}
+			if value.IsNegative() {
+				problems = append(problems, fmt.Sprintf(".%s.%s: must not be negative", field, participantName))
+			}
+		}
+	}
+
+	switch split.Rule {
+	case "", SplitRuleEqual:
+	case SplitRulePercentages:
+		validateParticipantNames("percentages", split.Percentages)
+		if total := decimal.Sum(decimal.Zero, maps.Values(split.Percentages)...); !total.Equal(decimal.NewFromInt(100)) {
+			problems = append(problems, fmt.Sprintf(".percentages: must add up to 100, got %s", total))
+		}
+	case SplitRuleFixed:
+		validateParticipantNames("amounts", split.Amounts)
+		for participantName, amount := range split.Amounts {
+			if !amount.Equal(amount.Round(2)) {
+				problems = append(problems, fmt.Sprintf(".amounts.%s: %s has more than 2 decimal places", participantName, amount))
+			}
+		}
+		if len(split.Amounts) == 0 || len(split.Amounts) >= len(participants) {
+			problems = append(problems, ".amounts: at least 1 participant must have a fixed amount and at least 1 must share the remainder")
+		}
+	case SplitRuleIncome:
+		for index, participant := range participants {
+			if !participant.Income.IsPositive() {
+				problems = append(problems, fmt.Sprintf(".rule: the income rule requires participants[%d].income to be declared", index))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(".rule: %q is not one of %s, %s, %s or %s",
+			split.Rule, SplitRuleEqual, SplitRulePercentages, SplitRuleFixed, SplitRuleIncome))
+	}
+
+	slices.Sort(problems)
+
+	return problems
+}
+
+// GetParticipantNames returns the names of the participants in the order they are declared
+func (config *Config) GetParticipantNames() []string {
+	participantNames := make([]string, 0, len(config.Participants))
+
+	for _, participant := range config.Participants {
+		participantNames = append(participantNames, participant.Name)
+	}
+
+	return participantNames
+}
+
+// GetCategoryNames returns the names of the monthly expenses categories in the order they are declared
+func (config *Config) GetCategoryNames() []string {
+	categoryNames := make([]string, 0, len(config.Categories.Expenses))
+
+	for _, expense := range config.Categories.Expenses {
+		categoryNames = append(categoryNames, expense.Name)
+	}
+
+	return categoryNames
+}
+
+// GetBudgetNames returns the names of the shared budget and of the individual budgets declared by the participants, each only once
+func (config *Config) GetBudgetNames() []string {
+	budgetNames := []string{config.Shared.Budget}
+
+	for _, participant := range config.Participants {
+		if participant.Budget != "" && !slices.Contains(budgetNames, participant.Budget) {
+			budgetNames = append(budgetNames, participant.Budget)
+		}
+	}
+
+	return budgetNames
+}
+
+// GetServerAddress returns the address the local HTTP API server listens on
+func (config *Config) GetServerAddress() string {
+	if config.Server.Address == "" {
+		return DefaultServerAddress
+	}
+
+	return config.Server.Address
+}
+
+// GetAPIKey returns the API key of the local HTTP API server, preferring the environment variable over the configuration file
+func (config *Config) GetAPIKey() string {
+	if apiKey := os.Getenv(APIKeyEnvironmentVariable); apiKey != "" {
+		return apiKey
+	}
+
+	return config.Server.APIKey
+}
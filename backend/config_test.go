@@ -0,0 +1,207 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validConfig = `
+version: 1
+shared:
+  budget: "Casa Reis-Pereira"
+  account: "Millennium bcp"
+participants:
+  - name: "Magui"
+    budget: "Magui"
+    account: "CGD"
+  - name: "Jão"
+categories:
+  group: "Obligatory Monthly Expenses"
+  expenses:
+    - name: "Electricity"
+      payee: "EDP"
+      memo:
+        rule: billing_cycle
+        billing_cycles:
+          - { start: 11, end: 10 }
+`
+
+func TestLoadConfig(t *testing.T) {
+	testCases := map[string]struct {
+		fileName         string
+		contents         string
+		expectedProblems []string
+	}{
+		"valid yaml configuration": {
+			fileName: "config.yaml",
+			contents: validConfig,
+		},
+		"valid json configuration": {
+			fileName: "config.json",
+			contents: `{
+				"version": 1,
+				"shared": {"budget": "Casa Reis-Pereira", "account": "Millennium bcp"},
+				"participants": [{"name": "Magui", "budget": "Magui", "account": "CGD"}, {"name": "Jão"}],
+				"categories": {"group": "Obligatory Monthly Expenses", "expenses": [{"name": "Water", "payee": "EPAL", "memo": {"rule": "next_month"}}]}
+			}`,
+		},
+		"unknown field": {
+			fileName:         "config.yaml",
+			contents:         validConfig + "currency: EUR\n",
+			expectedProblems: []string{"yaml: unmarshal errors:\n  line 20: field currency not found in type backend.Config"},
+		},
+		"invalid configuration": {
+			fileName: "config.yaml",
+			contents: `
+version: 2
+shared:
+  budget: "Casa Reis-Pereira"
+participants:
+  - name: "Magui"
+    budget: "Magui"
+  - name: "Magui"
+categories:
+  group: "Obligatory Monthly Expenses"
+  expenses:
+    - name: "Electricity"
+      memo:
+        rule: billing_cycle
+        billing_cycles:
+          - { start: 0, end: 10 }
+    - name: "Electricity"
+      payee: "EDP"
+      memo:
+        rule: weekly
+rounding:
+  strategy: favour_payer
+  payer: "Joana"
+server:
+  address: "8787"
+oauth:
+  client_id: "ynab-monthly-expenses-manager"
+ynab:
+  base_url: "api.ynab.com/v1"
+  timeout: "30"
+  proxy: "proxy.example.com:3128"
+`,
+			expectedProblems: []string{
+				"version: 2 is not supported, expected 1",
+				"shared.account: is required",
+				"participants[0]: budget and account must be declared together",
+				"participants[1].name: \"Magui\" is declared more than once",
+				"categories.expenses[0].payee: is required",
+				"categories.expenses[0].memo.billing_cycles[0].start: 0 is not a day of the month",
+				"categories.expenses[1].name: \"Electricity\" is declared more than once",
+				"categories.expenses[1].memo.rule: \"weekly\" is not one of none, text, current_month, next_month or billing_cycle",
+				"rounding.payer: \"Joana\" is not a participant",
+				"server.address: \"8787\" must be formatted as <host>:<port>",
+				"oauth: client_id and client_secret must be declared together",
+				"oauth.redirect_address: is required, matching the redirect URI registered with the YNAB OAuth application",
+				"ynab.base_url: \"api.ynab.com/v1\" must be an absolute URL with one of the schemes http, https",
+				"ynab.timeout: \"30\" must be a positive duration, e.g. 30s",
+				"ynab.proxy: \"proxy.example.com:3128\" must be an absolute URL with one of the schemes http, https, socks5",
+			},
+		},
+		"oauth redirect address without a fixed port": {
+			fileName:         "config.yaml",
+			contents:         validConfig + "oauth:\n  client_id: \"client\"\n  client_secret: \"secret\"\n  redirect_address: \"127.0.0.1:0\"\n",
+			expectedProblems: []string{"oauth.redirect_address: \"127.0.0.1:0\" must have a fixed port, matching the redirect URI registered with the YNAB OAuth application"},
+		},
+		"oauth redirect address on every interface": {
+			fileName:         "config.yaml",
+			contents:         validConfig + "oauth:\n  client_id: \"client\"\n  client_secret: \"secret\"\n  redirect_address: \"0.0.0.0:8788\"\n",
+			expectedProblems: []string{"oauth.redirect_address: \"0.0.0.0:8788\" must be a loopback address, e.g. 127.0.0.1 or localhost, so that no other machine can reach the OAuth redirect"},
+		},
+		"invalid split rules": {
+			fileName: "config.yaml",
+			contents: `
+version: 1
+shared:
+  budget: "Casa Reis-Pereira"
+  account: "Millennium bcp"
+participants:
+  - name: "Magui"
+    income: 2000
+  - name: "Jão"
+categories:
+  group: "Obligatory Monthly Expenses"
+  expenses:
+    - name: "Condominium"
+      payee: "Loja do Condomínio"
+      split:
+        rule: percentages
+        percentages: { Magui: 60, Joana: 30 }
+    - name: "Electricity"
+      payee: "EDP"
+      split:
+        rule: fixed
+        amounts: { Magui: 20.005, Jão: 10 }
+    - name: "Water"
+      payee: "EPAL"
+      split:
+        rule: income
+`,
+			expectedProblems: []string{
+				"categories.expenses[0].split.percentages: \"Joana\" is not a participant",
+				"categories.expenses[0].split.percentages: must add up to 100, got 90",
+				"categories.expenses[1].split.amounts.Magui: 20.005 has more than 2 decimal places",
+				"categories.expenses[1].split.amounts: at least 1 participant must have a fixed amount and at least 1 must share the remainder",
+				"categories.expenses[2].split.rule: the income rule requires participants[1].income to be declared",
+			},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), testCase.fileName)
+			assert.NoError(t, os.WriteFile(configPath, []byte(testCase.contents), 0600))
+
+			config, err := LoadConfig(configPath)
+
+			if testCase.expectedProblems == nil {
+				assert.NoError(t, err, "Expected configuration to be valid")
+				assert.Len(t, config.Participants, 2)
+				return
+			}
+
+			configError, ok := err.(*ConfigError)
+			assert.True(t, ok, fmt.Sprintf("Expected a configuration error, but got %v", err))
+			assert.Equal(t, configPath, configError.Path)
+			assert.Equal(t, testCase.expectedProblems, configError.Problems)
+		})
+	}
+}
+
+func TestGetSharedMonthlyExpenseMemo(t *testing.T) {
+	memo := GetSharedMonthlyExpenseMemo(MemoConfig{
+		Rule:          MemoRuleBillingCycle,
+		BillingCycles: []BillingCycleConfig{{Start: 9, End: 8}, {Start: 16, End: 15}},
+	})
+
+	expectedMemo := fmt.Sprintf("%s & %s",
+		getSharedMonthlyExpenseBillingCycleMemo(9, 8),
+		strings.Split(getSharedMonthlyExpenseBillingCycleMemo(16, 15), "- ")[1],
+	)
+
+	assert.Equal(t, expectedMemo, memo, "Expected billing cycles to be joined into a single memo")
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	assert.NoError(t, os.WriteFile(path, []byte("previous"), 0600))
+
+	assert.NoError(t, writeFileAtomically(path, []byte("current"), 0600))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "current", string(data))
+	assert.NoFileExists(t, path+".tmp")
+
+	err = writeFileAtomically(filepath.Join(t.TempDir(), "missing", "outbox.json"), []byte("current"), 0600)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/forPelevin/gomoji"
+)
+
+// Statuses of a diagnostic check
+const (
+	DiagnosticStatusPass    string = "pass"
+	DiagnosticStatusWarning string = "warning"
+	DiagnosticStatusFail    string = "fail"
+	DiagnosticStatusSkipped string = "skipped"
+)
+
+// Names of the diagnostic checks not specific to a YNAB budget
+const (
+	DiagnosticCheckConfiguration string = "Configuration"
+	DiagnosticCheckRounding      string = "Rounding"
+	DiagnosticCheckOutbox        string = "Outbox"
+	DiagnosticCheckAccessToken   string = "Access token"
+	DiagnosticCheckNetwork       string = "Network"
+	DiagnosticCheckAuthorization string = "YNAB authorization"
+	DiagnosticCheckBudgets       string = "Budgets"
+)
+
+// DiagnosticCheck represents one of the checks run while setting up the backend, with what to do about it if it failed
+type DiagnosticCheck struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// Diagnostics represents the report of every check run while setting up the backend
+// The backend is ready when every check passed and the monthly expenses can be imported
+type Diagnostics struct {
+	Ready  bool              `json:"ready"`
+	Checks []DiagnosticCheck `json:"checks"`
+}
+
+// pass adds a passed check to the report
+func (diagnostics *Diagnostics) pass(name string, format string, args ...interface{}) {
+	diagnostics.Checks = append(diagnostics.Checks, DiagnosticCheck{
+		Name:    name,
+		Status:  DiagnosticStatusPass,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// warn adds a check that passed with a caveat to the report, which does not keep the backend from being ready
+func (diagnostics *Diagnostics) warn(name string, message string, remediation string) {
+	diagnostics.Checks = append(diagnostics.Checks, DiagnosticCheck{
+		Name:        name,
+		Status:      DiagnosticStatusWarning,
+		Message:     message,
+		Remediation: remediation,
+	})
+}
+
+// fail adds a failed check to the report, with what to do about it
+func (diagnostics *Diagnostics) fail(name string, message string, remediation string) {
+	diagnostics.Checks = append(diagnostics.Checks, DiagnosticCheck{
+		Name:        name,
+		Status:      DiagnosticStatusFail,
+		Message:     message,
+		Remediation: remediation,
+	})
+}
+
+// skip adds a check that could not run to the report, because a check it depends on failed
+func (diagnostics *Diagnostics) skip(name string, message string) {
+	diagnostics.Checks = append(diagnostics.Checks, DiagnosticCheck{
+		Name:    name,
+		Status:  DiagnosticStatusSkipped,
+		Message: message,
+	})
+}
+
+// GetFailedChecks returns the checks that failed
+func (diagnostics *Diagnostics) GetFailedChecks() []DiagnosticCheck {
+	failedChecks := []DiagnosticCheck{}
+
+	for _, check := range diagnostics.Checks {
+		if check.Status == DiagnosticStatusFail {
+			failedChecks = append(failedChecks, check)
+		}
+	}
+
+	return failedChecks
+}
+
+// getAccessTokenRemediation explains what to do when the YNAB Personal Access Token could not be loaded
+func getAccessTokenRemediation(err error) string {
+	switch {
+	case errors.Is(err, ErrAccessTokenMissing):
+		return fmt.Sprintf("Enter a YNAB Personal Access Token, or sign in with YNAB if an OAuth application is configured, or set the %s environment variable", AccessTokenEnvironmentVariable)
+	case errors.Is(err, ErrVaultLocked), errors.Is(err, ErrVaultPassphrase):
+		return fmt.Sprintf("Unlock the vault with its passphrase, or set the %s environment variable", VaultPassphraseEnvironmentVariable)
+	default:
+		return "Fix the problem with the saved access token, or enter it again"
+	}
+}
+
+// isNetworkError checks if a YNAB API request failed before getting a response because YNAB could not be reached: a failed connection, a failed DNS lookup or a timeout
+// TLS errors are not, as a certificate that cannot be verified fails the same way however many times it is retried
+func isNetworkError(err error) bool {
+	if isTLSError(err) {
+		return false
+	}
+
+	var opError *net.OpError
+	var dnsError *net.DNSError
+	var netError net.Error
+
+	return errors.As(err, &opError) || errors.As(err, &dnsError) || (errors.As(err, &netError) && netError.Timeout())
+}
+
+// isTLSError checks if a YNAB API request failed because no secure connection could be established with YNAB, e.g. because its certificate could not be verified
+func isTLSError(err error) bool {
+	var certificateVerificationError *tls.CertificateVerificationError
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var certificateInvalidError x509.CertificateInvalidError
+	var alertError tls.AlertError
+	var recordHeaderError tls.RecordHeaderError
+
+	return errors.As(err, &certificateVerificationError) || errors.As(err, &unknownAuthorityError) || errors.As(err, &hostnameError) ||
+		errors.As(err, &certificateInvalidError) || errors.As(err, &alertError) || errors.As(err, &recordHeaderError)
+}
+
+// checkMonthlyExpensesBudget checks that the YNAB budget, account and categories declared for the monthly expenses of the shared budget or of a participant exist
+// It returns the budget, the account and the id of each monthly expense category by name, leaving out whatever was not found
+func (backend *Backend) checkMonthlyExpensesBudget(owner string, budgets Budgets, budgetName string, accountName string) (BudgetSummary, Account, map[string]*string) {
+	diagnostics := backend.Diagnostics
+	config := backend.Config
+
+	budgetCheck, accountCheck, categoriesCheck := owner+" budget", owner+" account", owner+" categories"
+
+	budget := budgets.GetBudget(budgetName)
+	if budget.Id == "" {
+		budgetNames := make([]string, 0, len(budgets))
+		for _, availableBudget := range budgets {
+			budgetNames = append(budgetNames, gomoji.RemoveEmojis(availableBudget.Name))
+		}
+
+		diagnostics.fail(budgetCheck, fmt.Sprintf("Budget %q was not found in YNAB", budgetName),
+			fmt.Sprintf("Declare the name of one of the YNAB budgets in the configuration: %s", strings.Join(budgetNames, ", ")))
+		diagnostics.skip(accountCheck, "The budget was not found")
+		diagnostics.skip(categoriesCheck, "The budget was not found")
+		return BudgetSummary{}, Account{}, map[string]*string{}
+	}
+	diagnostics.pass(budgetCheck, "Budget %q found", budgetName)
+
+	account := budget.Accounts.GetMonthlyExpensesAccount(accountName)
+	if account.Id == "" {
+		message := fmt.Sprintf("Account %q was not found in budget %q", accountName, budgetName)
+		remediation := "Declare the name of an open account of the budget in the configuration"
+
+		for _, budgetAccount := range budget.Accounts {
+			if budgetAccount.Name == accountName && budgetAccount.Closed {
+				message = fmt.Sprintf("Account %q of budget %q is closed", accountName, budgetName)
+				remediation = "Reopen the account in YNAB, or declare the name of an open account of the budget in the configuration"
+			}
+		}
+
+		diagnostics.fail(accountCheck, message, remediation)
+	} else {
+		diagnostics.pass(accountCheck, "Account %q found and open", accountName)
+	}
+
+	categories := backend.BudgetCache.GetCategories(budget.Id)
+	categoryIds := getCategoryIdsByName(categories.GetMonthlyExpensesCategories(config.Categories.Group))
+
+	var missingCategoryNames []string
+	for _, categoryName := range config.GetCategoryNames() {
+		if categoryIds[categoryName] == nil {
+			missingCategoryNames = append(missingCategoryNames, categoryName)
+		}
+	}
+
+	if len(missingCategoryNames) > 0 {
+		diagnostics.fail(categoriesCheck,
+			fmt.Sprintf("Categories %s were not found in group %q of budget %q", strings.Join(missingCategoryNames, ", "), config.Categories.Group, budgetName),
+			fmt.Sprintf("Create the categories in YNAB under group %q, or unhide them", config.Categories.Group))
+	} else {
+		diagnostics.pass(categoriesCheck, "Every category found in group %q", config.Categories.Group)
+	}
+
+	return budget, account, categoryIds
+}
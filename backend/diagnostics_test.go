@@ -0,0 +1,180 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMonthlyExpensesBudget(t *testing.T) {
+	cache := &BudgetCache{Details: map[string]*CachedBudget{
+		"shared": {CategoryGroups: CategoryGroupsWithCategories{{Name: "Obligatory Monthly Expenses", Categories: []Category{
+			{Id: "water", Name: "Water"},
+			{Id: "electricity", Name: "⚡ Electricity"},
+			{Id: "condominium", Name: "Condominium", Hidden: true},
+		}}}},
+	}}
+
+	budgets := Budgets{{
+		Id:   "shared",
+		Name: "🏠 Casa",
+		Accounts: Accounts{
+			{Id: "open", Name: "Millennium bcp"},
+			{Id: "closed", Name: "CGD", Closed: true},
+		},
+	}}
+
+	testCases := map[string]struct {
+		budgetName       string
+		accountName      string
+		categoryNames    []string
+		expectedStatuses []string
+		expectedMessages []string
+	}{
+		"every check passes": {
+			budgetName:       "Casa",
+			accountName:      "Millennium bcp",
+			categoryNames:    []string{"Water", "Electricity"},
+			expectedStatuses: []string{DiagnosticStatusPass, DiagnosticStatusPass, DiagnosticStatusPass},
+		},
+		"budget not found": {
+			budgetName:       "Magui",
+			accountName:      "Millennium bcp",
+			categoryNames:    []string{"Water"},
+			expectedStatuses: []string{DiagnosticStatusFail, DiagnosticStatusSkipped, DiagnosticStatusSkipped},
+			expectedMessages: []string{`Budget "Magui" was not found in YNAB`, "The budget was not found", "The budget was not found"},
+		},
+		"account closed and hidden category": {
+			budgetName:       "Casa",
+			accountName:      "CGD",
+			categoryNames:    []string{"Water", "Condominium", "Internet"},
+			expectedStatuses: []string{DiagnosticStatusPass, DiagnosticStatusFail, DiagnosticStatusFail},
+			expectedMessages: []string{
+				`Budget "Casa" found`,
+				`Account "CGD" of budget "Casa" is closed`,
+				`Categories Condominium, Internet were not found in group "Obligatory Monthly Expenses" of budget "Casa"`,
+			},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			config := &Config{Categories: CategoriesConfig{Group: "Obligatory Monthly Expenses"}}
+			for _, categoryName := range testCase.categoryNames {
+				config.Categories.Expenses = append(config.Categories.Expenses, ExpenseConfig{Name: categoryName})
+			}
+
+			backend := &Backend{
+				Config:      config,
+				Diagnostics: &Diagnostics{},
+				BudgetCache: cache,
+			}
+
+			_, account, categoryIds := backend.checkMonthlyExpensesBudget("Shared", budgets, testCase.budgetName, testCase.accountName)
+
+			var statuses, messages []string
+			for _, check := range backend.Diagnostics.Checks {
+				statuses = append(statuses, check.Status)
+				messages = append(messages, check.Message)
+
+				if check.Status == DiagnosticStatusFail {
+					assert.NotEmpty(t, check.Remediation, "Expected check %q to explain how to fix it", check.Name)
+				}
+			}
+
+			assert.Equal(t, testCase.expectedStatuses, statuses)
+			if testCase.expectedMessages != nil {
+				assert.Equal(t, testCase.expectedMessages, messages)
+			}
+
+			if testCase.expectedStatuses[1] == DiagnosticStatusPass {
+				assert.Equal(t, "open", account.Id)
+				assert.Len(t, categoryIds, 2)
+			}
+		})
+	}
+}
+
+func TestCheckYNABReportsUnreachableYNAB(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	backend := &Backend{
+		Config:        &Config{Shared: SharedConfig{Budget: "Casa"}},
+		Diagnostics:   &Diagnostics{},
+		BudgetService: &APIClient{Client: resty.New().SetBaseURL(server.URL)},
+		BudgetCache:   &BudgetCache{Path: filepath.Join(t.TempDir(), BudgetCacheFileName), Details: map[string]*CachedBudget{}},
+	}
+
+	budgets := backend.checkYNAB()
+
+	assert.Nil(t, budgets)
+	assert.Equal(t, DiagnosticStatusFail, backend.Diagnostics.Checks[0].Status, "Expected the network check to fail")
+	assert.Equal(t, DiagnosticCheckNetwork, backend.Diagnostics.Checks[0].Name)
+	assert.Equal(t, DiagnosticStatusSkipped, backend.Diagnostics.Checks[1].Status, "Expected the authorization check to be skipped")
+}
+
+func TestIsNetworkError(t *testing.T) {
+	requestError := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://api.ynab.com/v1/budgets", Err: err}
+	}
+
+	testCases := map[string]struct {
+		err                  error
+		expectedNetworkError bool
+		expectedUnreachable  bool
+	}{
+		"connection refused": {
+			err:                  requestError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}),
+			expectedNetworkError: true,
+			expectedUnreachable:  true,
+		},
+		"failed DNS lookup": {
+			err:                  requestError(&net.DNSError{Err: "no such host", Name: "api.ynab.com", IsNotFound: true}),
+			expectedNetworkError: true,
+			expectedUnreachable:  true,
+		},
+		"timeout": {
+			err:                  requestError(context.DeadlineExceeded),
+			expectedNetworkError: true,
+			expectedUnreachable:  true,
+		},
+		"unknown certificate authority": {
+			err: requestError(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}),
+		},
+		"certificate for another host": {
+			err: requestError(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "api.ynab.com"}),
+		},
+		"TLS alert from YNAB": {
+			err: requestError(&net.OpError{Op: "remote error", Err: tls.AlertError(42)}),
+		},
+		"unsupported protocol scheme": {
+			err: requestError(errors.New("unsupported protocol scheme \"ftp\"")),
+		},
+		"canceled request": {
+			err: requestError(context.Canceled),
+		},
+		"YNAB server error": {
+			err:                 &APIError{StatusCode: http.StatusServiceUnavailable},
+			expectedUnreachable: true,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedNetworkError, isNetworkError(testCase.err))
+			assert.Equal(t, testCase.expectedUnreachable, isUnreachable(testCase.err))
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ways of resolving the possible duplicates found when importing the monthly expenses
+// Proceeding creates every planned transaction regardless, skipping leaves out the ones that appear to be already entered, and replacing deletes the existing transactions once the import succeeds
+// Without a duplicate resolution, nothing is imported if any possible duplicate is found
+const (
+	DuplicateResolutionProceed string = "proceed"
+	DuplicateResolutionSkip    string = "skip"
+	DuplicateResolutionReplace string = "replace"
+)
+
+// ErrPossibleDuplicates is the error of an import refused because the expenses of the month appear to be already entered in YNAB, until a duplicate resolution is given
+var ErrPossibleDuplicates = errors.New("this month's expenses appear to be already entered in YNAB")
+
+// ErrReplaceRefused is the error of an import refused because some of the existing transactions the imported ones would replace were reconciled in YNAB
+var ErrReplaceRefused = errors.New("the possible duplicates cannot be replaced")
+
+// PossibleDuplicate represents a planned YNAB transaction that appears to be already entered in YNAB, e.g. by hand, along with the existing transactions it matches
+// The formatted amounts of the existing transactions, in the same order, are included for display
+type PossibleDuplicate struct {
+	BudgetId             string              `json:"budget_id"`
+	ParticipantName      string              `json:"participant_name"`
+	Description          string              `json:"description"`
+	ImportId             string              `json:"import_id"`
+	ExistingTransactions []TransactionDetail `json:"existing_transactions"`
+	FormattedAmounts     []string            `json:"formatted_amounts"`
+}
+
+// IsValidDuplicateResolution checks if a duplicate resolution is one of the supported ones, or empty
+func IsValidDuplicateResolution(duplicateResolution string) bool {
+	switch duplicateResolution {
+	case "", DuplicateResolutionProceed, DuplicateResolutionSkip, DuplicateResolutionReplace:
+		return true
+	default:
+		return false
+	}
+}
+
+// FindPossibleDuplicates looks for the transactions already entered in YNAB in the month of the import plan that each planned transaction appears to duplicate, fetching the transactions of each YNAB budget once
+// An existing transaction is a possible duplicate when it is in the same account, has the same payee and shares a category with the planned transaction or any of its sub-transactions
+// Transactions imported before for the same month are not possible duplicates, as YNAB recognizes them by their import id and never creates them again
+func (importPlan ImportPlan) FindPossibleDuplicates(ctx context.Context, budgetService BudgetService) ([]PossibleDuplicate, error) {
+	possibleDuplicates := []PossibleDuplicate{}
+
+	for _, budgetImportPlan := range importPlan.Budgets {
+		if len(budgetImportPlan.Transactions) == 0 {
+			continue
+		}
+
+		transactions, _, err := budgetService.GetTransactions(ctx, budgetImportPlan.BudgetId, TransactionsFilter{SinceDate: importPlan.Month + "-01"})
+		if err != nil {
+			return nil, fmt.Errorf("fetching transactions of budget %s: %w", budgetImportPlan.BudgetId, err)
+		}
+
+		for _, plannedTransaction := range budgetImportPlan.Transactions {
+			existingTransactions := []TransactionDetail{}
+			for _, transaction := range transactions {
+				if transaction.isPossibleDuplicateOf(plannedTransaction.Transaction, importPlan.Month) {
+					existingTransactions = append(existingTransactions, transaction)
+				}
+			}
+
+			if len(existingTransactions) == 0 {
+				continue
+			}
+
+			possibleDuplicates = append(possibleDuplicates, PossibleDuplicate{
+				BudgetId:             budgetImportPlan.BudgetId,
+				ParticipantName:      budgetImportPlan.ParticipantName,
+				Description:          plannedTransaction.Description,
+				ImportId:             getStringOrEmpty(plannedTransaction.Transaction.ImportId),
+				ExistingTransactions: existingTransactions,
+			})
+		}
+	}
+
+	return possibleDuplicates, nil
+}
+
+// WithoutPossibleDuplicates returns a copy of the import plan leaving out the planned transactions that appear to be already entered in YNAB
+func (importPlan ImportPlan) WithoutPossibleDuplicates(possibleDuplicates []PossibleDuplicate) ImportPlan {
+	duplicateImportIds := make(map[string]bool, len(possibleDuplicates))
+	for _, possibleDuplicate := range possibleDuplicates {
+		duplicateImportIds[possibleDuplicate.ImportId] = true
+	}
+
+	filteredImportPlan := ImportPlan{Month: importPlan.Month, Attempt: importPlan.Attempt, Budgets: []BudgetImportPlan{}}
+
+	for _, budgetImportPlan := range importPlan.Budgets {
+		filteredBudgetImportPlan := budgetImportPlan
+		filteredBudgetImportPlan.Transactions = []PlannedTransaction{}
+
+		for _, plannedTransaction := range budgetImportPlan.Transactions {
+			if !duplicateImportIds[getStringOrEmpty(plannedTransaction.Transaction.ImportId)] {
+				filteredBudgetImportPlan.Transactions = append(filteredBudgetImportPlan.Transactions, plannedTransaction)
+			}
+		}
+
+		filteredImportPlan.Budgets = append(filteredImportPlan.Budgets, filteredBudgetImportPlan)
+	}
+
+	return filteredImportPlan
+}
+
+// formatPossibleDuplicateAmounts formats the amount of every existing transaction of the possible duplicates according to the currency format of its YNAB budget
+func formatPossibleDuplicateAmounts(possibleDuplicates []PossibleDuplicate, currencyFormats map[string]CurrencyFormat) {
+	for index := range possibleDuplicates {
+		possibleDuplicate := &possibleDuplicates[index]
+
+		possibleDuplicate.FormattedAmounts = []string{}
+		for _, transaction := range possibleDuplicate.ExistingTransactions {
+			possibleDuplicate.FormattedAmounts = append(possibleDuplicate.FormattedAmounts, currencyFormats[possibleDuplicate.BudgetId].Format(transaction.Amount))
+		}
+	}
+}
+
+// skipPossibleDuplicates lists the planned transactions left out as possible duplicates among the transactions already present in each YNAB budget
+func (importResult *ImportResult) skipPossibleDuplicates(possibleDuplicates []PossibleDuplicate) {
+	for index := range importResult.Budgets {
+		budgetImportResult := &importResult.Budgets[index]
+
+		for _, possibleDuplicate := range possibleDuplicates {
+			if possibleDuplicate.BudgetId == budgetImportResult.BudgetId {
+				budgetImportResult.AlreadyPresent = append(budgetImportResult.AlreadyPresent, possibleDuplicate.Description)
+			}
+		}
+	}
+}
+
+// getReplaceProblems describes each existing transaction of the possible duplicates that cannot be deleted to be replaced, because it was reconciled in YNAB
+func getReplaceProblems(possibleDuplicates []PossibleDuplicate) []string {
+	problems := []string{}
+	for _, possibleDuplicate := range possibleDuplicates {
+		for _, transaction := range possibleDuplicate.ExistingTransactions {
+			if transaction.Cleared == "reconciled" {
+				problems = append(problems, fmt.Sprintf("%s in %s was reconciled", possibleDuplicate.Description, getBudgetOwner(possibleDuplicate.ParticipantName)))
+				break
+			}
+		}
+	}
+
+	return problems
+}
+
+// replacePossibleDuplicates deletes the existing transactions the imported ones replace from each YNAB budget
+// Every transaction is attempted even if deleting another one fails, and a transaction YNAB no longer has is as good as replaced
+func (importResult *ImportResult) replacePossibleDuplicates(ctx context.Context, budgetService BudgetService, possibleDuplicates []PossibleDuplicate) error {
+	var replaceErrors []error
+	replacedTransactionIds := map[string]bool{}
+
+	for index := range importResult.Budgets {
+		budgetImportResult := &importResult.Budgets[index]
+
+		var budgetReplaceErrors []error
+		for _, possibleDuplicate := range possibleDuplicates {
+			if possibleDuplicate.BudgetId != budgetImportResult.BudgetId {
+				continue
+			}
+
+			for _, transaction := range possibleDuplicate.ExistingTransactions {
+				// An existing transaction may be the possible duplicate of several planned transactions
+				if replacedTransactionIds[transaction.Id] {
+					continue
+				}
+				replacedTransactionIds[transaction.Id] = true
+
+				if _, err := budgetService.DeleteTransaction(ctx, budgetImportResult.BudgetId, transaction.Id); err != nil && !errors.Is(err, ErrNotFound) {
+					budgetReplaceErrors = append(budgetReplaceErrors,
+						fmt.Errorf("deleting transaction %s from budget %s: %w", transaction.Id, budgetImportResult.BudgetId, err))
+					continue
+				}
+				budgetImportResult.ReplacedTransactionIds = append(budgetImportResult.ReplacedTransactionIds, transaction.Id)
+			}
+		}
+
+		if len(budgetReplaceErrors) > 0 {
+			budgetImportResult.Error = errors.Join(budgetReplaceErrors...).Error()
+			replaceErrors = append(replaceErrors, budgetReplaceErrors...)
+		}
+	}
+
+	return errors.Join(replaceErrors...)
+}
+
+// isPossibleDuplicateOf checks if an existing YNAB transaction of a month (formatted as YYYY-MM) appears to be the planned transaction entered by other means, e.g. by hand
+func (transaction TransactionDetail) isPossibleDuplicateOf(saveTransaction SaveTransaction, month string) bool {
+	switch {
+	case transaction.Deleted:
+		return false
+	case !strings.HasPrefix(transaction.Date, month+"-"):
+		return false
+	case transaction.AccountId != getStringOrEmpty(saveTransaction.AccountId):
+		return false
+	case saveTransaction.ImportId != nil && transaction.ImportId == *saveTransaction.ImportId:
+		return false
+	case !strings.EqualFold(strings.TrimSpace(transaction.PayeeName), strings.TrimSpace(getStringOrEmpty(saveTransaction.PayeeName))):
+		return false
+	}
+
+	plannedCategoryIds := map[string]bool{getStringOrEmpty(saveTransaction.CategoryId): true}
+	for _, subTransaction := range saveTransaction.SubTransactions {
+		plannedCategoryIds[getStringOrEmpty(subTransaction.CategoryId)] = true
+	}
+	delete(plannedCategoryIds, "")
+
+	if plannedCategoryIds[transaction.CategoryId] {
+		return true
+	}
+	for _, subTransaction := range transaction.SubTransactions {
+		if !subTransaction.Deleted && plannedCategoryIds[subTransaction.CategoryId] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+
+	"ynab-monthly-expenses-manager/backend/ynabtest"
+)
+
+func TestImportPossibleDuplicates(t *testing.T) {
+	testCases := map[string]struct {
+		duplicateResolution     string
+		cleared                 string
+		expectedSuccess         bool
+		expectedSharedCount     int
+		expectedAlreadyPresent  []string
+		expectedReplacedCount   int
+		expectedDuplicatesFound bool
+		expectedError           string
+	}{
+		"no duplicate resolution - nothing imported": {
+			expectedSharedCount:     3,
+			expectedAlreadyPresent:  []string{},
+			expectedDuplicatesFound: true,
+		},
+		"proceed - every transaction imported": {
+			duplicateResolution:    DuplicateResolutionProceed,
+			expectedSuccess:        true,
+			expectedSharedCount:    3 + 4,
+			expectedAlreadyPresent: []string{},
+		},
+		"skip - possible duplicate left out": {
+			duplicateResolution:    DuplicateResolutionSkip,
+			expectedSuccess:        true,
+			expectedSharedCount:    3 + 3,
+			expectedAlreadyPresent: []string{"Electricity"},
+		},
+		"replace - existing transaction deleted": {
+			duplicateResolution:    DuplicateResolutionReplace,
+			expectedSuccess:        true,
+			expectedSharedCount:    2 + 4,
+			expectedAlreadyPresent: []string{},
+			expectedReplacedCount:  1,
+		},
+		"replace reconciled transaction - nothing imported": {
+			duplicateResolution:    DuplicateResolutionReplace,
+			cleared:                "reconciled",
+			expectedSharedCount:    3,
+			expectedAlreadyPresent: []string{},
+			expectedError:          ErrReplaceRefused.Error() + ": Electricity in the shared budget was reconciled",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			household := setupFakeHousehold(t)
+
+			backend := SetupBackend()
+			assert.True(t, backend.Diagnostics.Ready)
+
+			accountId := household.sharedBudget.Accounts[0].Id
+			electricityCategoryId := getFakeCategoryId(household.sharedBudget, "⚡ Electricity")
+			waterCategoryId := getFakeCategoryId(household.sharedBudget, "💧 Water")
+			today, lastMonth := time.Now().Format("2006-01-02"), time.Now().AddDate(0, 0, -time.Now().Day()).Format("2006-01-02")
+
+			// Only the electricity bill entered by hand this month is a possible duplicate, not last month's nor another payee's
+			_, err := backend.BudgetService.CreateTransactions(context.Background(), household.sharedBudget.Id, []SaveTransaction{
+				{AccountId: &accountId, Date: today, Amount: -130510, PayeeName: to.StringPtr("edp "), CategoryId: &electricityCategoryId, Cleared: testCase.cleared},
+				{AccountId: &accountId, Date: lastMonth, Amount: -128000, PayeeName: to.StringPtr("EDP"), CategoryId: &electricityCategoryId},
+				{AccountId: &accountId, Date: today, Amount: -60250, PayeeName: to.StringPtr("Galp"), CategoryId: &waterCategoryId},
+			})
+			assert.NoError(t, err)
+
+			importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), testCase.duplicateResolution)
+
+			assert.Equal(t, testCase.expectedSuccess, importResult.Success, importResult.Error)
+			assert.Len(t, household.sharedBudget.GetTransactions(), testCase.expectedSharedCount)
+
+			if testCase.expectedDuplicatesFound {
+				assert.Equal(t, ErrPossibleDuplicates.Error(), importResult.Error)
+				assert.Len(t, importResult.PossibleDuplicates, 1)
+				assert.Equal(t, "Electricity", importResult.PossibleDuplicates[0].Description)
+				assert.Equal(t, []string{"-130,51€"}, importResult.PossibleDuplicates[0].FormattedAmounts)
+				assert.Empty(t, household.maguiBudget.GetTransactions(), "Expected nothing to be imported until a duplicate resolution is given")
+				return
+			}
+			if testCase.expectedError != "" {
+				assert.Equal(t, testCase.expectedError, importResult.Error)
+				assert.Empty(t, household.maguiBudget.GetTransactions(), "Expected nothing to be imported when replacing is refused")
+				return
+			}
+
+			assert.Equal(t, testCase.expectedAlreadyPresent, importResult.Budgets[0].AlreadyPresent)
+			assert.Len(t, importResult.Budgets[0].ReplacedTransactionIds, testCase.expectedReplacedCount)
+			assert.Len(t, household.maguiBudget.GetTransactions(), 1, "Expected the individual budget to be imported regardless")
+		})
+	}
+}
+
+// getFakeCategoryId finds the id of a category of a budget of the fake YNAB API by name
+func getFakeCategoryId(budget *ynabtest.Budget, name string) string {
+	for _, category := range budget.Categories {
+		if category.Name == name {
+			return category.Id
+		}
+	}
+
+	return ""
+}
+
+func TestSubmitQueuedImportWithPossibleDuplicates(t *testing.T) {
+	household := setupFakeHousehold(t)
+
+	backend := SetupBackend()
+	assert.True(t, backend.Diagnostics.Ready)
+	backend.BudgetService.(*APIClient).SetRetryCount(0)
+
+	month := time.Now().Format("2006-01")
+
+	// YNAB cannot be reached to find possible duplicates nor to import, so the import is queued without being checked
+	household.ynab.Fail(http.MethodGet, "budgets/"+household.sharedBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+	household.ynab.Fail(http.MethodPost, "budgets/"+household.sharedBudget.Id+"/transactions", http.StatusServiceUnavailable, 1)
+	assert.True(t, backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "").Queued)
+
+	// Meanwhile, the electricity bill is entered by hand
+	accountId := household.sharedBudget.Accounts[0].Id
+	electricityCategoryId := getFakeCategoryId(household.sharedBudget, "⚡ Electricity")
+	_, err := backend.BudgetService.CreateTransactions(context.Background(), household.sharedBudget.Id, []SaveTransaction{
+		{AccountId: &accountId, Date: time.Now().Format("2006-01-02"), Amount: -130510, PayeeName: to.StringPtr("EDP"), CategoryId: &electricityCategoryId},
+	})
+	assert.NoError(t, err)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		queuedImports := backend.SubmitQueuedImports()
+		assert.Len(t, queuedImports, 1)
+		assert.Equal(t, QueuedImportStatusPossibleDuplicates, queuedImports[0].Status)
+		assert.Equal(t, ErrPossibleDuplicates.Error(), queuedImports[0].Error)
+		assert.Len(t, queuedImports[0].Result.PossibleDuplicates, 1)
+		assert.Equal(t, []string{"-130,51€"}, queuedImports[0].Result.PossibleDuplicates[0].FormattedAmounts)
+		assert.Len(t, household.sharedBudget.GetTransactions(), 1, "Expected nothing to be imported until the possible duplicates are resolved")
+		assert.Empty(t, household.maguiBudget.GetTransactions())
+	}
+
+	assert.Error(t, backend.ResolveQueuedImport(month, ""))
+	assert.ErrorIs(t, backend.ResolveQueuedImport("2000-01", DuplicateResolutionSkip), ErrNothingToResolve)
+
+	assert.NoError(t, backend.ResolveQueuedImport(month, DuplicateResolutionSkip))
+	assert.Empty(t, backend.SubmitQueuedImports())
+	assert.Len(t, household.sharedBudget.GetTransactions(), 1+3, "Expected the possible duplicate to be left out")
+	assert.Len(t, household.maguiBudget.GetTransactions(), 1)
+}
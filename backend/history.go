@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/exp/slices"
+)
+
+// HistoryFileName is the name of the embedded database, in the application directory, where every split and import of the monthly expenses is recorded
+const HistoryFileName string = "history.db"
+
+// HistoryOpenTimeout is how long opening the history waits for another process, e.g. the CLI while the application records an import, to be done with it
+const HistoryOpenTimeout time.Duration = 5 * time.Second
+
+// AppVersion is the version of the application recorded along with every split and import, set when building with -ldflags "-X ynab-monthly-expenses-manager/backend.AppVersion=<version>"
+var AppVersion = "dev"
+
+// historyRecordsBucket is the bucket of the history holding the records, keyed by month and id so that the records of a month are stored together in the order they were recorded
+var historyRecordsBucket = []byte("records")
+
+// Kinds of records in the history
+const (
+	HistoryRecordKindSplit  string = "split"
+	HistoryRecordKindImport string = "import"
+)
+
+// HistoryRecord represents a split or an import of the monthly expenses of a month, with the amount of each category, the share of each participant and how it was rounded
+// Imports also hold what happened in each YNAB budget, including the ids of the transactions created, whether they succeeded or not
+type HistoryRecord struct {
+	Id           uint64                                `json:"id"`
+	Kind         string                                `json:"kind"`
+	Month        string                                `json:"month"`
+	RecordedAt   time.Time                             `json:"recorded_at"`
+	AppVersion   string                                `json:"app_version"`
+	Amounts      map[string]decimal.Decimal            `json:"amounts"`
+	Shares       map[string]map[string]decimal.Decimal `json:"shares"`
+	Rounding     []RoundingLedgerEntry                 `json:"rounding"`
+	ImportResult *ImportResult                         `json:"import_result"`
+}
+
+// History represents the persisted record of every split and import of the monthly expenses, in an embedded database
+// The database is only open while it is read or written, so that the application and the CLI can both use it
+type History struct {
+	Path string
+}
+
+// HistoryPath returns the location of the history database
+func HistoryPath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, HistoryFileName), nil
+}
+
+// Record adds a record to the history, assigning it the next id, and returns it as recorded
+func (history *History) Record(record HistoryRecord) (HistoryRecord, error) {
+	err := history.update(func(bucket *bolt.Bucket) error {
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		record.Id = id
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(getHistoryRecordKey(record.Month, id), data)
+	})
+	if err != nil {
+		return HistoryRecord{}, fmt.Errorf("recording history: %w", err)
+	}
+
+	return record, nil
+}
+
+// GetRecords returns the records of a month (formatted as YYYY-MM) in the order they were recorded, or of every month, oldest first, if none is given
+func (history *History) GetRecords(month string) ([]HistoryRecord, error) {
+	records := []HistoryRecord{}
+
+	var prefix []byte
+	if month != "" {
+		prefix = []byte(month + "/")
+	}
+
+	err := history.view(func(bucket *bolt.Bucket) error {
+		cursor := bucket.Cursor()
+
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			var record HistoryRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("decoding history record %s: %w", key, err)
+			}
+			records = append(records, record)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	return records, nil
+}
+
+// GetMonths returns the months with records in the history, latest first
+func (history *History) GetMonths() ([]string, error) {
+	months := []string{}
+
+	err := history.view(func(bucket *bolt.Bucket) error {
+		cursor := bucket.Cursor()
+
+		for key, _ := cursor.Last(); key != nil; key, _ = cursor.Prev() {
+			month, _, _ := bytes.Cut(key, []byte("/"))
+			if len(months) == 0 || months[len(months)-1] != string(month) {
+				months = append(months, string(month))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	return months, nil
+}
+
+// update runs a read-write transaction on the history records, creating the history database if it does not exist yet
+func (history *History) update(update func(bucket *bolt.Bucket) error) error {
+	if err := os.MkdirAll(filepath.Dir(history.Path), 0700); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	database, err := bolt.Open(history.Path, 0600, &bolt.Options{Timeout: HistoryOpenTimeout})
+	if err != nil {
+		return fmt.Errorf("opening history %s: %w", history.Path, err)
+	}
+	defer database.Close()
+
+	return database.Update(func(transaction *bolt.Tx) error {
+		bucket, err := transaction.CreateBucketIfNotExists(historyRecordsBucket)
+		if err != nil {
+			return err
+		}
+
+		return update(bucket)
+	})
+}
+
+// view runs a read-only transaction on the history records, finding none if the history database does not exist yet
+func (history *History) view(view func(bucket *bolt.Bucket) error) error {
+	if _, err := os.Stat(history.Path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	database, err := bolt.Open(history.Path, 0600, &bolt.Options{Timeout: HistoryOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("opening history %s: %w", history.Path, err)
+	}
+	defer database.Close()
+
+	return database.View(func(transaction *bolt.Tx) error {
+		bucket := transaction.Bucket(historyRecordsBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return view(bucket)
+	})
+}
+
+// newHistoryRecord builds the record of a split or an import of the monthly expenses of a month from the rounding of the individual shares, which holds the share of every participant in every category
+// The amount of each category is the sum of the individual shares, as the shares always sum exactly to the shared amount
+func newHistoryRecord(kind string, month string, roundingEntries []RoundingLedgerEntry, importResult *ImportResult) HistoryRecord {
+	record := HistoryRecord{
+		Kind:         kind,
+		Month:        month,
+		RecordedAt:   time.Now(),
+		AppVersion:   AppVersion,
+		Amounts:      make(map[string]decimal.Decimal),
+		Shares:       make(map[string]map[string]decimal.Decimal),
+		Rounding:     slices.Clone(roundingEntries),
+		ImportResult: importResult,
+	}
+
+	for _, entry := range roundingEntries {
+		record.Amounts[entry.CategoryName] = record.Amounts[entry.CategoryName].Add(entry.RoundedShare)
+
+		if record.Shares[entry.ParticipantName] == nil {
+			record.Shares[entry.ParticipantName] = make(map[string]decimal.Decimal)
+		}
+		record.Shares[entry.ParticipantName][entry.CategoryName] = entry.RoundedShare
+	}
+
+	return record
+}
+
+// getHistoryRecordKey returns the key of a history record, which sorts by month and then by id
+func getHistoryRecordKey(month string, id uint64) []byte {
+	return []byte(fmt.Sprintf("%s/%020d", month, id))
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	history := &History{Path: filepath.Join(t.TempDir(), "history", HistoryFileName)}
+
+	months, err := history.GetMonths()
+	assert.NoError(t, err)
+	assert.Empty(t, months, "Expected an empty history before anything is recorded")
+
+	for _, record := range []HistoryRecord{
+		newHistoryRecord(HistoryRecordKindSplit, "2024-02", []RoundingLedgerEntry{
+			{Month: "2024-02", CategoryName: "Water", ParticipantName: "Magui", RoundedShare: decimal.RequireFromString("30.12")},
+			{Month: "2024-02", CategoryName: "Water", ParticipantName: "Jão", RoundedShare: decimal.RequireFromString("30.13")},
+		}, nil),
+		newHistoryRecord(HistoryRecordKindSplit, "2024-01", nil, nil),
+		newHistoryRecord(HistoryRecordKindImport, "2024-02", nil, &ImportResult{Success: true, Budgets: []BudgetImportResult{{CreatedTransactionIds: []string{"transaction-1"}}}}),
+		newHistoryRecord(HistoryRecordKindSplit, "2024-10", nil, nil),
+	} {
+		_, err = history.Record(record)
+		assert.NoError(t, err)
+	}
+
+	months, err = history.GetMonths()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-10", "2024-02", "2024-01"}, months)
+
+	testCases := map[string]struct {
+		month         string
+		expectedIds   []uint64
+		expectedKinds []string
+	}{
+		"month with a split and an import": {
+			month:         "2024-02",
+			expectedIds:   []uint64{1, 3},
+			expectedKinds: []string{HistoryRecordKindSplit, HistoryRecordKindImport},
+		},
+		"month without records": {
+			month: "2024-03",
+		},
+		"every month": {
+			expectedIds:   []uint64{2, 1, 3, 4},
+			expectedKinds: []string{HistoryRecordKindSplit, HistoryRecordKindSplit, HistoryRecordKindImport, HistoryRecordKindSplit},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			records, err := history.GetRecords(testCase.month)
+			assert.NoError(t, err)
+
+			var ids []uint64
+			var kinds []string
+			for _, record := range records {
+				ids = append(ids, record.Id)
+				kinds = append(kinds, record.Kind)
+			}
+			assert.Equal(t, testCase.expectedIds, ids)
+			assert.Equal(t, testCase.expectedKinds, kinds)
+		})
+	}
+
+	records, err := history.GetRecords("2024-02")
+	assert.NoError(t, err)
+	assert.Equal(t, "60.25", records[0].Amounts["Water"].String(), "Expected the amount to be the sum of the individual shares")
+	assert.Equal(t, "30.12", records[0].Shares["Magui"]["Water"].String())
+	assert.Equal(t, AppVersion, records[0].AppVersion)
+	assert.WithinDuration(t, time.Now(), records[0].RecordedAt, time.Minute)
+	assert.Equal(t, []string{"transaction-1"}, records[1].ImportResult.Budgets[0].CreatedTransactionIds)
+}
@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ImportIdPrefix is the prefix of the import ids generated by the application, which sets them apart from the ones YNAB assigns to imported transactions
+const ImportIdPrefix string = "MEM:"
+
+// Statuses of the import of the monthly expenses into a YNAB budget
+const (
+	BudgetImportStatusCreated        string = "created"
+	BudgetImportStatusFailed         string = "failed"
+	BudgetImportStatusRolledBack     string = "rolled_back"
+	BudgetImportStatusRollbackFailed string = "rollback_failed"
+)
+
+// ErrRollbackIncomplete is the error of an import whose rollback left some of the transactions it created behind in YNAB, which must be deleted by hand before importing again
+var ErrRollbackIncomplete = errors.New("some transactions could not be rolled back, delete them in YNAB before importing again")
+
+// ImportResult represents the outcome of importing the monthly expenses into YNAB, with the transactions created and already present in each YNAB budget
+// When the import fails in one of the budgets, the transactions already created in the other budgets are rolled back
+// When the import fails because YNAB cannot be reached, it is queued in the outbox to be submitted later
+// When the import is canceled, it is rolled back like a failed one, but never queued
+// When the monthly expenses appear to be already entered in YNAB, nothing is imported and the possible duplicates are listed instead
+type ImportResult struct {
+	Success            bool                 `json:"success"`
+	Error              string               `json:"error"`
+	RolledBack         bool                 `json:"rolled_back"`
+	Queued             bool                 `json:"queued"`
+	Canceled           bool                 `json:"canceled"`
+	Budgets            []BudgetImportResult `json:"budgets"`
+	PossibleDuplicates []PossibleDuplicate  `json:"possible_duplicates"`
+}
+
+// BudgetImportResult represents the outcome of importing the monthly expenses into a YNAB budget
+// Transactions already present in the budget, i.e. whose import id already exists, are not created again and are listed by description instead
+// Transactions that could not be deleted during a rollback are left in the budget and listed as created
+// Transactions left out as possible duplicates of existing ones are listed as already present as well, and the existing transactions deleted to be replaced are listed by id
+// The transactions created are also kept as YNAB created them, to record them in the undo log
+type BudgetImportResult struct {
+	BudgetId                 string   `json:"budget_id"`
+	ParticipantName          string   `json:"participant_name"`
+	Status                   string   `json:"status"`
+	Error                    string   `json:"error"`
+	CreatedTransactionIds    []string `json:"created_transaction_ids"`
+	RolledBackTransactionIds []string `json:"rolled_back_transaction_ids"`
+	DuplicateImportIds       []string `json:"duplicate_import_ids"`
+	AlreadyPresent           []string `json:"already_present"`
+	ReplacedTransactionIds   []string `json:"replaced_transaction_ids"`
+	importedTransactions     []ImportedTransaction
+}
+
+// GetImportId generates the deterministic import id of a monthly expenses transaction from its YNAB budget, month, category and participant
+// Either the category or the participant may be empty, e.g. for the transactions of the shared monthly expenses categories
+// The import id is at most 36 characters long, as required by YNAB
+func GetImportId(budgetId string, month string, categoryName string, participantName string) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{budgetId, month, categoryName, participantName}, "\x00")))
+
+	return ImportIdPrefix + hex.EncodeToString(hash[:])[:36-len(ImportIdPrefix)]
+}
+
+// createTransactions creates the YNAB transactions for a YNAB budget, reporting which of them were already present
+// The descriptions of the transactions, by import id, are used to report the transactions already present
+func createTransactions(ctx context.Context, budgetService BudgetService, budgetId string, participantName string, transactions []SaveTransaction, descriptions map[string]string) (BudgetImportResult, error) {
+	budgetImportResult := BudgetImportResult{
+		BudgetId:                 budgetId,
+		ParticipantName:          participantName,
+		Status:                   BudgetImportStatusCreated,
+		CreatedTransactionIds:    []string{},
+		RolledBackTransactionIds: []string{},
+		DuplicateImportIds:       []string{},
+		AlreadyPresent:           []string{},
+		ReplacedTransactionIds:   []string{},
+	}
+
+	// Every planned transaction of the budget may have been left out as a possible duplicate
+	if len(transactions) == 0 {
+		return budgetImportResult, nil
+	}
+
+	response, err := budgetService.CreateTransactions(ctx, budgetId, transactions)
+	if err != nil {
+		err = fmt.Errorf("creating transactions in budget %s: %w", budgetId, err)
+		budgetImportResult.Status = BudgetImportStatusFailed
+		budgetImportResult.Error = err.Error()
+		return budgetImportResult, err
+	}
+
+	for _, transaction := range response.Transactions {
+		budgetImportResult.CreatedTransactionIds = append(budgetImportResult.CreatedTransactionIds, transaction.Id)
+		budgetImportResult.importedTransactions = append(budgetImportResult.importedTransactions, newImportedTransaction(transaction, descriptions[transaction.ImportId]))
+	}
+
+	for _, duplicateImportId := range response.DuplicateImportIds {
+		budgetImportResult.DuplicateImportIds = append(budgetImportResult.DuplicateImportIds, duplicateImportId)
+		budgetImportResult.AlreadyPresent = append(budgetImportResult.AlreadyPresent, descriptions[duplicateImportId])
+	}
+
+	return budgetImportResult, nil
+}
+
+// ImportMonthlyExpenses creates the YNAB transactions for the shared and individual monthly expenses of a month (formatted as YYYY-MM), first in the shared budget and then in each individual budget
+// If creating the transactions fails in any budget, the transactions created so far are deleted, so that the import either succeeds in every budget or leaves them all as they were
+// Transactions that were already present before the import are never deleted
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) ImportMonthlyExpenses(ctx context.Context, budgetService BudgetService, month string) ImportResult {
+	importResult, _ := combinedMonthlyExpenses.GetImportPlan(month).Execute(ctx, budgetService)
+
+	return importResult
+}
+
+// Execute creates the YNAB transactions of the import plan, budget by budget in the order they are planned, rolling back the ones created so far if any budget fails
+// The error that made the import fail, if any, is returned along with the import result, e.g. to tell if YNAB could not be reached
+// Canceling the context aborts the import, and the transactions created so far are still rolled back, as leaving them behind would split the import
+func (importPlan ImportPlan) Execute(ctx context.Context, budgetService BudgetService) (ImportResult, error) {
+	importResult := ImportResult{Budgets: []BudgetImportResult{}}
+
+	for budgetIndex, budgetImportPlan := range importPlan.Budgets {
+		budgetImportResult, err := budgetImportPlan.execute(ctx, budgetService)
+		importResult.Budgets = append(importResult.Budgets, budgetImportResult)
+		if err == nil {
+			continue
+		}
+
+		importResult.Error = err.Error()
+		importResult.Canceled = errors.Is(err, context.Canceled)
+		if budgetIndex > 0 {
+			if rollbackErr := importResult.rollback(context.WithoutCancel(ctx), budgetService); rollbackErr != nil {
+				importResult.Error = errors.Join(err, rollbackErr).Error()
+			}
+		}
+
+		return importResult, err
+	}
+
+	importResult.Success = true
+
+	return importResult, nil
+}
+
+// rollback deletes the transactions created in every budget, in the reverse order they were created
+// Every transaction is attempted even if deleting another one fails, and the ones left behind remain listed as created
+func (importResult *ImportResult) rollback(ctx context.Context, budgetService BudgetService) error {
+	var rollbackErrors []error
+
+	for budgetIndex := len(importResult.Budgets) - 1; budgetIndex >= 0; budgetIndex-- {
+		budgetImportResult := &importResult.Budgets[budgetIndex]
+
+		if budgetImportResult.Status != BudgetImportStatusCreated || len(budgetImportResult.CreatedTransactionIds) == 0 {
+			continue
+		}
+
+		var remainingTransactionIds []string
+		var budgetRollbackErrors []error
+
+		for _, transactionId := range budgetImportResult.CreatedTransactionIds {
+			// A transaction YNAB no longer has, e.g. because it was deleted by hand meanwhile, is as good as rolled back
+			if _, err := budgetService.DeleteTransaction(ctx, budgetImportResult.BudgetId, transactionId); err != nil && !errors.Is(err, ErrNotFound) {
+				remainingTransactionIds = append(remainingTransactionIds, transactionId)
+				budgetRollbackErrors = append(budgetRollbackErrors,
+					fmt.Errorf("deleting transaction %s from budget %s: %w", transactionId, budgetImportResult.BudgetId, err))
+				continue
+			}
+			budgetImportResult.RolledBackTransactionIds = append(budgetImportResult.RolledBackTransactionIds, transactionId)
+		}
+
+		if len(budgetRollbackErrors) > 0 {
+			budgetImportResult.Status = BudgetImportStatusRollbackFailed
+			budgetImportResult.CreatedTransactionIds = remainingTransactionIds
+			budgetImportResult.Error = errors.Join(budgetRollbackErrors...).Error()
+			rollbackErrors = append(rollbackErrors, budgetRollbackErrors...)
+			continue
+		}
+
+		budgetImportResult.Status = BudgetImportStatusRolledBack
+		budgetImportResult.CreatedTransactionIds = []string{}
+	}
+
+	importResult.RolledBack = len(rollbackErrors) == 0
+
+	return errors.Join(rollbackErrors...)
+}
+
+// hasLeftoverTransactions checks if rolling back the failed import left any of the transactions it created behind in YNAB
+func (importResult ImportResult) hasLeftoverTransactions() bool {
+	for _, budgetImportResult := range importResult.Budgets {
+		if budgetImportResult.Status == BudgetImportStatusRollbackFailed {
+			return true
+		}
+	}
+
+	return false
+}
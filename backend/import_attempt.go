@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ImportAttemptsFileName is the name of the file, in the application directory, where the attempt of the import ids of each month is persisted
+const ImportAttemptsFileName string = "import_attempts.json"
+
+// ImportAttempts represents the persisted attempt of the import ids of each month
+// YNAB keeps the import id of a deleted transaction and never creates a transaction with the same import id again, so once the transactions of an import are deleted, by rolling it back or undoing it, the month moves on to the next attempt, whose import ids YNAB has never seen
+type ImportAttempts struct {
+	Path   string         `json:"-"`
+	Months map[string]int `json:"months"`
+	mutex  sync.Mutex
+}
+
+// ImportAttemptsPath returns the location of the import attempts file
+func ImportAttemptsPath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, ImportAttemptsFileName), nil
+}
+
+// LoadImportAttempts reads the import attempts persisted at the given location, returning no attempts if it does not exist yet
+func LoadImportAttempts(importAttemptsPath string) (*ImportAttempts, error) {
+	importAttempts := &ImportAttempts{Path: importAttemptsPath, Months: make(map[string]int)}
+
+	data, err := os.ReadFile(importAttemptsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return importAttempts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading import attempts: %w", err)
+	}
+
+	if err = json.Unmarshal(data, importAttempts); err != nil {
+		return nil, fmt.Errorf("decoding import attempts %s: %w", importAttemptsPath, err)
+	}
+	if importAttempts.Months == nil {
+		importAttempts.Months = make(map[string]int)
+	}
+
+	return importAttempts, nil
+}
+
+// Get returns the attempt of the import ids of a month (formatted as YYYY-MM), which is 0 until the transactions of an import of the month are deleted
+func (importAttempts *ImportAttempts) Get(month string) int {
+	importAttempts.mutex.Lock()
+	defer importAttempts.mutex.Unlock()
+
+	return importAttempts.Months[month]
+}
+
+// Next moves a month on to the next attempt of its import ids, once the transactions of an import of the month were deleted, and persists it
+func (importAttempts *ImportAttempts) Next(month string) (int, error) {
+	importAttempts.mutex.Lock()
+	defer importAttempts.mutex.Unlock()
+
+	importAttempts.Months[month]++
+
+	return importAttempts.Months[month], importAttempts.save()
+}
+
+// Advance moves a month on to the given attempt of its import ids, unless it is past it already, e.g. once a queued import of the month was rolled back, and persists it
+func (importAttempts *ImportAttempts) Advance(month string, attempt int) error {
+	importAttempts.mutex.Lock()
+	defer importAttempts.mutex.Unlock()
+
+	if importAttempts.Months[month] >= attempt {
+		return nil
+	}
+	importAttempts.Months[month] = attempt
+
+	return importAttempts.save()
+}
+
+// save persists the import attempts
+func (importAttempts *ImportAttempts) save() error {
+	data, err := json.MarshalIndent(importAttempts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(importAttempts.Path), 0700); err != nil {
+		return fmt.Errorf("creating import attempts directory: %w", err)
+	}
+
+	if err = writeFileAtomically(importAttempts.Path, data, 0600); err != nil {
+		return fmt.Errorf("writing import attempts: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttemptImportId returns the import id generated by GetImportId for an attempt of the import ids of its month, e.g. MEM:<hash>:2
+// The first attempt, 0, is the import id itself, and the hash is shortened for the others so that the import id is still at most 36 characters long
+func GetAttemptImportId(importId string, attempt int) string {
+	hash, _, _ := strings.Cut(strings.TrimPrefix(importId, ImportIdPrefix), ":")
+	if attempt == 0 {
+		return ImportIdPrefix + hash
+	}
+
+	suffix := ":" + strconv.Itoa(attempt)
+
+	return ImportIdPrefix + hash[:min(len(hash), 36-len(ImportIdPrefix)-len(suffix))] + suffix
+}
+
+// WithAttempt returns a copy of the import plan whose transactions have the import ids of the given attempt
+func (importPlan ImportPlan) WithAttempt(attempt int) ImportPlan {
+	attemptImportPlan := ImportPlan{Month: importPlan.Month, Attempt: attempt, Budgets: []BudgetImportPlan{}}
+
+	for _, budgetImportPlan := range importPlan.Budgets {
+		attemptBudgetImportPlan := budgetImportPlan
+		attemptBudgetImportPlan.Transactions = []PlannedTransaction{}
+
+		for _, plannedTransaction := range budgetImportPlan.Transactions {
+			if plannedTransaction.Transaction.ImportId != nil {
+				importId := GetAttemptImportId(*plannedTransaction.Transaction.ImportId, attempt)
+				plannedTransaction.Transaction.ImportId = &importId
+			}
+			attemptBudgetImportPlan.Transactions = append(attemptBudgetImportPlan.Transactions, plannedTransaction)
+		}
+
+		attemptImportPlan.Budgets = append(attemptImportPlan.Budgets, attemptBudgetImportPlan)
+	}
+
+	return attemptImportPlan
+}
+
+// isFullyRolledBack checks if every transaction created by the failed import was deleted by rolling it back, so that their import ids cannot be used again while none was left behind
+func (importResult ImportResult) isFullyRolledBack() bool {
+	if !importResult.RolledBack {
+		return false
+	}
+
+	deleted := false
+	for _, budgetImportResult := range importResult.Budgets {
+		if len(budgetImportResult.CreatedTransactionIds) > 0 {
+			return false
+		}
+		deleted = deleted || len(budgetImportResult.RolledBackTransactionIds) > 0
+	}
+
+	return deleted
+}
@@ -0,0 +1,247 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// ImportPlan represents every YNAB transaction that importing the monthly expenses of a month would create, without sending anything to YNAB
+// The attempt is the one of the import ids of the transactions, which moves on once the transactions of an import of the month are deleted
+type ImportPlan struct {
+	Month   string             `json:"month"`
+	Attempt int                `json:"attempt"`
+	Budgets []BudgetImportPlan `json:"budgets"`
+}
+
+// BudgetImportPlan represents the YNAB transactions that importing the monthly expenses would create in a YNAB budget
+type BudgetImportPlan struct {
+	BudgetId        string               `json:"budget_id"`
+	ParticipantName string               `json:"participant_name"`
+	Transactions    []PlannedTransaction `json:"transactions"`
+}
+
+// PlannedTransaction represents a YNAB transaction that importing the monthly expenses would create, exactly as it would be sent to YNAB
+// The formatted amounts of the transaction and of each of its sub-transactions, in the same order, are included for display
+type PlannedTransaction struct {
+	Description                    string          `json:"description"`
+	Transaction                    SaveTransaction `json:"transaction"`
+	FormattedAmount                string          `json:"formatted_amount"`
+	FormattedSubTransactionAmounts []string        `json:"formatted_subtransaction_amounts"`
+	SubTransactionDescriptions     []string        `json:"subtransaction_descriptions"`
+}
+
+// GetImportPlan builds the YNAB transactions for the shared and individual monthly expenses of a month (formatted as YYYY-MM), in the order they would be created
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) GetImportPlan(month string) ImportPlan {
+	return ImportPlan{
+		Month: month,
+		Budgets: append(
+			[]BudgetImportPlan{combinedMonthlyExpenses.GetSharedMonthlyExpensesImportPlan(month)},
+			combinedMonthlyExpenses.GetIndividualMonthlyExpensesImportPlans(month)...,
+		),
+	}
+}
+
+// GetSharedMonthlyExpensesImportPlan builds the YNAB transactions for the shared monthly expenses of a month (formatted as YYYY-MM)
+// Besides a transaction for each monthly expense category, a transaction is built for the individual share that each participant contributes
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) GetSharedMonthlyExpensesImportPlan(month string) BudgetImportPlan {
+	sharedMonthlyExpenses := combinedMonthlyExpenses.SharedMonthlyExpenses
+
+	budgetImportPlan := BudgetImportPlan{
+		BudgetId:     sharedMonthlyExpenses.BudgetId,
+		Transactions: []PlannedTransaction{},
+	}
+
+	categoryNames := maps.Keys(sharedMonthlyExpenses.Expenses)
+	slices.Sort(categoryNames)
+
+	for _, categoryName := range categoryNames {
+		monthlyExpense := sharedMonthlyExpenses.Expenses[categoryName]
+
+		budgetImportPlan.Transactions = append(budgetImportPlan.Transactions, PlannedTransaction{
+			Description: categoryName,
+			Transaction: createTransaction(
+				sharedMonthlyExpenses.AccountId,
+				monthlyExpense.Amount.Neg(),
+				monthlyExpense.PayeeName,
+				monthlyExpense.CategoryId,
+				monthlyExpense.Memo,
+				GetImportId(sharedMonthlyExpenses.BudgetId, month, categoryName, ""),
+				nil,
+			),
+		})
+	}
+
+	for _, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+		var subTransactions []SaveSubTransaction
+		var totalIndividualShareAmount decimal.Decimal
+
+		for _, categoryName := range categoryNames {
+			individualShareAmount := individualMonthlyExpenses.Expenses[categoryName].Amount
+			totalIndividualShareAmount = totalIndividualShareAmount.Add(individualShareAmount)
+
+			subTransactions = append(subTransactions,
+				createSubTransaction(
+					individualShareAmount,
+					sharedMonthlyExpenses.Expenses[categoryName].CategoryId,
+				),
+			)
+		}
+
+		payeeName := GetIndividualMonthlyExpensePayeeName(individualMonthlyExpenses.ParticipantName)
+
+		budgetImportPlan.Transactions = append(budgetImportPlan.Transactions, PlannedTransaction{
+			Description: payeeName,
+			Transaction: createTransaction(
+				sharedMonthlyExpenses.AccountId,
+				totalIndividualShareAmount,
+				to.StringPtr(payeeName),
+				nil,
+				to.StringPtr(GetIndividualMonthlyExpenseMemo()),
+				GetImportId(sharedMonthlyExpenses.BudgetId, month, "", individualMonthlyExpenses.ParticipantName),
+				subTransactions,
+			),
+			SubTransactionDescriptions: categoryNames,
+		})
+	}
+
+	return budgetImportPlan
+}
+
+// GetIndividualMonthlyExpensesImportPlans builds the YNAB transactions for the individual monthly expenses of a month (formatted as YYYY-MM) of each participant with a YNAB budget and account
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) GetIndividualMonthlyExpensesImportPlans(month string) []BudgetImportPlan {
+	budgetImportPlans := []BudgetImportPlan{}
+
+	for _, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+		if individualMonthlyExpenses.BudgetId == "" {
+			continue
+		}
+
+		budgetImportPlans = append(budgetImportPlans, individualMonthlyExpenses.getIndividualMonthlyExpensesImportPlan(month))
+	}
+
+	return budgetImportPlans
+}
+
+// getIndividualMonthlyExpensesImportPlan builds the YNAB transaction for the individual monthly expenses of a participant, with a sub-transaction for each monthly expense category
+func (individualMonthlyExpenses *MonthlyExpenses) getIndividualMonthlyExpensesImportPlan(month string) BudgetImportPlan {
+	var sampleExpense MonthlyExpense
+
+	var subTransactions []SaveSubTransaction
+	var totalTransactionAmount decimal.Decimal
+
+	categoryNames := maps.Keys(individualMonthlyExpenses.Expenses)
+	slices.Sort(categoryNames)
+
+	for _, categoryName := range categoryNames {
+		monthlyExpense := individualMonthlyExpenses.Expenses[categoryName]
+		sampleExpense = *monthlyExpense
+
+		subTransactionAmount := monthlyExpense.Amount
+		totalTransactionAmount = totalTransactionAmount.Add(subTransactionAmount)
+
+		subTransactions = append(subTransactions,
+			createSubTransaction(
+				subTransactionAmount.Neg(),
+				monthlyExpense.CategoryId,
+			),
+		)
+	}
+
+	return BudgetImportPlan{
+		BudgetId:        individualMonthlyExpenses.BudgetId,
+		ParticipantName: individualMonthlyExpenses.ParticipantName,
+		Transactions: []PlannedTransaction{
+			{
+				Description: fmt.Sprintf("%s's share", individualMonthlyExpenses.ParticipantName),
+				Transaction: createTransaction(
+					individualMonthlyExpenses.AccountId,
+					totalTransactionAmount.Neg(),
+					sampleExpense.PayeeName,
+					nil,
+					sampleExpense.Memo,
+					GetImportId(individualMonthlyExpenses.BudgetId, month, "", individualMonthlyExpenses.ParticipantName),
+					subTransactions,
+				),
+				SubTransactionDescriptions: categoryNames,
+			},
+		},
+	}
+}
+
+// FormatAmounts formats the amount of every planned transaction and sub-transaction according to the currency format of its YNAB budget
+func (importPlan *ImportPlan) FormatAmounts(currencyFormats map[string]CurrencyFormat) {
+	for _, budgetImportPlan := range importPlan.Budgets {
+		currencyFormat := currencyFormats[budgetImportPlan.BudgetId]
+
+		for transactionIndex := range budgetImportPlan.Transactions {
+			plannedTransaction := &budgetImportPlan.Transactions[transactionIndex]
+
+			plannedTransaction.FormattedAmount = currencyFormat.Format(plannedTransaction.Transaction.Amount)
+			plannedTransaction.FormattedSubTransactionAmounts = []string{}
+
+			for _, subTransaction := range plannedTransaction.Transaction.SubTransactions {
+				plannedTransaction.FormattedSubTransactionAmounts = append(plannedTransaction.FormattedSubTransactionAmounts,
+					currencyFormat.Format(subTransaction.Amount))
+			}
+		}
+	}
+}
+
+// execute creates the planned YNAB transactions in the YNAB budget, reporting which of them were already present
+func (budgetImportPlan BudgetImportPlan) execute(ctx context.Context, budgetService BudgetService) (BudgetImportResult, error) {
+	transactions := make([]SaveTransaction, 0, len(budgetImportPlan.Transactions))
+	descriptions := make(map[string]string, len(budgetImportPlan.Transactions))
+
+	for _, plannedTransaction := range budgetImportPlan.Transactions {
+		transactions = append(transactions, plannedTransaction.Transaction)
+		descriptions[*plannedTransaction.Transaction.ImportId] = plannedTransaction.Description
+	}
+
+	return createTransactions(ctx, budgetService, budgetImportPlan.BudgetId, budgetImportPlan.ParticipantName, transactions, descriptions)
+}
+
+// Format formats an amount in milliunits according to the currency format, e.g. -1.234,56€
+// Without a currency format, e.g. when the YNAB budget is unknown, the amount is formatted with 2 decimal digits and no symbol
+func (currencyFormat CurrencyFormat) Format(milliunits int64) string {
+	if currencyFormat.IsoCode == "" {
+		currencyFormat = CurrencyFormat{DecimalDigits: 2, DecimalSeparator: "."}
+	}
+
+	amount := decimal.New(milliunits, -3)
+
+	digits := amount.Abs().StringFixed(currencyFormat.DecimalDigits)
+	integerPart, fractionalPart, _ := strings.Cut(digits, ".")
+
+	var groupedIntegerPart strings.Builder
+	for digitIndex, digit := range integerPart {
+		if digitIndex > 0 && (len(integerPart)-digitIndex)%3 == 0 {
+			groupedIntegerPart.WriteString(currencyFormat.GroupSeparator)
+		}
+		groupedIntegerPart.WriteRune(digit)
+	}
+
+	formattedAmount := groupedIntegerPart.String()
+	if fractionalPart != "" {
+		formattedAmount += currencyFormat.DecimalSeparator + fractionalPart
+	}
+
+	if currencyFormat.DisplaySymbol {
+		if currencyFormat.SymbolFirst {
+			formattedAmount = currencyFormat.CurrencySymbol + formattedAmount
+		} else {
+			formattedAmount += currencyFormat.CurrencySymbol
+		}
+	}
+
+	if amount.IsNegative() {
+		formattedAmount = "-" + formattedAmount
+	}
+
+	return formattedAmount
+}
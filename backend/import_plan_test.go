@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetImportPlan(t *testing.T) {
+	combinedMonthlyExpenses := CombinedMonthlyExpenses{
+		SharedMonthlyExpenses: createFakeMonthlyExpenses(map[string]float64{
+			"Condominium":           245.75,
+			"Electricity":           130.52,
+			"TV / Internet / Phone": 85.90,
+			"Water":                 60.25,
+		}),
+		IndividualMonthlyExpenses: []*MonthlyExpenses{
+			createFakeMonthlyExpenses(map[string]float64{
+				"Condominium":           122.88,
+				"Electricity":           65.26,
+				"TV / Internet / Phone": 42.95,
+				"Water":                 30.12,
+			}),
+			createFakeMonthlyExpenses(map[string]float64{
+				"Condominium":           122.87,
+				"Electricity":           65.26,
+				"TV / Internet / Phone": 42.95,
+				"Water":                 30.13,
+			}),
+		},
+	}
+	combinedMonthlyExpenses.IndividualMonthlyExpenses[1].BudgetId = ""
+
+	importPlan := combinedMonthlyExpenses.GetImportPlan("2024-02")
+
+	assert.Len(t, importPlan.Budgets, 2, "Expected participants without a budget to be left out of the plan")
+
+	sharedBudgetImportPlan := importPlan.Budgets[0]
+	assert.Equal(t, combinedMonthlyExpenses.SharedMonthlyExpenses.BudgetId, sharedBudgetImportPlan.BudgetId)
+	assert.Len(t, sharedBudgetImportPlan.Transactions, len(categoryNames)+2)
+
+	for categoryIndex, categoryName := range categoryNames {
+		plannedTransaction := sharedBudgetImportPlan.Transactions[categoryIndex]
+
+		assert.Equal(t, categoryName, plannedTransaction.Description)
+		assert.Equal(t, GetImportId(sharedBudgetImportPlan.BudgetId, "2024-02", categoryName, ""), *plannedTransaction.Transaction.ImportId)
+	}
+	assert.Equal(t, int64(-245750), sharedBudgetImportPlan.Transactions[0].Transaction.Amount)
+	assert.Equal(t, int64(261210), sharedBudgetImportPlan.Transactions[len(categoryNames)].Transaction.Amount)
+	assert.Equal(t, int64(122880), sharedBudgetImportPlan.Transactions[len(categoryNames)].Transaction.SubTransactions[0].Amount)
+
+	individualBudgetImportPlan := importPlan.Budgets[1]
+	individualMonthlyExpenses := combinedMonthlyExpenses.IndividualMonthlyExpenses[0]
+	assert.Equal(t, individualMonthlyExpenses.BudgetId, individualBudgetImportPlan.BudgetId)
+	assert.Equal(t, individualMonthlyExpenses.ParticipantName, individualBudgetImportPlan.ParticipantName)
+	assert.Len(t, individualBudgetImportPlan.Transactions, 1)
+
+	plannedTransaction := individualBudgetImportPlan.Transactions[0]
+	assert.Equal(t, int64(-261210), plannedTransaction.Transaction.Amount)
+	assert.Equal(t, categoryNames[:], plannedTransaction.SubTransactionDescriptions)
+	assert.Equal(t, int64(-30120), plannedTransaction.Transaction.SubTransactions[3].Amount)
+
+	importPlan.FormatAmounts(map[string]CurrencyFormat{
+		individualMonthlyExpenses.BudgetId: {IsoCode: "EUR", DecimalDigits: 2, DecimalSeparator: ",", GroupSeparator: ".", CurrencySymbol: "€", DisplaySymbol: true},
+	})
+
+	assert.Equal(t, "-245.75", importPlan.Budgets[0].Transactions[0].FormattedAmount)
+	assert.Equal(t, "-261,21€", importPlan.Budgets[1].Transactions[0].FormattedAmount)
+	assert.Equal(t, "-30,12€", importPlan.Budgets[1].Transactions[0].FormattedSubTransactionAmounts[3])
+}
+
+func TestFormat(t *testing.T) {
+	testCases := map[string]struct {
+		currencyFormat          CurrencyFormat
+		milliunits              int64
+		expectedFormattedAmount string
+	}{
+		"no currency format": {
+			milliunits:              -1234560,
+			expectedFormattedAmount: "-1234.56",
+		},
+		"euro": {
+			currencyFormat:          CurrencyFormat{IsoCode: "EUR", DecimalDigits: 2, DecimalSeparator: ",", GroupSeparator: ".", CurrencySymbol: "€", DisplaySymbol: true},
+			milliunits:              -1234560,
+			expectedFormattedAmount: "-1.234,56€",
+		},
+		"us dollar": {
+			currencyFormat:          CurrencyFormat{IsoCode: "USD", DecimalDigits: 2, DecimalSeparator: ".", GroupSeparator: ",", CurrencySymbol: "$", SymbolFirst: true, DisplaySymbol: true},
+			milliunits:              1234567890,
+			expectedFormattedAmount: "$1,234,567.89",
+		},
+		"yen - no decimal digits": {
+			currencyFormat:          CurrencyFormat{IsoCode: "JPY", DecimalDigits: 0, DecimalSeparator: ".", GroupSeparator: ",", CurrencySymbol: "¥", SymbolFirst: true, DisplaySymbol: true},
+			milliunits:              123000,
+			expectedFormattedAmount: "¥123",
+		},
+		"symbol hidden": {
+			currencyFormat:          CurrencyFormat{IsoCode: "EUR", DecimalDigits: 2, DecimalSeparator: ",", GroupSeparator: " ", CurrencySymbol: "€"},
+			milliunits:              1000,
+			expectedFormattedAmount: "1,00",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedFormattedAmount, testCase.currencyFormat.Format(testCase.milliunits))
+		})
+	}
+}
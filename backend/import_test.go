@@ -0,0 +1,168 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetImportId(t *testing.T) {
+	budgetId := gofakeit.UUID()
+
+	importId := GetImportId(budgetId, "2024-02", "Water", "")
+
+	assert.Equal(t, importId, GetImportId(budgetId, "2024-02", "Water", ""), "Expected the import id to be deterministic")
+	assert.LessOrEqual(t, len(importId), 36, "Expected the import id to fit the YNAB limit of 36 characters")
+	assert.True(t, strings.HasPrefix(importId, ImportIdPrefix), "Expected the import id to start with %s", ImportIdPrefix)
+
+	otherImportIds := []string{
+		GetImportId(gofakeit.UUID(), "2024-02", "Water", ""),
+		GetImportId(budgetId, "2024-03", "Water", ""),
+		GetImportId(budgetId, "2024-02", "Electricity", ""),
+		GetImportId(budgetId, "2024-02", "", "Water"),
+		GetImportId(budgetId, "2024-02", "Water", "Magui"),
+	}
+
+	for _, otherImportId := range otherImportIds {
+		assert.NotEqual(t, importId, otherImportId, "Expected distinct transactions to have distinct import ids")
+	}
+
+	assert.Equal(t, importId, GetAttemptImportId(importId, 0), "Expected the first attempt to keep the import id")
+	for _, attempt := range []int{1, 9, 10, 12345} {
+		attemptImportId := GetAttemptImportId(importId, attempt)
+
+		assert.NotEqual(t, importId, attemptImportId, "Expected another attempt to have another import id")
+		assert.LessOrEqual(t, len(attemptImportId), 36, "Expected the import id of attempt %d to fit the YNAB limit of 36 characters", attempt)
+		assert.Equal(t, attemptImportId, GetAttemptImportId(GetAttemptImportId(importId, attempt-1), attempt), "Expected the import id of an attempt to follow from the previous one")
+	}
+}
+
+func TestImportMonthlyExpenses(t *testing.T) {
+	testCases := map[string]struct {
+		failingBudget            string
+		failingDeletion          bool
+		cancelingBudget          string
+		expectedSuccess          bool
+		expectedRolledBack       bool
+		expectedCanceled         bool
+		expectedBudgetStatuses   []string
+		expectedDeletedBudgetIds []string
+	}{
+		"every budget succeeds": {
+			expectedSuccess:        true,
+			expectedBudgetStatuses: []string{BudgetImportStatusCreated, BudgetImportStatusCreated, BudgetImportStatusCreated},
+		},
+		"shared budget fails - nothing to roll back": {
+			failingBudget:          "shared",
+			expectedBudgetStatuses: []string{BudgetImportStatusFailed},
+		},
+		"last individual budget fails - other budgets rolled back": {
+			failingBudget:            "Jão",
+			expectedRolledBack:       true,
+			expectedBudgetStatuses:   []string{BudgetImportStatusRolledBack, BudgetImportStatusRolledBack, BudgetImportStatusFailed},
+			expectedDeletedBudgetIds: []string{"Magui", "shared", "shared", "shared", "shared", "shared", "shared"},
+		},
+		"last individual budget fails - rollback fails": {
+			failingBudget:            "Jão",
+			failingDeletion:          true,
+			expectedBudgetStatuses:   []string{BudgetImportStatusRollbackFailed, BudgetImportStatusRollbackFailed, BudgetImportStatusFailed},
+			expectedDeletedBudgetIds: []string{"Magui", "shared", "shared", "shared", "shared", "shared", "shared"},
+		},
+		"canceled while importing into the last individual budget - other budgets still rolled back": {
+			cancelingBudget:          "Jão",
+			expectedRolledBack:       true,
+			expectedCanceled:         true,
+			expectedBudgetStatuses:   []string{BudgetImportStatusRolledBack, BudgetImportStatusRolledBack, BudgetImportStatusFailed},
+			expectedDeletedBudgetIds: []string{"Magui", "shared", "shared", "shared", "shared", "shared", "shared"},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var deletedBudgetIds []string
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				budgetId := strings.Split(strings.TrimPrefix(request.URL.Path, "/budgets/"), "/")[0]
+				writer.Header().Set("Content-Type", "application/json")
+
+				if request.Method == http.MethodDelete {
+					deletedBudgetIds = append(deletedBudgetIds, budgetId)
+					if testCase.failingDeletion {
+						writer.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					_, _ = writer.Write([]byte(`{"data": {"transaction": {}}}`))
+					return
+				}
+
+				if budgetId == testCase.cancelingBudget {
+					_, _ = io.Copy(io.Discard, request.Body)
+					cancel()
+					<-request.Context().Done()
+					return
+				}
+
+				if budgetId == testCase.failingBudget {
+					writer.WriteHeader(http.StatusBadRequest)
+					_, _ = writer.Write([]byte(`{"error": {"id": "400", "name": "bad_request"}}`))
+					return
+				}
+
+				body := struct {
+					Transactions []SaveTransaction `json:"transactions"`
+				}{}
+				assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+
+				response := SaveTransactionsResponse{DuplicateImportIds: []string{}}
+				for range body.Transactions {
+					transaction := TransactionDetail{}
+					transaction.Id = gofakeit.UUID()
+					response.Transactions = append(response.Transactions, transaction)
+				}
+				assert.NoError(t, json.NewEncoder(writer).Encode(map[string]any{"data": response}))
+			}))
+			defer server.Close()
+
+			client := &APIClient{Client: resty.New().SetBaseURL(server.URL)}
+
+			combinedMonthlyExpenses := CombinedMonthlyExpenses{
+				SharedMonthlyExpenses: createFakeMonthlyExpenses(nil),
+				IndividualMonthlyExpenses: []*MonthlyExpenses{
+					createFakeMonthlyExpenses(nil),
+					createFakeMonthlyExpenses(nil),
+				},
+			}
+			combinedMonthlyExpenses.SharedMonthlyExpenses.BudgetId = "shared"
+			for participantIndex, participantName := range []string{"Magui", "Jão"} {
+				combinedMonthlyExpenses.IndividualMonthlyExpenses[participantIndex].ParticipantName = participantName
+				combinedMonthlyExpenses.IndividualMonthlyExpenses[participantIndex].BudgetId = participantName
+			}
+
+			importResult := combinedMonthlyExpenses.ImportMonthlyExpenses(ctx, client, "2024-02")
+
+			assert.Equal(t, testCase.expectedSuccess, importResult.Success)
+			assert.Equal(t, testCase.expectedRolledBack, importResult.RolledBack)
+			assert.Equal(t, testCase.expectedCanceled, importResult.Canceled)
+			assert.Equal(t, testCase.expectedSuccess, importResult.Error == "", "Expected an error to be reported only if the import fails")
+
+			var actualBudgetStatuses []string
+			for _, budgetImportResult := range importResult.Budgets {
+				actualBudgetStatuses = append(actualBudgetStatuses, budgetImportResult.Status)
+			}
+
+			assert.Equal(t, testCase.expectedBudgetStatuses, actualBudgetStatuses)
+			assert.Equal(t, testCase.expectedDeletedBudgetIds, deletedBudgetIds, "Expected the created transactions to be deleted in the reverse order they were created")
+		})
+	}
+}
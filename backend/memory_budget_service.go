@@ -0,0 +1,619 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DemoEnvironmentVariable is the environment variable that, when set, makes the application import into a MemoryBudgetService seeded from the household configuration instead of YNAB
+const DemoEnvironmentVariable string = "YNAB_MONTHLY_EXPENSES_DEMO"
+
+// MemoryBudgetService is a BudgetService holding YNAB budgets, accounts, categories, payees and transactions in memory, e.g. for tests and demos
+// Every operation is recorded, so tests can check which operations were performed, and errors can be injected into the next operations
+// Like the YNAB API, every change increments its server knowledge, so delta requests only get what changed
+type MemoryBudgetService struct {
+	budgets         []*memoryBudget
+	calls           []string
+	failures        []*memoryFailure
+	serverKnowledge int64
+	lastId          int
+	mutex           sync.Mutex
+}
+
+// memoryBudget represents a YNAB budget held by the MemoryBudgetService, along with the server knowledge each of its accounts, categories, payees and transactions was last changed at
+type memoryBudget struct {
+	summary      BudgetSummary
+	accounts     Accounts
+	categories   []Category
+	payees       Payees
+	transactions []TransactionDetail
+	knowledge    map[string]int64
+}
+
+// memoryFailure represents an error the MemoryBudgetService returns instead of performing the next operations matching the given call
+type memoryFailure struct {
+	call  string
+	err   error
+	times int
+}
+
+// The MemoryBudgetService performs every operation in memory
+var _ BudgetService = (*MemoryBudgetService)(nil)
+
+// NewMemoryBudgetService creates an empty MemoryBudgetService
+func NewMemoryBudgetService() *MemoryBudgetService {
+	return &MemoryBudgetService{}
+}
+
+// NewMemoryBudgetServiceFromConfig creates a MemoryBudgetService with the YNAB budgets, accounts, monthly expenses categories and payees declared in the household configuration, e.g. to try the application out without a YNAB account
+func NewMemoryBudgetServiceFromConfig(config *Config) *MemoryBudgetService {
+	budgetService := NewMemoryBudgetService()
+
+	accountNames := map[string]string{config.Shared.Budget: config.Shared.Account}
+	for _, participant := range config.Participants {
+		if participant.Budget != "" {
+			accountNames[participant.Budget] = participant.Account
+		}
+	}
+
+	for _, budgetName := range config.GetBudgetNames() {
+		budgetId := budgetService.AddBudget(budgetName)
+		budgetService.AddAccount(budgetId, accountNames[budgetName])
+
+		for _, expense := range config.Categories.Expenses {
+			budgetService.AddCategory(budgetId, config.Categories.Group, expense.Name)
+			if expense.Payee != "" {
+				budgetService.AddPayee(budgetId, expense.Payee)
+			}
+		}
+	}
+
+	return budgetService
+}
+
+// AddBudget adds an empty YNAB budget, in euros, and returns its id
+func (budgetService *MemoryBudgetService) AddBudget(name string) string {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	budget := &memoryBudget{
+		summary: BudgetSummary{
+			Id:   budgetService.nextId("budget"),
+			Name: name,
+			CurrencyFormat: CurrencyFormat{
+				IsoCode: "EUR", ExampleFormat: "123 456,78", DecimalDigits: 2, DecimalSeparator: ",",
+				SymbolFirst: false, GroupSeparator: " ", CurrencySymbol: "€", DisplaySymbol: true,
+			},
+		},
+		knowledge: make(map[string]int64),
+	}
+	budgetService.touch(budget, budget.summary.Id)
+	budgetService.budgets = append(budgetService.budgets, budget)
+
+	return budget.summary.Id
+}
+
+// AddAccount adds an open on-budget account to a budget and returns its id
+func (budgetService *MemoryBudgetService) AddAccount(budgetId string, name string) string {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	budget := budgetService.getBudget(budgetId)
+	account := Account{Id: budgetService.nextId("account"), Name: name, Type: "checking", OnBudget: true}
+	budgetService.touch(budget, account.Id)
+	budget.accounts = append(budget.accounts, account)
+
+	return account.Id
+}
+
+// AddCategory adds a category to a category group of a budget, creating the group if needed, and returns its id
+func (budgetService *MemoryBudgetService) AddCategory(budgetId string, categoryGroupName string, name string) string {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	budget := budgetService.getBudget(budgetId)
+
+	categoryGroupId := ""
+	for _, category := range budget.categories {
+		if category.CategoryGroupName == categoryGroupName {
+			categoryGroupId = category.CategoryGroupId
+		}
+	}
+	if categoryGroupId == "" {
+		categoryGroupId = budgetService.nextId("category-group")
+	}
+
+	category := Category{Id: budgetService.nextId("category"), CategoryGroupId: categoryGroupId, CategoryGroupName: categoryGroupName, Name: name}
+	budgetService.touch(budget, category.Id)
+	budget.categories = append(budget.categories, category)
+
+	return category.Id
+}
+
+// AddPayee adds a payee to a budget, unless it already has one with the same name, and returns its id
+func (budgetService *MemoryBudgetService) AddPayee(budgetId string, name string) string {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	return budgetService.getOrCreatePayee(budgetService.getBudget(budgetId), name)
+}
+
+// GetBudgetTransactions returns the transactions of a budget which are not deleted, without recording an operation
+func (budgetService *MemoryBudgetService) GetBudgetTransactions(budgetId string) TransactionDetails {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	var transactions TransactionDetails
+	for _, transaction := range budgetService.getBudget(budgetId).transactions {
+		if !transaction.Deleted {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions
+}
+
+// Fail makes the MemoryBudgetService return the given error instead of performing the next operations matching the given call
+// The call is either the name of an operation, e.g. "CreateTransactions", or the name of an operation followed by the id of the budget it is performed on, e.g. "CreateTransactions budget-1"
+func (budgetService *MemoryBudgetService) Fail(call string, err error, times int) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	budgetService.failures = append(budgetService.failures, &memoryFailure{call: call, err: err, times: times})
+}
+
+// GetCalls returns the name of every operation performed so far, followed by the id of the budget it was performed on, if any, e.g. "CreateTransactions budget-1"
+func (budgetService *MemoryBudgetService) GetCalls() []string {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	return append([]string{}, budgetService.calls...)
+}
+
+// GetUser returns the user the MemoryBudgetService holds the budgets of
+func (budgetService *MemoryBudgetService) GetUser(ctx context.Context) (User, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetUser", ""); err != nil {
+		return User{}, err
+	}
+
+	return User{Id: "user"}, nil
+}
+
+// GetBudgets returns the list of budgets, without their accounts
+func (budgetService *MemoryBudgetService) GetBudgets(ctx context.Context) (Budgets, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetBudgets", ""); err != nil {
+		return nil, err
+	}
+
+	budgets := make(Budgets, 0, len(budgetService.budgets))
+	for _, budget := range budgetService.budgets {
+		budgets = append(budgets, budget.summary)
+	}
+
+	return budgets, nil
+}
+
+// GetAccounts returns the accounts of a budget changed since the given server knowledge, or every account if it is 0, along with the current server knowledge
+func (budgetService *MemoryBudgetService) GetAccounts(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (Accounts, int64, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetAccounts", budgetId); err != nil {
+		return nil, 0, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	accounts := Accounts{}
+	for _, account := range budget.accounts {
+		if budget.knowledge[account.Id] > lastKnowledgeOfServer {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return accounts, budgetService.serverKnowledge, nil
+}
+
+// GetCategoriesSince returns the category groups of a budget with their categories changed since the given server knowledge, or every category if it is 0, along with the current server knowledge
+func (budgetService *MemoryBudgetService) GetCategoriesSince(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (CategoryGroupsWithCategories, int64, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetCategoriesSince", budgetId); err != nil {
+		return nil, 0, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	categoryGroups := CategoryGroupsWithCategories{}
+	categoryGroupIndexes := make(map[string]int)
+
+	for _, category := range budget.categories {
+		if budget.knowledge[category.Id] <= lastKnowledgeOfServer {
+			continue
+		}
+
+		index, ok := categoryGroupIndexes[category.CategoryGroupId]
+		if !ok {
+			index = len(categoryGroups)
+			categoryGroupIndexes[category.CategoryGroupId] = index
+			categoryGroups = append(categoryGroups, CategoryGroupWithCategories{Id: category.CategoryGroupId, Name: category.CategoryGroupName})
+		}
+
+		categoryGroups[index].Categories = append(categoryGroups[index].Categories, category)
+	}
+
+	return categoryGroups, budgetService.serverKnowledge, nil
+}
+
+// GetPayees returns the payees of a budget changed since the given server knowledge, or every payee if it is 0, along with the current server knowledge
+func (budgetService *MemoryBudgetService) GetPayees(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (Payees, int64, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetPayees", budgetId); err != nil {
+		return nil, 0, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	payees := Payees{}
+	for _, payee := range budget.payees {
+		if budget.knowledge[payee.Id] > lastKnowledgeOfServer {
+			payees = append(payees, payee)
+		}
+	}
+
+	return payees, budgetService.serverKnowledge, nil
+}
+
+// GetTransactions returns the transactions of a budget matching the filter, along with the current server knowledge
+// Deleted transactions are only returned when fetching the changes since a server knowledge, and split transactions with a sub-transaction of the category of the filter are returned whole
+func (budgetService *MemoryBudgetService) GetTransactions(ctx context.Context, budgetId string, filter TransactionsFilter) (TransactionDetails, int64, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetTransactions", budgetId); err != nil {
+		return nil, 0, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	transactions := TransactionDetails{}
+	for _, transaction := range budget.transactions {
+		switch {
+		case budget.knowledge[transaction.Id] <= filter.LastKnowledgeOfServer:
+		case transaction.Deleted && filter.LastKnowledgeOfServer == 0:
+		case filter.SinceDate != "" && transaction.Date < filter.SinceDate:
+		case filter.Type == TransactionTypeUnapproved && transaction.Approved:
+		case filter.Type == TransactionTypeUncategorized && (transaction.CategoryId != "" || len(transaction.SubTransactions) > 0):
+		case filter.AccountId != "" && transaction.AccountId != filter.AccountId:
+		default:
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	if filter.CategoryId != "" {
+		transactions = transactions.filterByCategory(filter.CategoryId)
+	}
+
+	return transactions, budgetService.serverKnowledge, nil
+}
+
+// GetTransaction returns a transaction of a budget by id, even if it is deleted
+func (budgetService *MemoryBudgetService) GetTransaction(ctx context.Context, budgetId string, transactionId string) (TransactionDetail, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "GetTransaction", budgetId); err != nil {
+		return TransactionDetail{}, err
+	}
+
+	for _, transaction := range budgetService.getBudget(budgetId).transactions {
+		if transaction.Id == transactionId {
+			return transaction, nil
+		}
+	}
+
+	return TransactionDetail{}, newResourceNotFoundError()
+}
+
+// CreateTransactions creates transactions in a budget, skipping the ones whose import id already exists in their account
+// Like the YNAB API, it creates none of them if any is for an account the budget does not have
+func (budgetService *MemoryBudgetService) CreateTransactions(ctx context.Context, budgetId string, transactions []SaveTransaction) (SaveTransactionsResponse, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "CreateTransactions", budgetId); err != nil {
+		return SaveTransactionsResponse{}, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	for _, transaction := range transactions {
+		if transaction.AccountId == nil || budget.getAccount(*transaction.AccountId) == nil {
+			return SaveTransactionsResponse{}, &APIError{StatusCode: http.StatusBadRequest, Id: "400", Name: "bad_request", Detail: "account does not exist"}
+		}
+	}
+
+	saveTransactionsResponse := SaveTransactionsResponse{TransactionIds: []string{}, Transactions: []TransactionDetail{}, DuplicateImportIds: []string{}}
+
+	for _, transaction := range transactions {
+		if transaction.ImportId != nil && budget.hasImportId(*transaction.AccountId, *transaction.ImportId) {
+			saveTransactionsResponse.DuplicateImportIds = append(saveTransactionsResponse.DuplicateImportIds, *transaction.ImportId)
+			continue
+		}
+
+		createdTransaction := budgetService.newTransactionDetail(budget, transaction)
+		budgetService.touch(budget, createdTransaction.Id)
+		budget.transactions = append(budget.transactions, createdTransaction)
+
+		saveTransactionsResponse.TransactionIds = append(saveTransactionsResponse.TransactionIds, createdTransaction.Id)
+		saveTransactionsResponse.Transactions = append(saveTransactionsResponse.Transactions, createdTransaction)
+	}
+
+	saveTransactionsResponse.ServerKnowledge = budgetService.serverKnowledge
+
+	return saveTransactionsResponse, nil
+}
+
+// UpdateTransactions updates transactions of a budget, leaving the fields that are not set as they are
+// Like the YNAB API, it updates none of them if any does not exist or is moved to an account the budget does not have
+func (budgetService *MemoryBudgetService) UpdateTransactions(ctx context.Context, budgetId string, transactions []SaveTransactionWithId) (SaveTransactionsResponse, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "UpdateTransactions", budgetId); err != nil {
+		return SaveTransactionsResponse{}, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	for _, transaction := range transactions {
+		if budget.getTransaction(transaction.Id) == nil {
+			return SaveTransactionsResponse{}, newResourceNotFoundError()
+		}
+		if transaction.AccountId != nil && budget.getAccount(*transaction.AccountId) == nil {
+			return SaveTransactionsResponse{}, &APIError{StatusCode: http.StatusBadRequest, Id: "400", Name: "bad_request", Detail: "account does not exist"}
+		}
+	}
+
+	saveTransactionsResponse := SaveTransactionsResponse{TransactionIds: []string{}, Transactions: []TransactionDetail{}, DuplicateImportIds: []string{}}
+
+	for _, transaction := range transactions {
+		updatedTransaction := budget.getTransaction(transaction.Id)
+		budgetService.updateTransactionDetail(budget, updatedTransaction, transaction)
+		budgetService.touch(budget, updatedTransaction.Id)
+
+		saveTransactionsResponse.TransactionIds = append(saveTransactionsResponse.TransactionIds, updatedTransaction.Id)
+		saveTransactionsResponse.Transactions = append(saveTransactionsResponse.Transactions, *updatedTransaction)
+	}
+
+	saveTransactionsResponse.ServerKnowledge = budgetService.serverKnowledge
+
+	return saveTransactionsResponse, nil
+}
+
+// DeleteTransaction deletes a transaction from a budget
+func (budgetService *MemoryBudgetService) DeleteTransaction(ctx context.Context, budgetId string, transactionId string) (TransactionDetail, error) {
+	budgetService.mutex.Lock()
+	defer budgetService.mutex.Unlock()
+
+	if err := budgetService.record(ctx, "DeleteTransaction", budgetId); err != nil {
+		return TransactionDetail{}, err
+	}
+
+	budget := budgetService.getBudget(budgetId)
+
+	transaction := budget.getTransaction(transactionId)
+	if transaction == nil {
+		return TransactionDetail{}, newResourceNotFoundError()
+	}
+
+	transaction.Deleted = true
+	budgetService.touch(budget, transactionId)
+
+	return *transaction, nil
+}
+
+// record records an operation, then returns the error it fails with, if any: the context error if it is done, the next injected failure, or a not found error if the budget does not exist
+func (budgetService *MemoryBudgetService) record(ctx context.Context, operation string, budgetId string) error {
+	call := operation
+	if budgetId != "" {
+		call += " " + budgetId
+	}
+	budgetService.calls = append(budgetService.calls, call)
+
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return ErrCanceled
+		}
+		return err
+	}
+
+	for _, failure := range budgetService.failures {
+		if failure.times > 0 && (failure.call == operation || failure.call == call) {
+			failure.times--
+			return failure.err
+		}
+	}
+
+	if budgetId != "" && budgetService.getBudget(budgetId) == nil {
+		return newResourceNotFoundError()
+	}
+
+	return nil
+}
+
+// touch marks a budget and one of its accounts, categories, payees or transactions as changed, incrementing the server knowledge
+func (budgetService *MemoryBudgetService) touch(budget *memoryBudget, id string) {
+	budgetService.serverKnowledge++
+	budget.knowledge[id] = budgetService.serverKnowledge
+	budget.summary.LastModifiedOn = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(budgetService.serverKnowledge) * time.Second).Format(time.RFC3339)
+}
+
+// nextId generates a unique id with the given prefix
+func (budgetService *MemoryBudgetService) nextId(prefix string) string {
+	budgetService.lastId++
+
+	return fmt.Sprintf("%s-%d", prefix, budgetService.lastId)
+}
+
+// getBudget finds a budget by id
+func (budgetService *MemoryBudgetService) getBudget(budgetId string) *memoryBudget {
+	for _, budget := range budgetService.budgets {
+		if budget.summary.Id == budgetId {
+			return budget
+		}
+	}
+
+	return nil
+}
+
+// getOrCreatePayee returns the id of the payee of a budget with the given name, creating it if needed
+func (budgetService *MemoryBudgetService) getOrCreatePayee(budget *memoryBudget, name string) string {
+	for _, payee := range budget.payees {
+		if payee.Name == name {
+			return payee.Id
+		}
+	}
+
+	payee := Payee{Id: budgetService.nextId("payee"), Name: name}
+	budgetService.touch(budget, payee.Id)
+	budget.payees = append(budget.payees, payee)
+
+	return payee.Id
+}
+
+// newTransactionDetail creates the details of a new transaction of a budget from the schema it is saved with, creating its payee if needed
+func (budgetService *MemoryBudgetService) newTransactionDetail(budget *memoryBudget, transaction SaveTransaction) TransactionDetail {
+	transactionDetail := TransactionDetail{
+		TransactionSummary: TransactionSummary{
+			Id:         budgetService.nextId("transaction"),
+			Date:       transaction.Date,
+			Amount:     transaction.Amount,
+			Memo:       getStringOrEmpty(transaction.Memo),
+			Cleared:    transaction.Cleared,
+			Approved:   transaction.Approved,
+			FlagColor:  getStringOrEmpty(transaction.FlagColor),
+			AccountId:  *transaction.AccountId,
+			PayeeId:    getStringOrEmpty(transaction.PayeeId),
+			CategoryId: getStringOrEmpty(transaction.CategoryId),
+			ImportId:   getStringOrEmpty(transaction.ImportId),
+		},
+		AccountName: budget.getAccount(*transaction.AccountId).Name,
+		PayeeName:   getStringOrEmpty(transaction.PayeeName),
+	}
+
+	if transactionDetail.PayeeId == "" && transactionDetail.PayeeName != "" {
+		transactionDetail.PayeeId = budgetService.getOrCreatePayee(budget, transactionDetail.PayeeName)
+	}
+
+	for _, subTransaction := range transaction.SubTransactions {
+		transactionDetail.SubTransactions = append(transactionDetail.SubTransactions, SubTransaction{
+			Id:            budgetService.nextId("subtransaction"),
+			TransactionId: transactionDetail.Id,
+			Amount:        subTransaction.Amount,
+			Memo:          getStringOrEmpty(subTransaction.Memo),
+			PayeeId:       getStringOrEmpty(subTransaction.PayeeId),
+			PayeeName:     getStringOrEmpty(subTransaction.PayeeName),
+			CategoryId:    getStringOrEmpty(subTransaction.CategoryId),
+		})
+	}
+
+	return transactionDetail
+}
+
+// updateTransactionDetail updates the fields of a transaction of a budget that are set in the schema it is saved with, creating its payee if needed
+func (budgetService *MemoryBudgetService) updateTransactionDetail(budget *memoryBudget, transactionDetail *TransactionDetail, transaction SaveTransactionWithId) {
+	if transaction.AccountId != nil {
+		transactionDetail.AccountId = *transaction.AccountId
+		transactionDetail.AccountName = budget.getAccount(*transaction.AccountId).Name
+	}
+	if transaction.Date != nil {
+		transactionDetail.Date = *transaction.Date
+	}
+	if transaction.Amount != nil {
+		transactionDetail.Amount = *transaction.Amount
+	}
+	if transaction.PayeeName != nil {
+		transactionDetail.PayeeName = *transaction.PayeeName
+		transactionDetail.PayeeId = budgetService.getOrCreatePayee(budget, *transaction.PayeeName)
+	}
+	if transaction.PayeeId != nil {
+		transactionDetail.PayeeId = *transaction.PayeeId
+	}
+	if transaction.CategoryId != nil {
+		transactionDetail.CategoryId = *transaction.CategoryId
+	}
+	if transaction.Memo != nil {
+		transactionDetail.Memo = *transaction.Memo
+	}
+	if transaction.Cleared != nil {
+		transactionDetail.Cleared = *transaction.Cleared
+	}
+	if transaction.Approved != nil {
+		transactionDetail.Approved = *transaction.Approved
+	}
+	if transaction.FlagColor != nil {
+		transactionDetail.FlagColor = *transaction.FlagColor
+	}
+}
+
+// getAccount finds an open account of the budget by id
+func (budget *memoryBudget) getAccount(accountId string) *Account {
+	for index, account := range budget.accounts {
+		if account.Id == accountId && !account.Deleted {
+			return &budget.accounts[index]
+		}
+	}
+
+	return nil
+}
+
+// getTransaction finds a transaction of the budget by id, unless it is deleted
+func (budget *memoryBudget) getTransaction(transactionId string) *TransactionDetail {
+	for index, transaction := range budget.transactions {
+		if transaction.Id == transactionId && !transaction.Deleted {
+			return &budget.transactions[index]
+		}
+	}
+
+	return nil
+}
+
+// hasImportId checks if a transaction of an account already has the import id
+// Like the YNAB API, a deleted transaction still holds its import id, so a transaction with the same import id is never created again
+func (budget *memoryBudget) hasImportId(accountId string, importId string) bool {
+	for _, transaction := range budget.transactions {
+		if transaction.AccountId == accountId && transaction.ImportId == importId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newResourceNotFoundError creates the error the YNAB API responds with when a budget or transaction does not exist
+func newResourceNotFoundError() *APIError {
+	return &APIError{StatusCode: http.StatusNotFound, Id: "404.2", Name: "resource_not_found", Detail: "Resource not found"}
+}
+
+// getStringOrEmpty dereferences an optional string, or returns an empty string if it is nil
+func getStringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
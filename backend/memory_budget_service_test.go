@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportWithMemoryBudgetService(t *testing.T) {
+	testCases := map[string]struct {
+		failBudget           string
+		expectedSuccess      bool
+		expectedRolledBack   bool
+		expectedSharedCount  int
+		expectedMaguiCount   int
+		expectedRollbackCall bool
+	}{
+		"every budget imported": {
+			expectedSuccess:     true,
+			expectedSharedCount: 4,
+			expectedMaguiCount:  1,
+		},
+		"individual budget rejects the transactions - shared budget rolled back": {
+			failBudget:           "Magui",
+			expectedRolledBack:   true,
+			expectedRollbackCall: true,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			setupApplicationDirectory(t)
+
+			config, err := LoadConfig(ConfigPath())
+			assert.NoError(t, err)
+
+			budgetService := NewMemoryBudgetServiceFromConfig(config)
+			budgets, err := budgetService.GetBudgets(context.Background())
+			assert.NoError(t, err)
+			sharedBudgetId, maguiBudgetId := budgets.GetBudget("Casa").Id, budgets.GetBudget("Magui").Id
+
+			if testCase.failBudget != "" {
+				budgetService.Fail("CreateTransactions "+budgets.GetBudget(testCase.failBudget).Id, &APIError{StatusCode: http.StatusBadRequest}, 1)
+			}
+
+			backend := SetupBackendWithBudgetService(budgetService)
+
+			assert.NoError(t, backend.SetupError)
+			assert.True(t, backend.Diagnostics.Ready, "Expected every check to pass, but got %v", backend.Diagnostics.GetFailedChecks())
+
+			importResult := backend.CreateMonthlyExpensesTransactions(splitFakeMonthlyExpenses(t, backend), "")
+
+			assert.Equal(t, testCase.expectedSuccess, importResult.Success, importResult.Error)
+			assert.Equal(t, testCase.expectedRolledBack, importResult.RolledBack)
+			assert.False(t, importResult.Queued, "Expected a rejected import not to be queued")
+			assert.Len(t, budgetService.GetBudgetTransactions(sharedBudgetId), testCase.expectedSharedCount)
+			assert.Len(t, budgetService.GetBudgetTransactions(maguiBudgetId), testCase.expectedMaguiCount)
+			assert.Contains(t, budgetService.GetCalls(), "CreateTransactions "+maguiBudgetId)
+			if testCase.expectedRollbackCall {
+				assert.Contains(t, budgetService.GetCalls(), "DeleteTransaction "+sharedBudgetId, "Expected the shared budget transactions to be deleted")
+			}
+		})
+	}
+}
+
+func TestMemoryBudgetServiceTransactions(t *testing.T) {
+	budgetService := NewMemoryBudgetService()
+	budgetId := budgetService.AddBudget("Casa")
+	accountId := budgetService.AddAccount(budgetId, "Millennium bcp")
+	importId := "YNAB-MONTHLY-EXPENSES:2024-02:Water"
+
+	saveTransactions := []SaveTransaction{{AccountId: &accountId, Date: "2024-02-01", Amount: -60250, ImportId: &importId}}
+
+	response, err := budgetService.CreateTransactions(context.Background(), budgetId, saveTransactions)
+	assert.NoError(t, err)
+	assert.Len(t, response.TransactionIds, 1)
+
+	response, err = budgetService.CreateTransactions(context.Background(), budgetId, saveTransactions)
+	assert.NoError(t, err)
+	assert.Empty(t, response.TransactionIds)
+	assert.Equal(t, []string{importId}, response.DuplicateImportIds)
+
+	accounts, serverKnowledge, err := budgetService.GetAccounts(context.Background(), budgetId, 0)
+	assert.NoError(t, err)
+	assert.Len(t, accounts, 1)
+
+	accounts, _, err = budgetService.GetAccounts(context.Background(), budgetId, serverKnowledge)
+	assert.NoError(t, err)
+	assert.Empty(t, accounts, "Expected no accounts to have changed since the last server knowledge")
+
+	transactions, _, err := budgetService.GetTransactions(context.Background(), budgetId, TransactionsFilter{Type: TransactionTypeUnapproved})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+
+	_, err = budgetService.UpdateTransactions(context.Background(), budgetId, []SaveTransactionWithId{{Id: transactions[0].Id, Approved: to.BoolPtr(true)}})
+	assert.NoError(t, err)
+
+	transactions, _, err = budgetService.GetTransactions(context.Background(), budgetId, TransactionsFilter{Type: TransactionTypeUnapproved})
+	assert.NoError(t, err)
+	assert.Empty(t, transactions, "Expected the approved transaction not to be fetched as unapproved")
+	assert.Equal(t, int64(-60250), budgetService.GetBudgetTransactions(budgetId)[0].Amount, "Expected the fields that are not set to be left as they are")
+
+	_, err = budgetService.DeleteTransaction(context.Background(), "unknown", response.DuplicateImportIds[0])
+	assert.ErrorIs(t, err, ErrNotFound)
+}
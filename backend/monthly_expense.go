@@ -1,8 +1,9 @@
 package backend
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
 	"strings"
 	"time"
 
@@ -12,68 +13,72 @@ import (
 	"golang.org/x/exp/slices"
 )
 
-// MonthlyExpense represents a monthly expense with its YNAB category id, payee name, amount, and memo
+// ErrSplitMismatch is the error of monthly expenses whose individual shares were not split from their shared amounts, e.g. because an amount was changed after splitting them
+var ErrSplitMismatch = errors.New("the individual shares do not match the shared monthly expenses, split them again")
+
+// MonthlyExpense represents a monthly expense with its YNAB category id, payee name, amount, memo, and, for shared monthly expenses, the rule used to split it
 type MonthlyExpense struct {
 	CategoryId *string         `json:"category_id" mapstructure:"category_id" fake:"{uuid}"`
 	PayeeName  *string         `json:"payee_name" mapstructure:"payee_name" fake:"{company}"`
 	Amount     decimal.Decimal `json:"amount" mapstructure:"amount" fake:"skip"`
 	Memo       *string         `json:"memo" mapstructure:"memo" fake:"{sentence}"`
+	SplitRule  *SplitRule      `json:"split_rule" mapstructure:"split_rule" fake:"skip"`
 }
 
 // MonthlyExpenses represents a collection of monthly expenses per category for a specific YNAB budget and account
+// For individual monthly expenses, the participant name identifies whose share it is and the YNAB budget and account are only set if the participant has them
 type MonthlyExpenses struct {
-	BudgetId  string                     `json:"budget_id" mapstructure:"budget_id" fake:"{uuid}"`
-	AccountId string                     `json:"account_id" mapstructure:"account_id" fake:"{uuid}"`
-	Expenses  map[string]*MonthlyExpense `json:"expenses" mapstructure:"expenses" fake:"skip"`
+	ParticipantName string                     `json:"participant_name" mapstructure:"participant_name" fake:"{firstname}"`
+	BudgetId        string                     `json:"budget_id" mapstructure:"budget_id" fake:"{uuid}"`
+	AccountId       string                     `json:"account_id" mapstructure:"account_id" fake:"{uuid}"`
+	Expenses        map[string]*MonthlyExpense `json:"expenses" mapstructure:"expenses" fake:"skip"`
 }
 
-// CombinedMonthlyExpenses represents a collection of monthly expenses, combining both the shared and individual monthly expenses
+// CombinedMonthlyExpenses represents a collection of monthly expenses, combining the shared monthly expenses and the individual monthly expenses of each participant
+// The rounding strategy decides how the individual shares are rounded when splitting, which defaults to the alternating rounding strategy
 type CombinedMonthlyExpenses struct {
-	SharedMonthlyExpenses     *MonthlyExpenses `json:"shared_monthly_expenses"`
-	IndividualMonthlyExpenses *MonthlyExpenses `json:"individual_monthly_expenses"`
+	SharedMonthlyExpenses     *MonthlyExpenses   `json:"shared_monthly_expenses"`
+	IndividualMonthlyExpenses []*MonthlyExpenses `json:"individual_monthly_expenses"`
+	RoundingStrategy          RoundingStrategy   `json:"-"`
 }
 
-// IsValid checks if a collection of monthly expenses is valid by ensuring a non-empty YNAB budget id and account id, and having exactly 4 monthly expenses
+// IsValid checks if a collection of monthly expenses is valid by ensuring a non-empty YNAB budget id and account id, and having at least one monthly expense, each with a YNAB category id
 func (monthlyExpenses *MonthlyExpenses) IsValid() bool {
-	return monthlyExpenses.AccountId != "" &&
-		monthlyExpenses.BudgetId != "" &&
-		len(monthlyExpenses.Expenses) == 4
-}
+	if monthlyExpenses.AccountId == "" || monthlyExpenses.BudgetId == "" || len(monthlyExpenses.Expenses) == 0 {
+		return false
+	}
 
-// GetSharedMonthlyExpensePayeeName returns the predefined payee name for a given shared monthly expense category
-func GetSharedMonthlyExpensePayeeName(categoryName string) string {
-	var payeeName string
-
-	switch categoryName {
-	case "Condominium":
-		payeeName = "Loja do Condomínio"
-	case "Electricity":
-		payeeName = "EDP"
-	case "Water":
-		payeeName = "EPAL"
-	case "TV / Internet / Phone":
-		payeeName = "Vodafone"
+	for _, monthlyExpense := range monthlyExpenses.Expenses {
+		if monthlyExpense.CategoryId == nil || *monthlyExpense.CategoryId == "" {
+			return false
+		}
 	}
 
-	return payeeName
+	return true
 }
 
-// GetSharedMonthlyExpenseMemo returns the predefined memo for a given shared monthly expense category
-func GetSharedMonthlyExpenseMemo(categoryName string) string {
-	switch categoryName {
-	case "Condominium":
-		currentMonth := time.Now()
+// GetSharedMonthlyExpenseMemo returns the memo for a shared monthly expense generated by its memo rule
+func GetSharedMonthlyExpenseMemo(memo MemoConfig) string {
+	currentMonth := time.Now()
+
+	switch memo.Rule {
+	case MemoRuleText:
+		return memo.Text
+	case MemoRuleCurrentMonth:
+		return currentMonth.Format("January 2006")
+	case MemoRuleNextMonth:
 		nextMonth := time.Date(currentMonth.Year(), currentMonth.Month()+1, 1, 0, 0, 0, 0, currentMonth.Location())
 		return nextMonth.Format("January 2006")
-	case "Electricity":
-		return getSharedMonthlyExpenseBillingCycleMemo(11, 10)
-	case "Water":
-		return getSharedMonthlyExpenseBillingCycleMemo(4, 3)
-	case "TV / Internet / Phone":
-		return fmt.Sprintf("%s & %s",
-			getSharedMonthlyExpenseBillingCycleMemo(9, 8),
-			strings.Split(getSharedMonthlyExpenseBillingCycleMemo(16, 15), "- ")[1],
-		)
+	case MemoRuleBillingCycle:
+		var billingCycleMemos []string
+		for index, billingCycle := range memo.BillingCycles {
+			billingCycleMemo := getSharedMonthlyExpenseBillingCycleMemo(billingCycle.Start, billingCycle.End)
+			if index > 0 {
+				billingCycleMemo = strings.Split(billingCycleMemo, "- ")[1]
+			}
+			billingCycleMemos = append(billingCycleMemos, billingCycleMemo)
+		}
+		return strings.Join(billingCycleMemos, " & ")
 	default:
 		return ""
 	}
@@ -101,148 +106,151 @@ func GetIndividualMonthlyExpenseMemo() string {
 	return fmt.Sprintf("%s - Household Expenses", time.Now().Format("January 2006"))
 }
 
-// SplitSharedMonthlyExpenses calculates the individual share for each monthly expense category
-func (combinedMonthlyExpenses *CombinedMonthlyExpenses) SplitSharedMonthlyExpenses() {
+// SplitSharedMonthlyExpenses calculates the individual share of each participant for each monthly expense category according to its split rule
+// The shares of a category always sum exactly to the shared amount, with the cents left over by rounding down distributed among the participants by the rounding strategy
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) SplitSharedMonthlyExpenses() error {
 	sharedMonthlyExpenses := combinedMonthlyExpenses.SharedMonthlyExpenses
 	individualMonthlyExpenses := combinedMonthlyExpenses.IndividualMonthlyExpenses
 
-	roundUp := rand.Float64() <= 0.4
+	participantCount := len(individualMonthlyExpenses)
+	if participantCount == 0 {
+		return nil
+	}
+
+	participantNames := make([]string, 0, participantCount)
+	for _, monthlyExpenses := range individualMonthlyExpenses {
+		participantNames = append(participantNames, monthlyExpenses.ParticipantName)
+	}
+
+	if combinedMonthlyExpenses.RoundingStrategy == nil {
+		combinedMonthlyExpenses.RoundingStrategy = NewAlternatingRoundingStrategy(nil)
+	}
+	roundingStrategy := combinedMonthlyExpenses.RoundingStrategy
+
+	roundingStrategy.BeginSplit(participantNames)
 
 	categoryNames := maps.Keys(sharedMonthlyExpenses.Expenses)
 	slices.Sort(categoryNames)
 
 	for _, categoryName := range categoryNames {
 		sharedMonthlyExpense := sharedMonthlyExpenses.Expenses[categoryName]
-		individualMonthlyExpense := individualMonthlyExpenses.Expenses[categoryName]
+		sharedExpenseAmount := sharedMonthlyExpense.Amount.Round(2)
 
-		splitExpenseAmount := sharedMonthlyExpense.Amount.Div(decimal.NewFromInt(2))
-		roundedSplitExpenseAmount := sharedMonthlyExpense.Amount.DivRound(decimal.NewFromInt(2), 2)
+		exactShares, err := sharedMonthlyExpense.SplitRule.GetExactShares(sharedExpenseAmount, participantNames)
+		if err != nil {
+			return fmt.Errorf("splitting %s: %w", categoryName, err)
+		}
 
-		if !splitExpenseAmount.Equal(roundedSplitExpenseAmount) {
-			if roundUp {
-				splitExpenseAmount = splitExpenseAmount.RoundUp(2)
-			} else {
-				splitExpenseAmount = splitExpenseAmount.RoundDown(2)
-			}
+		allocation := RoundingAllocation{
+			CategoryName:     categoryName,
+			ParticipantNames: participantNames,
+			ExactShares:      exactShares,
+			Shares:           make([]decimal.Decimal, participantCount),
+		}
 
-			roundUp = !roundUp
+		leftoverAmount := sharedExpenseAmount
+		for participantIndex, exactShare := range exactShares {
+			allocation.Shares[participantIndex] = exactShare.RoundFloor(2)
+			leftoverAmount = leftoverAmount.Sub(allocation.Shares[participantIndex])
 		}
+		allocation.LeftoverCents = leftoverAmount.Shift(2).IntPart()
 
-		individualMonthlyExpense.Amount = splitExpenseAmount
-	}
-}
+		roundingStrategy.DistributeLeftoverCents(&allocation)
 
-// CreateSharedMonthlyExpensesTransactions creates the YNAB transactions for the shared monthly expenses
-func (combinedMonthlyExpenses *CombinedMonthlyExpenses) CreateSharedMonthlyExpensesTransactions(client APIClient) bool {
-	sharedMonthlyExpenses := combinedMonthlyExpenses.SharedMonthlyExpenses
-	individualMonthlyExpenses := combinedMonthlyExpenses.IndividualMonthlyExpenses
+		if allocation.LeftoverCents != 0 {
+			return fmt.Errorf("splitting %s: %d leftover cents were not distributed", categoryName, allocation.LeftoverCents)
+		}
+
+		for participantIndex, monthlyExpenses := range individualMonthlyExpenses {
+			individualMonthlyExpense, ok := monthlyExpenses.Expenses[categoryName]
+			if !ok {
+				individualMonthlyExpense = &MonthlyExpense{}
+				monthlyExpenses.Expenses[categoryName] = individualMonthlyExpense
+			}
 
-	var transactions []SaveTransaction
-
-	var subTransactionsForMyIndividualShare []SaveSubTransaction
-	var totalMyIndividualShareAmount decimal.Decimal
-
-	var subTransactionsForOtherIndividualShare []SaveSubTransaction
-	var totalOtherIndividualShareAmount decimal.Decimal
-
-	for categoryName, monthlyExpense := range sharedMonthlyExpenses.Expenses {
-		transactionAmount := monthlyExpense.Amount
-
-		transactions = append(transactions,
-			createTransaction(
-				sharedMonthlyExpenses.AccountId,
-				transactionAmount.Neg(),
-				monthlyExpense.PayeeName,
-				monthlyExpense.CategoryId,
-				monthlyExpense.Memo,
-				nil,
-			),
-		)
-
-		myIndividualShareAmount := individualMonthlyExpenses.Expenses[categoryName].Amount
-		totalMyIndividualShareAmount = totalMyIndividualShareAmount.Add(myIndividualShareAmount)
-
-		otherIndividualShareAmount := transactionAmount.Sub(myIndividualShareAmount)
-		totalOtherIndividualShareAmount = totalOtherIndividualShareAmount.Add(otherIndividualShareAmount)
-
-		subTransactionsForMyIndividualShare = append(subTransactionsForMyIndividualShare,
-			createSubTransaction(
-				myIndividualShareAmount,
-				monthlyExpense.CategoryId,
-			),
-		)
-
-		subTransactionsForOtherIndividualShare = append(subTransactionsForOtherIndividualShare,
-			createSubTransaction(
-				otherIndividualShareAmount,
-				monthlyExpense.CategoryId,
-			),
-		)
+			individualMonthlyExpense.Amount = allocation.Shares[participantIndex]
+		}
 	}
 
-	transactions = append(transactions,
-		createTransaction(
-			sharedMonthlyExpenses.AccountId,
-			totalMyIndividualShareAmount,
-			to.StringPtr(GetIndividualMonthlyExpensePayeeName("Magui")),
-			nil,
-			to.StringPtr(GetIndividualMonthlyExpenseMemo()),
-			subTransactionsForMyIndividualShare,
-		),
-		createTransaction(
-			sharedMonthlyExpenses.AccountId,
-			totalOtherIndividualShareAmount,
-			to.StringPtr(GetIndividualMonthlyExpensePayeeName("Jão")),
-			nil,
-			to.StringPtr(GetIndividualMonthlyExpenseMemo()),
-			subTransactionsForOtherIndividualShare,
-		),
-	)
+	return nil
+}
 
-	_, err := client.CreateTransactions(sharedMonthlyExpenses.BudgetId, transactions)
+// CheckSplit checks that the individual shares of each shared monthly expense add up to its amount and are its exact shares, according to the given split rules by category name, rounded to the cent
+// The participants and categories must be the given ones, as declared in the configuration
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) CheckSplit(participantNames []string, splitRules map[string]*SplitRule) error {
+	if combinedMonthlyExpenses.SharedMonthlyExpenses == nil {
+		return fmt.Errorf("%w: no shared monthly expenses", ErrSplitMismatch)
+	}
 
-	return err == nil
-}
+	individualParticipantNames := make([]string, 0, len(combinedMonthlyExpenses.IndividualMonthlyExpenses))
+	for _, monthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+		individualParticipantNames = append(individualParticipantNames, monthlyExpenses.ParticipantName)
+	}
+	if !slices.Equal(individualParticipantNames, participantNames) {
+		return fmt.Errorf("%w: the participants are %s instead of %s", ErrSplitMismatch, strings.Join(individualParticipantNames, ", "), strings.Join(participantNames, ", "))
+	}
 
-// CreateIndividualMonthlyExpensesTransactions creates the YNAB transactions for the individual monthly expenses
-func (combinedMonthlyExpenses *CombinedMonthlyExpenses) CreateIndividualMonthlyExpensesTransactions(client APIClient) bool {
-	individualMonthlyExpenses := combinedMonthlyExpenses.IndividualMonthlyExpenses
+	categoryNames := maps.Keys(combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses)
+	slices.Sort(categoryNames)
 
-	var sampleExpense MonthlyExpense
+	for _, categoryName := range categoryNames {
+		splitRule, ok := splitRules[categoryName]
+		if !ok {
+			return fmt.Errorf("%w: category %q is not declared in the configuration", ErrSplitMismatch, categoryName)
+		}
 
-	var subTransactions []SaveSubTransaction
-	var totalTransactionAmount decimal.Decimal
+		sharedExpenseAmount := combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses[categoryName].Amount.Round(2)
+		exactShares, err := splitRule.GetExactShares(sharedExpenseAmount, participantNames)
+		if err != nil {
+			return fmt.Errorf("splitting %s: %w", categoryName, err)
+		}
 
-	for _, monthlyExpense := range individualMonthlyExpenses.Expenses {
-		sampleExpense = *monthlyExpense
+		sharesTotal := decimal.Zero
+		for participantIndex, monthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+			individualMonthlyExpense, ok := monthlyExpenses.Expenses[categoryName]
+			if !ok {
+				return fmt.Errorf("%w: %s has no share of %s", ErrSplitMismatch, monthlyExpenses.ParticipantName, categoryName)
+			}
 
-		subTransactionAmount := monthlyExpense.Amount
-		totalTransactionAmount = totalTransactionAmount.Add(subTransactionAmount)
+			// Rounding moves a share by less than a cent away from the exact share
+			if individualMonthlyExpense.Amount.Sub(exactShares[participantIndex]).Abs().GreaterThanOrEqual(decimal.New(1, -2)) {
+				return fmt.Errorf("%w: the share of %s of %s is %s instead of %s", ErrSplitMismatch, monthlyExpenses.ParticipantName, categoryName, individualMonthlyExpense.Amount, exactShares[participantIndex].StringFixed(2))
+			}
+			sharesTotal = sharesTotal.Add(individualMonthlyExpense.Amount)
+		}
 
-		subTransactions = append(subTransactions,
-			createSubTransaction(
-				subTransactionAmount.Neg(),
-				monthlyExpense.CategoryId,
-			),
-		)
+		if !sharesTotal.Equal(sharedExpenseAmount) {
+			return fmt.Errorf("%w: the shares of %s add up to %s instead of %s", ErrSplitMismatch, categoryName, sharesTotal, sharedExpenseAmount)
+		}
 	}
 
-	transaction := createTransaction(
-		individualMonthlyExpenses.AccountId,
-		totalTransactionAmount.Neg(),
-		sampleExpense.PayeeName,
-		nil,
-		sampleExpense.Memo,
-		subTransactions,
-	)
+	return nil
+}
 
-	_, err := client.CreateTransaction(individualMonthlyExpenses.BudgetId, transaction)
+// CreateSharedMonthlyExpensesTransactions creates the YNAB transactions for the shared monthly expenses of a month (formatted as YYYY-MM)
+// Every transaction has a deterministic import id, so transactions already created for the month are reported instead of being duplicated
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) CreateSharedMonthlyExpensesTransactions(ctx context.Context, budgetService BudgetService, month string) (BudgetImportResult, error) {
+	return combinedMonthlyExpenses.GetSharedMonthlyExpensesImportPlan(month).execute(ctx, budgetService)
+}
+
+// CreateIndividualMonthlyExpensesTransactions creates the YNAB transactions for the individual monthly expenses of a month (formatted as YYYY-MM) of each participant with a YNAB budget and account
+// The import results of the participants whose transaction was created before a failure, as well as of the participant whose transaction failed, are returned along with the error
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) CreateIndividualMonthlyExpensesTransactions(ctx context.Context, budgetService BudgetService, month string) ([]BudgetImportResult, error) {
+	var budgetImportResults []BudgetImportResult
+
+	for _, budgetImportPlan := range combinedMonthlyExpenses.GetIndividualMonthlyExpensesImportPlans(month) {
+		budgetImportResult, err := budgetImportPlan.execute(ctx, budgetService)
+		budgetImportResults = append(budgetImportResults, budgetImportResult)
+		if err != nil {
+			return budgetImportResults, err
+		}
+	}
 
-	return err == nil
+	return budgetImportResults, nil
 }
 
 // createTransaction creates a new SaveTransaction instance
-func createTransaction(accountId string, amount decimal.Decimal, payeeName *string, categoryId *string, memo *string, subTransactions []SaveSubTransaction) SaveTransaction {
+func createTransaction(accountId string, amount decimal.Decimal, payeeName *string, categoryId *string, memo *string, importId string, subTransactions []SaveSubTransaction) SaveTransaction {
 	return SaveTransaction{
 		AccountId:       to.StringPtr(accountId),
 		Date:            time.Now().Format("2006-01-02"),
@@ -252,6 +260,7 @@ func createTransaction(accountId string, amount decimal.Decimal, payeeName *stri
 		Memo:            memo,
 		Cleared:         "uncleared",
 		Approved:        false,
+		ImportId:        to.StringPtr(importId),
 		SubTransactions: subTransactions,
 	}
 }
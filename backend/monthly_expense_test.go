@@ -2,10 +2,8 @@ package backend
 
 import (
 	"fmt"
-	"math/rand"
 	"testing"
 
-	"github.com/agiledragon/gomonkey/v2"
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -93,24 +91,25 @@ func TestSplitSharedMonthlyExpenses(t *testing.T) {
 
 	for testName, testCase := range testCases {
 		t.Run(testName, func(t *testing.T) {
-			patches := gomonkey.ApplyFunc(rand.Float64, func() float64 {
-				if testCase.roundUp {
-					return 0.0
-				} else {
-					return 0.9
-				}
-			})
-			defer patches.Reset()
+			roundUpParticipant := fixedRandomSource(1)
+			if testCase.roundUp {
+				roundUpParticipant = fixedRandomSource(0)
+			}
 
 			combinedMonthlyExpenses := CombinedMonthlyExpenses{
-				SharedMonthlyExpenses:     createFakeMonthlyExpenses(sharedExpenseAmounts),
-				IndividualMonthlyExpenses: createFakeMonthlyExpenses(individualExpenseAmounts),
+				SharedMonthlyExpenses: createFakeMonthlyExpenses(sharedExpenseAmounts),
+				IndividualMonthlyExpenses: []*MonthlyExpenses{
+					createFakeMonthlyExpenses(individualExpenseAmounts),
+					createFakeMonthlyExpenses(individualExpenseAmounts),
+				},
+				RoundingStrategy: NewAlternatingRoundingStrategy(roundUpParticipant),
 			}
-			combinedMonthlyExpenses.SplitSharedMonthlyExpenses()
+			assert.NoError(t, combinedMonthlyExpenses.SplitSharedMonthlyExpenses())
 
 			for _, categoryName := range categoryNames {
 				sharedMonthlyExpense := combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses[categoryName]
-				individualMonthlyExpense := combinedMonthlyExpenses.IndividualMonthlyExpenses.Expenses[categoryName]
+				individualMonthlyExpense := combinedMonthlyExpenses.IndividualMonthlyExpenses[0].Expenses[categoryName]
+				otherIndividualMonthlyExpense := combinedMonthlyExpenses.IndividualMonthlyExpenses[1].Expenses[categoryName]
 
 				expectedIndividualExpenseAmount := decimal.NewFromFloat(testCase.expectedIndividualExpenseAmounts[categoryName])
 				actualIndividualExpenseAmount := individualMonthlyExpense.Amount
@@ -118,11 +117,184 @@ func TestSplitSharedMonthlyExpenses(t *testing.T) {
 				assert.True(t, expectedIndividualExpenseAmount.Equal(actualIndividualExpenseAmount),
 					fmt.Sprintf("Expected individual share for category '%s' to be %s (half of shared expense of %s), but got %s",
 						categoryName, expectedIndividualExpenseAmount.String(), sharedMonthlyExpense.Amount.String(), actualIndividualExpenseAmount.String()))
+
+				assert.True(t, sharedMonthlyExpense.Amount.Equal(actualIndividualExpenseAmount.Add(otherIndividualMonthlyExpense.Amount)),
+					fmt.Sprintf("Expected individual shares for category '%s' to sum to the shared expense of %s, but got %s and %s",
+						categoryName, sharedMonthlyExpense.Amount.String(), actualIndividualExpenseAmount.String(), otherIndividualMonthlyExpense.Amount.String()))
 			}
 		})
 	}
 }
 
+func TestSplitSharedMonthlyExpensesAmongParticipants(t *testing.T) {
+	sharedExpenseAmounts := map[string]float64{
+		"Condominium":           245.75,
+		"Electricity":           130.52,
+		"TV / Internet / Phone": 85.90,
+		"Water":                 60.25,
+	}
+
+	testCases := map[string]struct {
+		roundUpParticipant               fixedRandomSource
+		expectedIndividualExpenseAmounts []map[string]float64
+	}{
+		"three participants - first participant rounds up first": {
+			roundUpParticipant: 0,
+			expectedIndividualExpenseAmounts: []map[string]float64{
+				{"Condominium": 81.92, "Electricity": 43.51, "TV / Internet / Phone": 28.63, "Water": 20.08},
+				{"Condominium": 81.92, "Electricity": 43.50, "TV / Internet / Phone": 28.64, "Water": 20.08},
+				{"Condominium": 81.91, "Electricity": 43.51, "TV / Internet / Phone": 28.63, "Water": 20.09},
+			},
+		},
+		"three participants - third participant rounds up first": {
+			roundUpParticipant: 2,
+			expectedIndividualExpenseAmounts: []map[string]float64{
+				{"Condominium": 81.92, "Electricity": 43.50, "TV / Internet / Phone": 28.64, "Water": 20.08},
+				{"Condominium": 81.91, "Electricity": 43.51, "TV / Internet / Phone": 28.63, "Water": 20.09},
+				{"Condominium": 81.92, "Electricity": 43.51, "TV / Internet / Phone": 28.63, "Water": 20.08},
+			},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			combinedMonthlyExpenses := CombinedMonthlyExpenses{
+				SharedMonthlyExpenses: createFakeMonthlyExpenses(sharedExpenseAmounts),
+				RoundingStrategy:      NewAlternatingRoundingStrategy(testCase.roundUpParticipant),
+			}
+			for range testCase.expectedIndividualExpenseAmounts {
+				combinedMonthlyExpenses.IndividualMonthlyExpenses = append(combinedMonthlyExpenses.IndividualMonthlyExpenses, createFakeMonthlyExpenses(nil))
+			}
+			assert.NoError(t, combinedMonthlyExpenses.SplitSharedMonthlyExpenses())
+
+			for _, categoryName := range categoryNames {
+				sharedMonthlyExpense := combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses[categoryName]
+				totalIndividualExpenseAmount := decimal.Zero
+
+				for participantIndex, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+					expectedIndividualExpenseAmount := decimal.NewFromFloat(testCase.expectedIndividualExpenseAmounts[participantIndex][categoryName])
+					actualIndividualExpenseAmount := individualMonthlyExpenses.Expenses[categoryName].Amount
+					totalIndividualExpenseAmount = totalIndividualExpenseAmount.Add(actualIndividualExpenseAmount)
+
+					assert.True(t, expectedIndividualExpenseAmount.Equal(actualIndividualExpenseAmount),
+						fmt.Sprintf("Expected individual share of participant %d for category '%s' to be %s, but got %s",
+							participantIndex, categoryName, expectedIndividualExpenseAmount.String(), actualIndividualExpenseAmount.String()))
+				}
+
+				assert.True(t, sharedMonthlyExpense.Amount.Equal(totalIndividualExpenseAmount),
+					fmt.Sprintf("Expected individual shares for category '%s' to sum to the shared expense of %s, but got %s",
+						categoryName, sharedMonthlyExpense.Amount.String(), totalIndividualExpenseAmount.String()))
+			}
+		})
+	}
+}
+
+func TestSplitSharedMonthlyExpensesWithSplitRules(t *testing.T) {
+	sharedExpenseAmounts := map[string]float64{
+		"Condominium":           245.75,
+		"Electricity":           130.52,
+		"TV / Internet / Phone": 85.90,
+		"Water":                 60.25,
+	}
+
+	testCases := map[string]struct {
+		splitRule                        *SplitRule
+		expectedIndividualExpenseAmounts [2]map[string]float64
+		expectedError                    bool
+	}{
+		"percentages split": {
+			splitRule: &SplitRule{
+				Rule:    SplitRulePercentages,
+				Weights: map[string]decimal.Decimal{"Magui": decimal.NewFromInt(60), "Jão": decimal.NewFromInt(40)},
+			},
+			expectedIndividualExpenseAmounts: [2]map[string]float64{
+				{"Condominium": 147.45, "Electricity": 78.32, "TV / Internet / Phone": 51.54, "Water": 36.15},
+				{"Condominium": 98.30, "Electricity": 52.20, "TV / Internet / Phone": 34.36, "Water": 24.10},
+			},
+		},
+		"fixed amounts split": {
+			splitRule: &SplitRule{
+				Rule:    SplitRuleFixed,
+				Amounts: map[string]decimal.Decimal{"Jão": decimal.NewFromInt(25)},
+			},
+			expectedIndividualExpenseAmounts: [2]map[string]float64{
+				{"Condominium": 220.75, "Electricity": 105.52, "TV / Internet / Phone": 60.90, "Water": 35.25},
+				{"Condominium": 25.00, "Electricity": 25.00, "TV / Internet / Phone": 25.00, "Water": 25.00},
+			},
+		},
+		"income split": {
+			splitRule: &SplitRule{
+				Rule:    SplitRuleIncome,
+				Weights: map[string]decimal.Decimal{"Magui": decimal.NewFromInt(2000), "Jão": decimal.NewFromInt(1000)},
+			},
+			expectedIndividualExpenseAmounts: [2]map[string]float64{
+				{"Condominium": 163.84, "Electricity": 87.01, "TV / Internet / Phone": 57.27, "Water": 40.16},
+				{"Condominium": 81.91, "Electricity": 43.51, "TV / Internet / Phone": 28.63, "Water": 20.09},
+			},
+		},
+		"fixed amounts exceeding the shared amount": {
+			splitRule: &SplitRule{
+				Rule:    SplitRuleFixed,
+				Amounts: map[string]decimal.Decimal{"Jão": decimal.NewFromInt(100)},
+			},
+			expectedError: true,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			combinedMonthlyExpenses := CombinedMonthlyExpenses{
+				SharedMonthlyExpenses: createFakeMonthlyExpenses(sharedExpenseAmounts),
+				IndividualMonthlyExpenses: []*MonthlyExpenses{
+					createFakeMonthlyExpenses(nil),
+					createFakeMonthlyExpenses(nil),
+				},
+				RoundingStrategy: NewAlternatingRoundingStrategy(fixedRandomSource(0)),
+			}
+			combinedMonthlyExpenses.IndividualMonthlyExpenses[0].ParticipantName = "Magui"
+			combinedMonthlyExpenses.IndividualMonthlyExpenses[1].ParticipantName = "Jão"
+
+			for _, sharedMonthlyExpense := range combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses {
+				sharedMonthlyExpense.SplitRule = testCase.splitRule
+			}
+
+			err := combinedMonthlyExpenses.SplitSharedMonthlyExpenses()
+
+			if testCase.expectedError {
+				assert.Error(t, err, "Expected split to fail")
+				return
+			}
+			assert.NoError(t, err)
+
+			for _, categoryName := range categoryNames {
+				sharedMonthlyExpense := combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses[categoryName]
+				totalIndividualExpenseAmount := decimal.Zero
+
+				for participantIndex, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+					expectedIndividualExpenseAmount := decimal.NewFromFloat(testCase.expectedIndividualExpenseAmounts[participantIndex][categoryName])
+					actualIndividualExpenseAmount := individualMonthlyExpenses.Expenses[categoryName].Amount
+					totalIndividualExpenseAmount = totalIndividualExpenseAmount.Add(actualIndividualExpenseAmount)
+
+					assert.True(t, expectedIndividualExpenseAmount.Equal(actualIndividualExpenseAmount),
+						fmt.Sprintf("Expected individual share of %s for category '%s' to be %s, but got %s",
+							individualMonthlyExpenses.ParticipantName, categoryName, expectedIndividualExpenseAmount.String(), actualIndividualExpenseAmount.String()))
+				}
+
+				assert.True(t, sharedMonthlyExpense.Amount.Equal(totalIndividualExpenseAmount),
+					fmt.Sprintf("Expected individual shares for category '%s' to sum to the shared expense of %s, but got %s",
+						categoryName, sharedMonthlyExpense.Amount.String(), totalIndividualExpenseAmount.String()))
+			}
+		})
+	}
+}
+
+// fixedRandomSource is a source of randomness that always picks the same participant
+type fixedRandomSource int
+
+func (source fixedRandomSource) Intn(n int) int {
+	return int(source) % n
+}
+
 func createFakeMonthlyExpense(expenseAmount float64) *MonthlyExpense {
 	monthlyExpense := &MonthlyExpense{}
 	gofakeit.Struct(monthlyExpense)
@@ -0,0 +1,333 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Default YNAB OAuth endpoints, used unless others are declared in OAuthConfig
+const (
+	DefaultOAuthAuthorizeURL string = "https://app.ynab.com/oauth/authorize"
+	DefaultOAuthTokenURL     string = "https://app.ynab.com/oauth/token"
+)
+
+// OAuthTokenFileName is the name of the file, in the application directory, holding the OAuth tokens of the authorized YNAB login
+// The file is only readable and writable by its owner
+const OAuthTokenFileName string = "oauth_token.json"
+
+// OAuthCallbackPath is the path of the local loopback listener YNAB redirects to once the YNAB login is authorized
+const OAuthCallbackPath string = "/oauth/callback"
+
+// AccessTokenSourceOAuth is the source of an access token obtained by authorizing a YNAB login with OAuth
+const AccessTokenSourceOAuth string = "oauth"
+
+// OAuthAuthorizationTimeout is how long the YNAB login has to be authorized before the authorization code flow is abandoned
+const OAuthAuthorizationTimeout time.Duration = 5 * time.Minute
+
+// OAuthRequestTimeout is how long a request to the OAuth token endpoint may take, so that a stalled endpoint never blocks the requests waiting for the access token
+const OAuthRequestTimeout time.Duration = 30 * time.Second
+
+// oauthTokenExpiryMargin is how long before their expiry OAuth access tokens are refreshed, so that they do not expire mid-request
+const oauthTokenExpiryMargin time.Duration = time.Minute
+
+// TokenSource represents where the APIClient gets the access token of each request from, with the context of the request
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenSource is a TokenSource always returning the same access token, e.g. a YNAB Personal Access Token
+type StaticTokenSource string
+
+// Token returns the access token
+func (tokenSource StaticTokenSource) Token(_ context.Context) (string, error) {
+	return string(tokenSource), nil
+}
+
+// OAuthToken represents the tokens obtained by authorizing a YNAB login, as persisted in the OAuth token file
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// OAuthClient represents a YNAB OAuth application, authorizing YNAB logins with the authorization code flow and refreshing their tokens
+type OAuthClient struct {
+	Config OAuthConfig
+	Client *resty.Client
+}
+
+// NewOAuthClient creates a new OAuthClient instance, defaulting to YNAB's OAuth endpoints
+// There is no default redirect address, as YNAB only redirects to the redirect URI registered with the YNAB OAuth application
+func NewOAuthClient(config OAuthConfig) *OAuthClient {
+	if config.AuthorizeURL == "" {
+		config.AuthorizeURL = DefaultOAuthAuthorizeURL
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = DefaultOAuthTokenURL
+	}
+
+	return &OAuthClient{Config: config, Client: resty.New().SetTimeout(OAuthRequestTimeout)}
+}
+
+// GetAuthorizationURL returns the URL where the YNAB login is authorized, which redirects back to the given redirect URI
+func (oauthClient *OAuthClient) GetAuthorizationURL(redirectURI string, state string) string {
+	query := url.Values{
+		"client_id":     {oauthClient.Config.ClientId},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"state":         {state},
+	}
+
+	return oauthClient.Config.AuthorizeURL + "?" + query.Encode()
+}
+
+// Authorize runs the authorization code flow: it listens on the redirect address, opens the authorization URL and exchanges the code YNAB redirects back with for tokens
+// The flow is abandoned when the context is done, e.g. if the YNAB login is never authorized
+func (oauthClient *OAuthClient) Authorize(ctx context.Context, openURL func(authorizationURL string) error) (OAuthToken, error) {
+	if oauthClient.Config.RedirectAddress == "" {
+		return OAuthToken{}, errors.New("no redirect address is declared for the YNAB OAuth application")
+	}
+
+	listener, err := net.Listen("tcp", oauthClient.Config.RedirectAddress)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("listening for the OAuth redirect: %w", err)
+	}
+	defer listener.Close()
+
+	// The redirect URI must match the one registered with the YNAB OAuth application, as declared, rather than the address the listener resolved to
+	redirectURI := fmt.Sprintf("http://%s%s", oauthClient.Config.RedirectAddress, OAuthCallbackPath)
+
+	state, err := generateOAuthState()
+	if err != nil {
+		return OAuthToken{}, err
+	}
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	callbackResults := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OAuthCallbackPath, func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+
+		// Any local process may request the callback, so only the redirect of this authorization request finishes the flow
+		if query.Get("state") != state {
+			http.Error(writer, "the OAuth redirect does not match the authorization request", http.StatusBadRequest)
+			return
+		}
+
+		var result callbackResult
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("the YNAB login was not authorized: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("code") == "":
+			result.err = errors.New("the OAuth redirect has no authorization code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(writer, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(writer, "The YNAB login is authorized, you may close this window.")
+		}
+
+		select {
+		case callbackResults <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err = openURL(oauthClient.GetAuthorizationURL(redirectURI, state)); err != nil {
+		return OAuthToken{}, fmt.Errorf("opening the authorization URL: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return OAuthToken{}, fmt.Errorf("waiting for the OAuth redirect: %w", ctx.Err())
+	case result := <-callbackResults:
+		if result.err != nil {
+			return OAuthToken{}, result.err
+		}
+		return oauthClient.Exchange(ctx, result.code, redirectURI)
+	}
+}
+
+// Exchange exchanges an authorization code for tokens
+// POST https://app.ynab.com/oauth/token
+func (oauthClient *OAuthClient) Exchange(ctx context.Context, code string, redirectURI string) (OAuthToken, error) {
+	return oauthClient.requestToken(ctx, map[string]string{
+		"grant_type":   "authorization_code",
+		"code":         code,
+		"redirect_uri": redirectURI,
+	})
+}
+
+// Refresh exchanges a refresh token for new tokens
+// POST https://app.ynab.com/oauth/token
+func (oauthClient *OAuthClient) Refresh(ctx context.Context, refreshToken string) (OAuthToken, error) {
+	return oauthClient.requestToken(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+	})
+}
+
+// requestToken requests tokens from the OAuth token endpoint with the given grant
+func (oauthClient *OAuthClient) requestToken(ctx context.Context, grant map[string]string) (OAuthToken, error) {
+	tokenResponse := struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}{}
+
+	grant["client_id"] = oauthClient.Config.ClientId
+	grant["client_secret"] = oauthClient.Config.ClientSecret
+
+	response, err := oauthClient.Client.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/json").
+		SetFormData(grant).
+		SetResult(&tokenResponse).
+		Post(oauthClient.Config.TokenURL)
+
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	if response.IsError() {
+		return OAuthToken{}, fmt.Errorf("requesting OAuth token: %s", response.Body())
+	}
+	if tokenResponse.AccessToken == "" {
+		return OAuthToken{}, errors.New("requesting OAuth token: the response has no access token")
+	}
+
+	return OAuthToken{
+		AccessToken:  tokenResponse.AccessToken,
+		RefreshToken: tokenResponse.RefreshToken,
+		TokenType:    tokenResponse.TokenType,
+		ExpiresAt:    time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// generateOAuthState generates the random state binding the OAuth redirect to the authorization request
+func generateOAuthState() (string, error) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(state), nil
+}
+
+// OAuthTokenSource is a TokenSource returning the access token of an authorized YNAB login, refreshing and persisting it before it expires
+type OAuthTokenSource struct {
+	OAuthClient *OAuthClient
+	Path        string
+	token       OAuthToken
+	mutex       sync.Mutex
+}
+
+// NewOAuthTokenSource creates a new OAuthTokenSource instance from the tokens persisted at the given location
+func NewOAuthTokenSource(oauthClient *OAuthClient, tokenPath string) (*OAuthTokenSource, error) {
+	token, err := LoadOAuthToken(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthTokenSource{OAuthClient: oauthClient, Path: tokenPath, token: token}, nil
+}
+
+// Token returns the access token, refreshing it first if it is about to expire
+// Refreshing it is abandoned when the context of the request is done, or after OAuthRequestTimeout
+func (tokenSource *OAuthTokenSource) Token(ctx context.Context) (string, error) {
+	tokenSource.mutex.Lock()
+	defer tokenSource.mutex.Unlock()
+
+	if time.Now().Add(oauthTokenExpiryMargin).Before(tokenSource.token.ExpiresAt) {
+		return tokenSource.token.AccessToken, nil
+	}
+
+	if tokenSource.token.RefreshToken == "" {
+		return "", errors.New("the OAuth access token expired and cannot be refreshed, authorize the YNAB login again")
+	}
+
+	token, err := tokenSource.OAuthClient.Refresh(ctx, tokenSource.token.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+	// The refresh token stays valid when no new one is issued with the refreshed access token
+	if token.RefreshToken == "" {
+		token.RefreshToken = tokenSource.token.RefreshToken
+	}
+
+	if err = SaveOAuthToken(tokenSource.Path, token); err != nil {
+		return "", err
+	}
+	tokenSource.token = token
+
+	return token.AccessToken, nil
+}
+
+// OAuthTokenPath returns the location of the OAuth token file
+func OAuthTokenPath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, OAuthTokenFileName), nil
+}
+
+// LoadOAuthToken reads the OAuth tokens persisted at the given location
+func LoadOAuthToken(tokenPath string) (OAuthToken, error) {
+	var token OAuthToken
+
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return token, err
+	}
+
+	if err = json.Unmarshal(data, &token); err != nil {
+		return token, fmt.Errorf("decoding OAuth token %s: %w", tokenPath, err)
+	}
+
+	return token, nil
+}
+
+// SaveOAuthToken persists the OAuth tokens at the given location, only readable and writable by its owner
+func SaveOAuthToken(tokenPath string, token OAuthToken) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(tokenPath), 0700); err != nil {
+		return fmt.Errorf("creating OAuth token directory: %w", err)
+	}
+
+	if err = writeFileAtomically(tokenPath, data, 0600); err != nil {
+		return fmt.Errorf("writing OAuth token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// standInAuthorizationServer is a local stand-in for YNAB's OAuth endpoints, authorizing every login unless told to deny them
+type standInAuthorizationServer struct {
+	*httptest.Server
+	deny             bool
+	omitRefreshToken bool
+	tokenRequests    []url.Values
+}
+
+func newStandInAuthorizationServer(t *testing.T) *standInAuthorizationServer {
+	authorizationServer := &standInAuthorizationServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/authorize", func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		assert.Equal(t, "client", query.Get("client_id"))
+		assert.Equal(t, "code", query.Get("response_type"))
+
+		redirect := url.Values{"state": {query.Get("state")}}
+		if authorizationServer.deny {
+			redirect.Set("error", "access_denied")
+		} else {
+			redirect.Set("code", "authorization-code")
+		}
+
+		http.Redirect(writer, request, query.Get("redirect_uri")+"?"+redirect.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/oauth/token", func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, request.ParseForm())
+		authorizationServer.tokenRequests = append(authorizationServer.tokenRequests, request.PostForm)
+
+		if request.PostForm.Get("client_secret") != "secret" {
+			http.Error(writer, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		refreshToken := `"refresh-token"`
+		if authorizationServer.omitRefreshToken {
+			refreshToken = `null`
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"access_token": "access-token-` + request.PostForm.Get("grant_type") + `", "refresh_token": ` + refreshToken + `, "token_type": "Bearer", "expires_in": 7200}`))
+	})
+
+	authorizationServer.Server = httptest.NewServer(mux)
+	t.Cleanup(authorizationServer.Close)
+
+	return authorizationServer
+}
+
+func (authorizationServer *standInAuthorizationServer) oauthConfig(t *testing.T) OAuthConfig {
+	return OAuthConfig{
+		ClientId:        "client",
+		ClientSecret:    "secret",
+		AuthorizeURL:    authorizationServer.URL + "/oauth/authorize",
+		TokenURL:        authorizationServer.URL + "/oauth/token",
+		RedirectAddress: getFreeLoopbackAddress(t),
+	}
+}
+
+// getFreeLoopbackAddress returns a localhost address with a port no listener is using, standing in for the fixed port of a registered redirect URI
+func getFreeLoopbackAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return fmt.Sprintf("localhost:%d", listener.Addr().(*net.TCPAddr).Port)
+}
+
+// followAuthorizationURL stands in for the browser, following the authorization URL and the redirect back to the loopback listener
+func followAuthorizationURL(authorizationURL string) error {
+	go func() {
+		response, err := http.Get(authorizationURL)
+		if err == nil {
+			response.Body.Close()
+		}
+	}()
+
+	return nil
+}
+
+func TestAuthorize(t *testing.T) {
+	testCases := map[string]struct {
+		deny                bool
+		expectedAccessToken string
+		expectedError       string
+	}{
+		"login authorized": {
+			expectedAccessToken: "access-token-authorization_code",
+		},
+		"login denied": {
+			deny:          true,
+			expectedError: "the YNAB login was not authorized: access_denied",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			authorizationServer := newStandInAuthorizationServer(t)
+			authorizationServer.deny = testCase.deny
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			oauthConfig := authorizationServer.oauthConfig(t)
+			token, err := NewOAuthClient(oauthConfig).Authorize(ctx, followAuthorizationURL)
+
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				assert.Empty(t, authorizationServer.tokenRequests, "Expected no tokens to be requested for a denied login")
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedAccessToken, token.AccessToken)
+			assert.Equal(t, "refresh-token", token.RefreshToken)
+			assert.WithinDuration(t, time.Now().Add(2*time.Hour), token.ExpiresAt, time.Minute)
+
+			assert.Len(t, authorizationServer.tokenRequests, 1)
+			assert.Equal(t, "authorization-code", authorizationServer.tokenRequests[0].Get("code"))
+			assert.Equal(t, "http://"+oauthConfig.RedirectAddress+OAuthCallbackPath, authorizationServer.tokenRequests[0].Get("redirect_uri"))
+		})
+	}
+}
+
+func TestAuthorizeIgnoresRedirectsOfOtherRequests(t *testing.T) {
+	authorizationServer := newStandInAuthorizationServer(t)
+	oauthConfig := authorizationServer.oauthConfig(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := NewOAuthClient(oauthConfig).Authorize(ctx, func(authorizationURL string) error {
+		go func() {
+			response, err := http.Get("http://" + oauthConfig.RedirectAddress + OAuthCallbackPath + "?state=forged&code=forged-code")
+			if assert.NoError(t, err) {
+				response.Body.Close()
+				assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+			}
+
+			_ = followAuthorizationURL(authorizationURL)
+		}()
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token-authorization_code", token.AccessToken)
+	assert.Len(t, authorizationServer.tokenRequests, 1)
+	assert.Equal(t, "authorization-code", authorizationServer.tokenRequests[0].Get("code"))
+}
+
+func TestAuthorizeTimesOut(t *testing.T) {
+	authorizationServer := newStandInAuthorizationServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := NewOAuthClient(authorizationServer.oauthConfig(t)).Authorize(ctx, func(string) error { return nil })
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAuthorizeRequiresRedirectAddress(t *testing.T) {
+	oauthConfig := newStandInAuthorizationServer(t).oauthConfig(t)
+	oauthConfig.RedirectAddress = ""
+
+	_, err := NewOAuthClient(oauthConfig).Authorize(context.Background(), func(string) error {
+		t.Error("Expected the authorization URL not to be opened without a redirect address")
+		return nil
+	})
+
+	assert.ErrorContains(t, err, "no redirect address")
+}
+
+func TestOAuthTokenSourceRefreshesExpiredTokens(t *testing.T) {
+	authorizationServer := newStandInAuthorizationServer(t)
+
+	tokenPath := filepath.Join(t.TempDir(), OAuthTokenFileName)
+	assert.NoError(t, SaveOAuthToken(tokenPath, OAuthToken{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "refresh-token",
+		ExpiresAt:    time.Now().Add(-time.Hour),
+	}))
+
+	tokenSource, err := NewOAuthTokenSource(NewOAuthClient(authorizationServer.oauthConfig(t)), tokenPath)
+	assert.NoError(t, err)
+
+	var authorizationHeaders []string
+	ynabServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		authorizationHeaders = append(authorizationHeaders, request.Header.Get("Authorization"))
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"data": {"user": {"id": "user"}}}`))
+	}))
+	defer ynabServer.Close()
+
+	client := APIClient{Client: resty.New()}
+	assert.NoError(t, client.Configure(tokenSource, YNABConfig{BaseURL: ynabServer.URL}))
+
+	for range [2]struct{}{} {
+		user, err := client.GetUser(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "user", user.Id)
+	}
+
+	assert.Equal(t, []string{"Bearer access-token-refresh_token", "Bearer access-token-refresh_token"}, authorizationHeaders)
+	assert.Len(t, authorizationServer.tokenRequests, 1, "Expected the access token to be refreshed only once")
+	assert.Equal(t, "refresh_token", authorizationServer.tokenRequests[0].Get("grant_type"))
+
+	persistedToken, err := LoadOAuthToken(tokenPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token-refresh_token", persistedToken.AccessToken, "Expected the refreshed access token to be persisted")
+}
+
+func TestOAuthTokenSourceKeepsRefreshTokenNotReissued(t *testing.T) {
+	authorizationServer := newStandInAuthorizationServer(t)
+	authorizationServer.omitRefreshToken = true
+
+	tokenPath := filepath.Join(t.TempDir(), OAuthTokenFileName)
+	assert.NoError(t, SaveOAuthToken(tokenPath, OAuthToken{AccessToken: "expired-access-token", RefreshToken: "original-refresh-token", ExpiresAt: time.Now().Add(-time.Hour)}))
+
+	tokenSource, err := NewOAuthTokenSource(NewOAuthClient(authorizationServer.oauthConfig(t)), tokenPath)
+	assert.NoError(t, err)
+
+	accessToken, err := tokenSource.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token-refresh_token", accessToken)
+
+	persistedToken, err := LoadOAuthToken(tokenPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "original-refresh-token", persistedToken.RefreshToken, "Expected the refresh token to be kept when no new one is issued")
+}
+
+func TestOAuthTokenSourceGivesUpOnStalledRefresh(t *testing.T) {
+	released := make(chan struct{})
+	stalledTokenServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-released
+	}))
+	defer stalledTokenServer.Close()
+	defer close(released)
+
+	tokenPath := filepath.Join(t.TempDir(), OAuthTokenFileName)
+	assert.NoError(t, SaveOAuthToken(tokenPath, OAuthToken{AccessToken: "expired-access-token", RefreshToken: "refresh-token", ExpiresAt: time.Now().Add(-time.Hour)}))
+
+	tokenSource, err := NewOAuthTokenSource(NewOAuthClient(OAuthConfig{ClientId: "client", ClientSecret: "secret", TokenURL: stalledTokenServer.URL}), tokenPath)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	startedAt := time.Now()
+	_, err = tokenSource.Token(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(startedAt), OAuthRequestTimeout, "Expected refreshing to be abandoned with the request")
+}
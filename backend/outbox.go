@@ -0,0 +1,278 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// OutboxFileName is the name of the file, in the application directory, where the imports waiting for YNAB to be reachable are persisted
+const OutboxFileName string = "outbox.json"
+
+// OutboxRetryInterval is how often the queued imports are submitted again while YNAB cannot be reached
+const OutboxRetryInterval time.Duration = time.Minute
+
+// Statuses of an import in the outbox
+const (
+	QueuedImportStatusQueued             string = "queued"
+	QueuedImportStatusFailed             string = "failed"
+	QueuedImportStatusPossibleDuplicates string = "possible_duplicates"
+)
+
+// ErrNothingToResolve is returned when resolving the possible duplicates of a month that has no import waiting for them to be resolved in the outbox
+var ErrNothingToResolve = errors.New("no queued import is waiting for its possible duplicates to be resolved")
+
+// QueuedImport represents an import of the monthly expenses of a month that could not reach YNAB, kept in the outbox to be submitted once YNAB is reachable again
+// The rounding of the individual shares is recorded in the rounding ledger once the import is submitted
+// An import YNAB rejects is kept as failed, with its last import result, until it is queued again or discarded
+// An import queued without a duplicate resolution whose expenses appear to be already entered in YNAB when submitting it waits for them to be resolved, with the possible duplicates in its last import result
+type QueuedImport struct {
+	Month               string                `json:"month"`
+	Plan                ImportPlan            `json:"plan"`
+	DuplicateResolution string                `json:"duplicate_resolution"`
+	RoundingEntries     []RoundingLedgerEntry `json:"rounding_entries"`
+	Status              string                `json:"status"`
+	QueuedAt            time.Time             `json:"queued_at"`
+	Attempts            int                   `json:"attempts"`
+	LastAttemptAt       *time.Time            `json:"last_attempt_at"`
+	Error               string                `json:"error"`
+	Result              *ImportResult         `json:"result"`
+}
+
+// Outbox represents the persisted queue of imports waiting for YNAB to be reachable, holding at most one import per month
+type Outbox struct {
+	Path    string         `json:"-"`
+	Imports []QueuedImport `json:"imports"`
+	mutex   sync.Mutex
+}
+
+// OutboxPath returns the location of the outbox file
+func OutboxPath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, OutboxFileName), nil
+}
+
+// LoadOutbox reads the outbox persisted at the given location, returning an empty outbox if it does not exist yet
+func LoadOutbox(outboxPath string) (*Outbox, error) {
+	outbox := &Outbox{Path: outboxPath, Imports: []QueuedImport{}}
+
+	data, err := os.ReadFile(outboxPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return outbox, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading outbox: %w", err)
+	}
+
+	if err = json.Unmarshal(data, outbox); err != nil {
+		return nil, fmt.Errorf("decoding outbox %s: %w", outboxPath, err)
+	}
+
+	return outbox, nil
+}
+
+// Enqueue adds an import to the outbox and persists it, replacing any import of the same month, as the latest amounts are the ones to import
+func (outbox *Outbox) Enqueue(queuedImport QueuedImport) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	queuedImport.Status = QueuedImportStatusQueued
+
+	outbox.Imports = append(outbox.withoutMonth(queuedImport.Month), queuedImport)
+
+	return outbox.save()
+}
+
+// Discard removes the import of a month from the outbox, e.g. once YNAB rejected it and it was imported otherwise
+func (outbox *Outbox) Discard(month string) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	outbox.Imports = outbox.withoutMonth(month)
+
+	return outbox.save()
+}
+
+// Resolve queues the import of a month waiting for its possible duplicates to be resolved again, to be submitted with the given duplicate resolution, and persists the outbox
+func (outbox *Outbox) Resolve(month string, duplicateResolution string) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	for index := range outbox.Imports {
+		queuedImport := &outbox.Imports[index]
+		if queuedImport.Month != month || queuedImport.Status != QueuedImportStatusPossibleDuplicates {
+			continue
+		}
+
+		queuedImport.Status = QueuedImportStatusQueued
+		queuedImport.DuplicateResolution = duplicateResolution
+
+		return outbox.save()
+	}
+
+	return fmt.Errorf("%w for %s", ErrNothingToResolve, month)
+}
+
+// withoutMonth returns the imports in the outbox other than the import of the given month
+func (outbox *Outbox) withoutMonth(month string) []QueuedImport {
+	imports := make([]QueuedImport, 0, len(outbox.Imports))
+
+	for _, queuedImport := range outbox.Imports {
+		if queuedImport.Month != month {
+			imports = append(imports, queuedImport)
+		}
+	}
+
+	return imports
+}
+
+// Submit executes the import plan of every queued import, in the order they were queued, and persists the outbox
+// Submitted imports are removed from the outbox and returned, imports that still cannot reach YNAB stay queued, and imports YNAB rejects otherwise are marked as failed
+// Imports whose expenses appear to be already entered in YNAB, e.g. by hand while YNAB could not be reached, wait for the possible duplicates to be resolved, whose amounts are formatted according to the currency format of their YNAB budget
+// Canceling the context stops submitting, leaving the import being submitted and the ones after it queued
+// Submitting an import again is safe, as the transactions YNAB already has are recognized by their import ids and not created twice
+// Once the transactions of a submission are all rolled back, the import moves on to the next attempt of its import ids, as YNAB would never create them again
+// A submission whose rollback left transactions behind is marked as failed instead, as submitting it again under the next attempt would duplicate them
+func (outbox *Outbox) Submit(ctx context.Context, budgetService BudgetService, currencyFormats map[string]CurrencyFormat) ([]QueuedImport, error) {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	var submittedImports []QueuedImport
+	remainingImports := make([]QueuedImport, 0, len(outbox.Imports))
+
+	for _, queuedImport := range outbox.Imports {
+		if queuedImport.Status != QueuedImportStatusQueued || ctx.Err() != nil {
+			remainingImports = append(remainingImports, queuedImport)
+			continue
+		}
+
+		attemptedAt := time.Now()
+		importResult, err := queuedImport.submit(ctx, budgetService)
+
+		queuedImport.Attempts++
+		queuedImport.LastAttemptAt = &attemptedAt
+		queuedImport.Result = &importResult
+
+		if err == nil {
+			submittedImports = append(submittedImports, queuedImport)
+			continue
+		}
+
+		if errors.Is(err, ErrPossibleDuplicates) {
+			formatPossibleDuplicateAmounts(importResult.PossibleDuplicates, currencyFormats)
+			queuedImport.Status = QueuedImportStatusPossibleDuplicates
+			queuedImport.Error = importResult.Error
+			remainingImports = append(remainingImports, queuedImport)
+			continue
+		}
+
+		switch {
+		case importResult.isFullyRolledBack():
+			queuedImport.Plan = queuedImport.Plan.WithAttempt(queuedImport.Plan.Attempt + 1)
+		case importResult.hasLeftoverTransactions():
+			importResult.Error = fmt.Sprintf("%s: %s", ErrRollbackIncomplete.Error(), importResult.Error)
+		}
+
+		// An import waiting for YNAB to be reachable, or for a valid access token, is submitted again later, as is one whose submission was canceled
+		// An import whose rollback left transactions behind is never submitted again by itself, as that would create them once more
+		queuedImport.Error = importResult.Error
+		if importResult.hasLeftoverTransactions() || (!isUnreachable(err) && !errors.Is(err, ErrUnauthorized) && !errors.Is(err, ErrCanceled)) {
+			queuedImport.Status = QueuedImportStatusFailed
+		}
+		remainingImports = append(remainingImports, queuedImport)
+	}
+
+	outbox.Imports = remainingImports
+
+	return submittedImports, outbox.save()
+}
+
+// submit executes the import plan of the queued import, checking for possible duplicates first unless it was resolved to proceed regardless
+// Without a duplicate resolution, nothing is imported if any possible duplicate is found, and ErrPossibleDuplicates is returned along with them
+func (queuedImport QueuedImport) submit(ctx context.Context, budgetService BudgetService) (ImportResult, error) {
+	importPlan := queuedImport.Plan
+
+	var possibleDuplicates []PossibleDuplicate
+	if queuedImport.DuplicateResolution != DuplicateResolutionProceed {
+		var err error
+		if possibleDuplicates, err = importPlan.FindPossibleDuplicates(ctx, budgetService); err != nil {
+			err = fmt.Errorf("finding possible duplicates: %w", err)
+			return ImportResult{Error: err.Error(), Canceled: errors.Is(err, context.Canceled), Budgets: []BudgetImportResult{}}, err
+		}
+	}
+
+	switch {
+	case queuedImport.DuplicateResolution == "" && len(possibleDuplicates) > 0:
+		return ImportResult{Error: ErrPossibleDuplicates.Error(), Budgets: []BudgetImportResult{}, PossibleDuplicates: possibleDuplicates}, ErrPossibleDuplicates
+	case queuedImport.DuplicateResolution == DuplicateResolutionSkip:
+		importPlan = importPlan.WithoutPossibleDuplicates(possibleDuplicates)
+	case queuedImport.DuplicateResolution == DuplicateResolutionReplace:
+		if problems := getReplaceProblems(possibleDuplicates); len(problems) > 0 {
+			err := fmt.Errorf("%w: %s", ErrReplaceRefused, strings.Join(problems, ", "))
+			return ImportResult{Error: err.Error(), Budgets: []BudgetImportResult{}}, err
+		}
+	}
+
+	importResult, err := importPlan.Execute(ctx, budgetService)
+	if err != nil {
+		return importResult, err
+	}
+
+	switch queuedImport.DuplicateResolution {
+	case DuplicateResolutionSkip:
+		importResult.skipPossibleDuplicates(possibleDuplicates)
+	case DuplicateResolutionReplace:
+		// The import already succeeded, so the existing transactions left behind are only reported in the results of their budgets
+		_ = importResult.replacePossibleDuplicates(context.WithoutCancel(ctx), budgetService, possibleDuplicates)
+	}
+
+	return importResult, nil
+}
+
+// GetImports returns every import in the outbox, in the order they were queued
+func (outbox *Outbox) GetImports() []QueuedImport {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	return slices.Clone(outbox.Imports)
+}
+
+// HasQueuedImports checks if any import in the outbox is waiting to be submitted
+func (outbox *Outbox) HasQueuedImports() bool {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	return slices.ContainsFunc(outbox.Imports, func(queuedImport QueuedImport) bool {
+		return queuedImport.Status == QueuedImportStatusQueued
+	})
+}
+
+// save persists the outbox
+func (outbox *Outbox) save() error {
+	data, err := json.MarshalIndent(outbox, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(outbox.Path), 0700); err != nil {
+		return fmt.Errorf("creating outbox directory: %w", err)
+	}
+
+	if err = writeFileAtomically(outbox.Path, data, 0600); err != nil {
+		return fmt.Errorf("writing outbox: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxSubmit(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode          int
+		unreachable         bool
+		expectedSubmitted   int
+		expectedStatus      string
+		expectedPostedPlans int
+	}{
+		"YNAB reachable again - import submitted": {
+			statusCode:          http.StatusCreated,
+			expectedSubmitted:   1,
+			expectedPostedPlans: 1,
+		},
+		"YNAB still unreachable - import stays queued": {
+			unreachable:    true,
+			expectedStatus: QueuedImportStatusQueued,
+		},
+		"YNAB failing - import stays queued": {
+			statusCode:          http.StatusServiceUnavailable,
+			expectedStatus:      QueuedImportStatusQueued,
+			expectedPostedPlans: 1,
+		},
+		"YNAB rejects the import - import failed": {
+			statusCode:          http.StatusBadRequest,
+			expectedStatus:      QueuedImportStatusFailed,
+			expectedPostedPlans: 1,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var postedPlans int
+
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "application/json")
+				if request.Method == http.MethodGet {
+					_, _ = writer.Write([]byte(`{"data": {"transactions": [], "server_knowledge": 1}}`))
+					return
+				}
+
+				postedPlans++
+				writer.WriteHeader(testCase.statusCode)
+				_, _ = writer.Write([]byte(`{"data": {"transaction_ids": ["created"], "duplicate_import_ids": []}}`))
+			}))
+			defer server.Close()
+			if testCase.unreachable {
+				server.Close()
+			}
+
+			outboxPath := filepath.Join(t.TempDir(), OutboxFileName)
+			outbox, err := LoadOutbox(outboxPath)
+			assert.NoError(t, err)
+
+			queuedImport := QueuedImport{
+				Month: "2024-02",
+				Plan: ImportPlan{Month: "2024-02", Budgets: []BudgetImportPlan{{
+					BudgetId: "shared",
+					Transactions: []PlannedTransaction{{
+						Description: "Water",
+						Transaction: SaveTransaction{ImportId: to.StringPtr(GetImportId("shared", "2024-02", "Water", ""))},
+					}},
+				}}},
+				QueuedAt: time.Now(),
+			}
+			assert.NoError(t, outbox.Enqueue(queuedImport))
+			assert.NoError(t, outbox.Enqueue(queuedImport), "Expected an import of the same month to replace the queued one")
+			assert.Len(t, outbox.GetImports(), 1)
+
+			submittedImports, err := outbox.Submit(context.Background(), &APIClient{Client: resty.New().SetBaseURL(server.URL)}, nil)
+			assert.NoError(t, err)
+			assert.Len(t, submittedImports, testCase.expectedSubmitted)
+			assert.Equal(t, testCase.expectedPostedPlans, postedPlans)
+
+			outbox, err = LoadOutbox(outboxPath)
+			assert.NoError(t, err)
+
+			if testCase.expectedSubmitted > 0 {
+				assert.Empty(t, outbox.GetImports(), "Expected the submitted import to leave the outbox")
+				return
+			}
+
+			queuedImports := outbox.GetImports()
+			assert.Len(t, queuedImports, 1)
+			assert.Equal(t, testCase.expectedStatus, queuedImports[0].Status)
+			assert.Equal(t, 1, queuedImports[0].Attempts)
+			assert.NotEmpty(t, queuedImports[0].Error)
+			assert.Equal(t, testCase.expectedStatus == QueuedImportStatusQueued, outbox.HasQueuedImports())
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+)
+
+// Payee represents a YNAB payee
+// This struct corresponds to the data structure defined in the YNAB API documentation
+type Payee struct {
+	Id                string `json:"id"`
+	Name              string `json:"name"`
+	TransferAccountId string `json:"transfer_account_id"`
+	Deleted           bool   `json:"deleted"`
+}
+
+// Payees represents a collection of YNAB payees
+type Payees []Payee
+
+// GetPayees fetches the YNAB payees of a YNAB budget changed since the given server knowledge, or every payee if it is 0, along with the current server knowledge
+// GET https://api.ynab.com/v1/budgets/{budget_id}/payees
+func (client *APIClient) GetPayees(ctx context.Context, budgetId string, lastKnowledgeOfServer int64) (Payees, int64, error) {
+	payeesResponse := struct {
+		Data struct {
+			Payees          Payees `json:"payees"`
+			ServerKnowledge int64  `json:"server_knowledge"`
+		} `json:"data"`
+	}{}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetQueryParams(getDeltaQueryParams(lastKnowledgeOfServer)).
+		SetResult(&payeesResponse).
+		Get(fmt.Sprintf("budgets/%s/payees", budgetId))
+
+	if err = client.ValidateResponse(response, err); err != nil {
+		return nil, 0, err
+	}
+
+	return payeesResponse.Data.Payees, payeesResponse.Data.ServerKnowledge, nil
+}
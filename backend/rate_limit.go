@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// YNAB API rate limit of every access token
+const (
+	YNABRateLimit         int           = 200
+	YNABRateLimitInterval time.Duration = time.Hour
+)
+
+// RateLimitStatus represents how many YNAB API requests may still be sent, out of the rate limit
+type RateLimitStatus struct {
+	Limit     int `json:"limit"`
+	Remaining int `json:"remaining"`
+}
+
+// TokenBucket limits the YNAB API requests sent on the client side, so that the YNAB API rate limit is never reached
+// The bucket holds up to Capacity tokens, each request takes one and the bucket refills completely over RefillInterval
+type TokenBucket struct {
+	Capacity       int
+	RefillInterval time.Duration
+	tokens         float64
+	updatedAt      time.Time
+	now            func() time.Time
+	mutex          sync.Mutex
+}
+
+// NewTokenBucket creates a full token bucket
+func NewTokenBucket(capacity int, refillInterval time.Duration) *TokenBucket {
+	return &TokenBucket{
+		Capacity:       capacity,
+		RefillInterval: refillInterval,
+		tokens:         float64(capacity),
+		updatedAt:      time.Now(),
+		now:            time.Now,
+	}
+}
+
+// Allow takes a token from the bucket if one is left, as required by resty.RateLimiter
+func (bucket *TokenBucket) Allow() bool {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill()
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
+
+// GetStatus returns how many requests may still be sent right now
+func (bucket *TokenBucket) GetStatus() RateLimitStatus {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill()
+
+	return RateLimitStatus{Limit: bucket.Capacity, Remaining: int(math.Floor(bucket.tokens))}
+}
+
+// GetWaitTime returns how long until the bucket holds a token again
+func (bucket *TokenBucket) GetWaitTime() time.Duration {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill()
+
+	if bucket.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - bucket.tokens) * float64(bucket.RefillInterval) / float64(bucket.Capacity))
+}
+
+// Sync takes the tokens YNAB reports as already used out of the bucket, e.g. by other applications sharing the access token
+// YNAB reports them in the X-Rate-Limit response header, formatted as "<used>/<limit>"
+func (bucket *TokenBucket) Sync(rateLimitHeader string) {
+	usedValue, limitValue, found := strings.Cut(rateLimitHeader, "/")
+	if !found {
+		return
+	}
+
+	used, err := strconv.Atoi(usedValue)
+	if err != nil {
+		return
+	}
+	limit, err := strconv.Atoi(limitValue)
+	if err != nil {
+		return
+	}
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill()
+
+	bucket.tokens = math.Max(0, math.Min(bucket.tokens, float64(limit-used)))
+}
+
+// refill adds the tokens accumulated since the bucket was last updated, up to its capacity
+func (bucket *TokenBucket) refill() {
+	now := bucket.now()
+
+	elapsed := now.Sub(bucket.updatedAt)
+	bucket.tokens = math.Min(float64(bucket.Capacity), bucket.tokens+float64(bucket.Capacity)*elapsed.Seconds()/bucket.RefillInterval.Seconds())
+	bucket.updatedAt = now
+}
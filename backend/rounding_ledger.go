@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// RoundingLedgerFileName is the name of the file, in the application directory, where the rounding ledger is persisted
+const RoundingLedgerFileName string = "rounding_ledger.json"
+
+// RoundingLedgerEntry represents the rounding of the individual share of a participant for a monthly expense category in a given month
+// The amount is positive when the rounding was in the participant's favour, i.e. when the participant pays less than their exact share
+type RoundingLedgerEntry struct {
+	Month           string          `json:"month"`
+	CategoryName    string          `json:"category_name"`
+	ParticipantName string          `json:"participant_name"`
+	ExactShare      decimal.Decimal `json:"exact_share"`
+	RoundedShare    decimal.Decimal `json:"rounded_share"`
+	Amount          decimal.Decimal `json:"amount"`
+	RecordedAt      time.Time       `json:"recorded_at"`
+}
+
+// RoundingLedger represents the persisted record of every rounding of the individual shares, used to keep the rounding fair across months
+type RoundingLedger struct {
+	Path    string                `json:"-"`
+	Entries []RoundingLedgerEntry `json:"entries"`
+	mutex   sync.Mutex
+}
+
+// RoundingLedgerPath returns the location of the rounding ledger file
+func RoundingLedgerPath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, RoundingLedgerFileName), nil
+}
+
+// LoadRoundingLedger reads the rounding ledger persisted at the given location, returning an empty ledger if it does not exist yet
+func LoadRoundingLedger(ledgerPath string) (*RoundingLedger, error) {
+	ledger := &RoundingLedger{Path: ledgerPath}
+
+	data, err := os.ReadFile(ledgerPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading rounding ledger: %w", err)
+	}
+
+	if err = json.Unmarshal(data, ledger); err != nil {
+		return nil, fmt.Errorf("decoding rounding ledger %s: %w", ledgerPath, err)
+	}
+
+	return ledger, nil
+}
+
+// Record adds the rounding of the individual shares to the ledger and persists it
+// Entries for a month, category and participant already in the ledger are replaced, so that recording the same month again does not count its rounding twice
+func (ledger *RoundingLedger) Record(entries []RoundingLedgerEntry) error {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+
+	for _, entry := range entries {
+		index := slices.IndexFunc(ledger.Entries, func(existingEntry RoundingLedgerEntry) bool {
+			return existingEntry.Month == entry.Month &&
+				existingEntry.CategoryName == entry.CategoryName &&
+				existingEntry.ParticipantName == entry.ParticipantName
+		})
+
+		if index >= 0 {
+			ledger.Entries[index] = entry
+		} else {
+			ledger.Entries = append(ledger.Entries, entry)
+		}
+	}
+
+	return ledger.save()
+}
+
+// Discard removes the rounding of a month from the ledger and persists it, e.g. once the import of the month was undone
+func (ledger *RoundingLedger) Discard(month string) error {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+
+	entries := make([]RoundingLedgerEntry, 0, len(ledger.Entries))
+	for _, entry := range ledger.Entries {
+		if entry.Month != month {
+			entries = append(entries, entry)
+		}
+	}
+	ledger.Entries = entries
+
+	return ledger.save()
+}
+
+// save persists the ledger
+func (ledger *RoundingLedger) save() error {
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(ledger.Path), 0700); err != nil {
+		return fmt.Errorf("creating rounding ledger directory: %w", err)
+	}
+
+	if err = writeFileAtomically(ledger.Path, data, 0600); err != nil {
+		return fmt.Errorf("writing rounding ledger: %w", err)
+	}
+
+	return nil
+}
+
+// GetBalances returns the cumulative rounding in each participant's favour across every recorded month
+func (ledger *RoundingLedger) GetBalances() map[string]decimal.Decimal {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+
+	balances := make(map[string]decimal.Decimal)
+
+	for _, entry := range ledger.Entries {
+		balances[entry.ParticipantName] = balances[entry.ParticipantName].Add(entry.Amount)
+	}
+
+	return balances
+}
+
+// GetEntries returns the ledger entries of a month, or of every month if none is given, ordered by month, category and participant
+func (ledger *RoundingLedger) GetEntries(month string) []RoundingLedgerEntry {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+
+	entries := []RoundingLedgerEntry{}
+
+	for _, entry := range ledger.Entries {
+		if month == "" || entry.Month == month {
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortFunc(entries, func(entry RoundingLedgerEntry, otherEntry RoundingLedgerEntry) bool {
+		if entry.Month != otherEntry.Month {
+			return entry.Month < otherEntry.Month
+		}
+		if entry.CategoryName != otherEntry.CategoryName {
+			return entry.CategoryName < otherEntry.CategoryName
+		}
+		return entry.ParticipantName < otherEntry.ParticipantName
+	})
+
+	return entries
+}
+
+// GetRoundingLedgerEntries calculates how the individual share of each participant was rounded for each monthly expense category
+// The exact shares are recalculated from the split rules, so the rounding can be recorded from the monthly expenses alone
+func (combinedMonthlyExpenses *CombinedMonthlyExpenses) GetRoundingLedgerEntries(month string) ([]RoundingLedgerEntry, error) {
+	sharedMonthlyExpenses := combinedMonthlyExpenses.SharedMonthlyExpenses
+
+	participantNames := make([]string, 0, len(combinedMonthlyExpenses.IndividualMonthlyExpenses))
+	for _, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+		participantNames = append(participantNames, individualMonthlyExpenses.ParticipantName)
+	}
+
+	categoryNames := maps.Keys(sharedMonthlyExpenses.Expenses)
+	slices.Sort(categoryNames)
+
+	var entries []RoundingLedgerEntry
+	recordedAt := time.Now()
+
+	for _, categoryName := range categoryNames {
+		sharedMonthlyExpense := sharedMonthlyExpenses.Expenses[categoryName]
+
+		exactShares, err := sharedMonthlyExpense.SplitRule.GetExactShares(sharedMonthlyExpense.Amount.Round(2), participantNames)
+		if err != nil {
+			return nil, fmt.Errorf("splitting %s: %w", categoryName, err)
+		}
+
+		for participantIndex, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+			roundedShare := individualMonthlyExpenses.Expenses[categoryName].Amount
+
+			entries = append(entries, RoundingLedgerEntry{
+				Month:           month,
+				CategoryName:    categoryName,
+				ParticipantName: individualMonthlyExpenses.ParticipantName,
+				ExactShare:      exactShares[participantIndex],
+				RoundedShare:    roundedShare,
+				Amount:          exactShares[participantIndex].Sub(roundedShare),
+				RecordedAt:      recordedAt,
+			})
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,147 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSharedMonthlyExpensesWithRoundingLedger(t *testing.T) {
+	sharedExpenseAmounts := map[string]float64{
+		"Condominium":           245.75,
+		"Electricity":           130.52,
+		"TV / Internet / Phone": 85.90,
+		"Water":                 60.25,
+	}
+
+	testCases := map[string]struct {
+		ledgerEntries                    []RoundingLedgerEntry
+		expectedIndividualExpenseAmounts map[string]float64
+		expectedBalance                  float64
+	}{
+		"empty ledger": {
+			expectedIndividualExpenseAmounts: map[string]float64{
+				"Condominium":           122.88,
+				"Electricity":           65.26,
+				"TV / Internet / Phone": 42.95,
+				"Water":                 30.12,
+			},
+			expectedBalance: 0.0,
+		},
+		"ledger in favour of the first participant": {
+			ledgerEntries: []RoundingLedgerEntry{
+				{Month: "2024-01", CategoryName: "Water", ParticipantName: "Magui", Amount: decimal.NewFromFloat(0.005)},
+				{Month: "2024-01", CategoryName: "Water", ParticipantName: "Jão", Amount: decimal.NewFromFloat(-0.005)},
+			},
+			expectedIndividualExpenseAmounts: map[string]float64{
+				"Condominium":           122.88,
+				"Electricity":           65.26,
+				"TV / Internet / Phone": 42.95,
+				"Water":                 30.13,
+			},
+			expectedBalance: -0.005,
+		},
+		"ledger against the first participant": {
+			ledgerEntries: []RoundingLedgerEntry{
+				{Month: "2024-01", CategoryName: "Water", ParticipantName: "Magui", Amount: decimal.NewFromFloat(-0.005)},
+				{Month: "2024-01", CategoryName: "Water", ParticipantName: "Jão", Amount: decimal.NewFromFloat(0.005)},
+			},
+			expectedIndividualExpenseAmounts: map[string]float64{
+				"Condominium":           122.87,
+				"Electricity":           65.26,
+				"TV / Internet / Phone": 42.95,
+				"Water":                 30.13,
+			},
+			expectedBalance: -0.005,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			ledger, err := LoadRoundingLedger(filepath.Join(t.TempDir(), RoundingLedgerFileName))
+			assert.NoError(t, err)
+			assert.NoError(t, ledger.Record(testCase.ledgerEntries))
+
+			combinedMonthlyExpenses := CombinedMonthlyExpenses{
+				SharedMonthlyExpenses: createFakeMonthlyExpenses(sharedExpenseAmounts),
+				IndividualMonthlyExpenses: []*MonthlyExpenses{
+					createFakeMonthlyExpenses(nil),
+					createFakeMonthlyExpenses(nil),
+				},
+				RoundingStrategy: &LedgerRoundingStrategy{Ledger: ledger},
+			}
+			combinedMonthlyExpenses.IndividualMonthlyExpenses[0].ParticipantName = "Magui"
+			combinedMonthlyExpenses.IndividualMonthlyExpenses[1].ParticipantName = "Jão"
+
+			assert.NoError(t, combinedMonthlyExpenses.SplitSharedMonthlyExpenses())
+
+			for _, categoryName := range categoryNames {
+				expectedIndividualExpenseAmount := decimal.NewFromFloat(testCase.expectedIndividualExpenseAmounts[categoryName])
+				actualIndividualExpenseAmount := combinedMonthlyExpenses.IndividualMonthlyExpenses[0].Expenses[categoryName].Amount
+
+				assert.True(t, expectedIndividualExpenseAmount.Equal(actualIndividualExpenseAmount),
+					fmt.Sprintf("Expected individual share for category '%s' to be %s, but got %s",
+						categoryName, expectedIndividualExpenseAmount.String(), actualIndividualExpenseAmount.String()))
+			}
+
+			entries, err := combinedMonthlyExpenses.GetRoundingLedgerEntries("2024-02")
+			assert.NoError(t, err)
+			assert.NoError(t, ledger.Record(entries))
+
+			reloadedLedger, err := LoadRoundingLedger(ledger.Path)
+			assert.NoError(t, err)
+
+			expectedBalance := decimal.NewFromFloat(testCase.expectedBalance)
+			actualBalance := reloadedLedger.GetBalances()["Magui"]
+
+			assert.True(t, expectedBalance.Equal(actualBalance),
+				fmt.Sprintf("Expected cumulative rounding in favour of the first participant to be %s, but got %s", expectedBalance.String(), actualBalance.String()))
+			assert.True(t, expectedBalance.Neg().Equal(reloadedLedger.GetBalances()["Jão"]),
+				"Expected cumulative rounding of both participants to cancel out")
+		})
+	}
+}
+
+func TestRecordReplacesRoundingOfTheSameMonth(t *testing.T) {
+	ledger, err := LoadRoundingLedger(filepath.Join(t.TempDir(), RoundingLedgerFileName))
+	assert.NoError(t, err)
+
+	entry := RoundingLedgerEntry{Month: "2024-01", CategoryName: "Water", ParticipantName: "Magui", Amount: decimal.NewFromFloat(0.005)}
+
+	assert.NoError(t, ledger.Record([]RoundingLedgerEntry{entry}))
+	assert.NoError(t, ledger.Record([]RoundingLedgerEntry{entry}))
+
+	assert.Len(t, ledger.GetEntries("2024-01"), 1, "Expected recording the same month twice to keep a single entry")
+	assert.True(t, decimal.NewFromFloat(0.005).Equal(ledger.GetBalances()["Magui"]), "Expected rounding of the same month not to be counted twice")
+}
+
+func TestGetEntriesOrdersByMonthCategoryAndParticipant(t *testing.T) {
+	ledger, err := LoadRoundingLedger(filepath.Join(t.TempDir(), RoundingLedgerFileName))
+	assert.NoError(t, err)
+
+	assert.NoError(t, ledger.Record([]RoundingLedgerEntry{
+		{Month: "2024-02", CategoryName: "Water", ParticipantName: "Magui"},
+		{Month: "2024-02", CategoryName: "Water", ParticipantName: "Jão"},
+		{Month: "2024-02", CategoryName: "Electricity", ParticipantName: "Magui"},
+		{Month: "2024-02", CategoryName: "Electricity", ParticipantName: "Jão"},
+	}))
+	assert.NoError(t, ledger.Record([]RoundingLedgerEntry{
+		{Month: "2024-01", CategoryName: "Water", ParticipantName: "Magui"},
+	}))
+
+	var actualOrder []string
+	for _, entry := range ledger.GetEntries("") {
+		actualOrder = append(actualOrder, entry.Month+" "+entry.CategoryName+" "+entry.ParticipantName)
+	}
+
+	assert.Equal(t, []string{
+		"2024-01 Water Magui",
+		"2024-02 Electricity Jão",
+		"2024-02 Electricity Magui",
+		"2024-02 Water Jão",
+		"2024-02 Water Magui",
+	}, actualOrder)
+}
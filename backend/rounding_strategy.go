@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/slices"
+)
+
+// Rounding strategies supported by RoundingConfig
+const (
+	RoundingStrategyAlternating      string = "alternating"
+	RoundingStrategyLargestRemainder string = "largest_remainder"
+	RoundingStrategyBankers          string = "bankers"
+	RoundingStrategyFavourPayer      string = "favour_payer"
+	RoundingStrategyLedger           string = "ledger"
+)
+
+// RoundingAllocation represents the individual shares of a monthly expense category while they are being rounded
+// The shares start rounded down to the cent and the rounding strategy hands out the leftover cents, one per participant at most, so that the shares sum exactly to the shared amount
+type RoundingAllocation struct {
+	CategoryName     string
+	ParticipantNames []string
+	ExactShares      []decimal.Decimal
+	Shares           []decimal.Decimal
+	LeftoverCents    int64
+}
+
+// RoundingStrategy represents how the cents left over by rounding down the individual shares are distributed among the participants
+type RoundingStrategy interface {
+	// BeginSplit is called before the monthly expense categories are split, in alphabetical order
+	BeginSplit(participantNames []string)
+	// DistributeLeftoverCents hands out the leftover cents of a monthly expense category
+	DistributeLeftoverCents(allocation *RoundingAllocation)
+}
+
+// RandomSource represents the source of randomness used by the alternating rounding strategy, which *rand.Rand satisfies
+type RandomSource interface {
+	Intn(n int) int
+}
+
+// IsRounded checks if the exact share of a participant had to be rounded down to the cent
+func (allocation *RoundingAllocation) IsRounded(participantIndex int) bool {
+	return !allocation.Shares[participantIndex].Equal(allocation.ExactShares[participantIndex])
+}
+
+// RoundUp hands out the leftover cents to the participants in the given order of priority, skipping those whose exact share did not have to be rounded
+func (allocation *RoundingAllocation) RoundUp(participantIndexes []int) {
+	for _, participantIndex := range participantIndexes {
+		if allocation.LeftoverCents == 0 {
+			return
+		}
+
+		if allocation.IsRounded(participantIndex) {
+			allocation.Shares[participantIndex] = allocation.Shares[participantIndex].Add(decimal.New(1, -2))
+			allocation.LeftoverCents--
+		}
+	}
+}
+
+// AlternatingRoundingStrategy hands out the leftover cents rotating through the participants, starting from a random participant on each split
+type AlternatingRoundingStrategy struct {
+	Random             RandomSource
+	roundUpParticipant int
+}
+
+// NewAlternatingRoundingStrategy creates a new AlternatingRoundingStrategy instance, seeding its source of randomness from the current time if none is given
+func NewAlternatingRoundingStrategy(random RandomSource) *AlternatingRoundingStrategy {
+	if random == nil {
+		random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return &AlternatingRoundingStrategy{Random: random}
+}
+
+// BeginSplit picks the random participant receiving the first leftover cent
+func (strategy *AlternatingRoundingStrategy) BeginSplit(participantNames []string) {
+	strategy.roundUpParticipant = strategy.Random.Intn(len(participantNames))
+}
+
+// DistributeLeftoverCents hands out the leftover cents to the participants next in line
+func (strategy *AlternatingRoundingStrategy) DistributeLeftoverCents(allocation *RoundingAllocation) {
+	participantCount := len(allocation.Shares)
+
+	for allocation.LeftoverCents > 0 {
+		if allocation.IsRounded(strategy.roundUpParticipant) {
+			allocation.RoundUp([]int{strategy.roundUpParticipant})
+		}
+		strategy.roundUpParticipant = (strategy.roundUpParticipant + 1) % participantCount
+	}
+}
+
+// LargestRemainderRoundingStrategy hands out the leftover cents to the participants whose exact share lost the most by rounding down (Hamilton method), ties going to the participant declared first
+type LargestRemainderRoundingStrategy struct{}
+
+// BeginSplit does nothing, as the largest remainder method does not depend on previous categories
+func (strategy *LargestRemainderRoundingStrategy) BeginSplit(participantNames []string) {}
+
+// DistributeLeftoverCents hands out the leftover cents by descending remainder
+func (strategy *LargestRemainderRoundingStrategy) DistributeLeftoverCents(allocation *RoundingAllocation) {
+	participantIndexes := getParticipantIndexes(allocation)
+
+	slices.SortStableFunc(participantIndexes, func(participantIndex int, otherParticipantIndex int) bool {
+		remainder := allocation.ExactShares[participantIndex].Sub(allocation.Shares[participantIndex])
+		otherRemainder := allocation.ExactShares[otherParticipantIndex].Sub(allocation.Shares[otherParticipantIndex])
+		return remainder.GreaterThan(otherRemainder)
+	})
+
+	allocation.RoundUp(participantIndexes)
+}
+
+// BankersRoundingStrategy hands out the leftover cents first to the participants whose exact share rounds up under banker's rounding (round half to even), in the order they are declared
+// Since the shares must sum exactly to the shared amount, the remaining leftover cents go to the other participants, and participants whose exact share rounds up may still be rounded down if there are not enough leftover cents
+type BankersRoundingStrategy struct{}
+
+// BeginSplit does nothing, as banker's rounding does not depend on previous categories
+func (strategy *BankersRoundingStrategy) BeginSplit(participantNames []string) {}
+
+// DistributeLeftoverCents hands out the leftover cents according to banker's rounding
+func (strategy *BankersRoundingStrategy) DistributeLeftoverCents(allocation *RoundingAllocation) {
+	participantIndexes := getParticipantIndexes(allocation)
+
+	slices.SortStableFunc(participantIndexes, func(participantIndex int, otherParticipantIndex int) bool {
+		return allocation.ExactShares[participantIndex].RoundBank(2).GreaterThan(allocation.Shares[participantIndex]) &&
+			!allocation.ExactShares[otherParticipantIndex].RoundBank(2).GreaterThan(allocation.Shares[otherParticipantIndex])
+	})
+
+	allocation.RoundUp(participantIndexes)
+}
+
+// FavourPayerRoundingStrategy always rounds in favour of the participant who pays the shared monthly expenses, handing out the leftover cents to the other participants in the order they are declared
+// The payer only rounds up when the other participants cannot take every leftover cent
+type FavourPayerRoundingStrategy struct {
+	PayerName string
+}
+
+// BeginSplit does nothing, as the payer is always favoured
+func (strategy *FavourPayerRoundingStrategy) BeginSplit(participantNames []string) {}
+
+// DistributeLeftoverCents hands out the leftover cents to the participants other than the payer first
+func (strategy *FavourPayerRoundingStrategy) DistributeLeftoverCents(allocation *RoundingAllocation) {
+	participantIndexes := getParticipantIndexes(allocation)
+
+	slices.SortStableFunc(participantIndexes, func(participantIndex int, otherParticipantIndex int) bool {
+		return allocation.ParticipantNames[participantIndex] != strategy.PayerName &&
+			allocation.ParticipantNames[otherParticipantIndex] == strategy.PayerName
+	})
+
+	allocation.RoundUp(participantIndexes)
+}
+
+// LedgerRoundingStrategy hands out the leftover cents to the participants with the most rounding in their favour recorded in the rounding ledger, ties going to the participant declared first
+// This keeps the cumulative imbalance between participants as close to zero as possible across months
+type LedgerRoundingStrategy struct {
+	Ledger           *RoundingLedger
+	roundingBalances map[string]decimal.Decimal
+}
+
+// BeginSplit reads the cumulative rounding of each participant from the rounding ledger
+func (strategy *LedgerRoundingStrategy) BeginSplit(participantNames []string) {
+	strategy.roundingBalances = strategy.Ledger.GetBalances()
+}
+
+// DistributeLeftoverCents hands out the leftover cents by descending rounding balance
+// The rounding balances are updated with the rounding of every share, so that subsequent categories take it into account
+func (strategy *LedgerRoundingStrategy) DistributeLeftoverCents(allocation *RoundingAllocation) {
+	participantIndexes := getParticipantIndexes(allocation)
+
+	slices.SortStableFunc(participantIndexes, func(participantIndex int, otherParticipantIndex int) bool {
+		return strategy.roundingBalances[allocation.ParticipantNames[participantIndex]].GreaterThan(
+			strategy.roundingBalances[allocation.ParticipantNames[otherParticipantIndex]])
+	})
+
+	allocation.RoundUp(participantIndexes)
+
+	for participantIndex, participantName := range allocation.ParticipantNames {
+		strategy.roundingBalances[participantName] = strategy.roundingBalances[participantName].Add(
+			allocation.ExactShares[participantIndex].Sub(allocation.Shares[participantIndex]))
+	}
+}
+
+// getParticipantIndexes returns the index of every participant in the order they are declared
+func getParticipantIndexes(allocation *RoundingAllocation) []int {
+	participantIndexes := make([]int, len(allocation.Shares))
+
+	for participantIndex := range participantIndexes {
+		participantIndexes[participantIndex] = participantIndex
+	}
+
+	return participantIndexes
+}
+
+// RoundingStrategyFromConfig creates the rounding strategy declared in the household configuration, which defaults to the rounding ledger
+func RoundingStrategyFromConfig(rounding RoundingConfig, ledger *RoundingLedger) (RoundingStrategy, error) {
+	switch rounding.Strategy {
+	case "", RoundingStrategyLedger:
+		if ledger == nil {
+			return nil, fmt.Errorf("the %s rounding strategy requires a rounding ledger", RoundingStrategyLedger)
+		}
+		return &LedgerRoundingStrategy{Ledger: ledger}, nil
+	case RoundingStrategyAlternating:
+		return NewAlternatingRoundingStrategy(nil), nil
+	case RoundingStrategyLargestRemainder:
+		return &LargestRemainderRoundingStrategy{}, nil
+	case RoundingStrategyBankers:
+		return &BankersRoundingStrategy{}, nil
+	case RoundingStrategyFavourPayer:
+		return &FavourPayerRoundingStrategy{PayerName: rounding.Payer}, nil
+	default:
+		return nil, fmt.Errorf("rounding strategy %q is not supported", rounding.Strategy)
+	}
+}
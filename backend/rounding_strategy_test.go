@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundingStrategies(t *testing.T) {
+	ledger, err := LoadRoundingLedger(filepath.Join(t.TempDir(), RoundingLedgerFileName))
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.Record([]RoundingLedgerEntry{
+		{Month: "2024-01", CategoryName: "Water", ParticipantName: "Magui", Amount: decimal.NewFromFloat(0.005)},
+		{Month: "2024-01", CategoryName: "Water", ParticipantName: "Jão", Amount: decimal.NewFromFloat(-0.005)},
+	}))
+
+	// A 25/75 split of 0.18€ gives exact shares of 0.045€ and 0.135€, so a single leftover cent must be handed out
+	testCases := map[string]struct {
+		roundingStrategy                 RoundingStrategy
+		expectedIndividualExpenseAmounts [2]float64
+	}{
+		"alternating": {
+			roundingStrategy:                 NewAlternatingRoundingStrategy(fixedRandomSource(1)),
+			expectedIndividualExpenseAmounts: [2]float64{0.04, 0.14},
+		},
+		"largest remainder - ties go to the participant declared first": {
+			roundingStrategy:                 &LargestRemainderRoundingStrategy{},
+			expectedIndividualExpenseAmounts: [2]float64{0.05, 0.13},
+		},
+		"banker's rounding - only the odd cent rounds up": {
+			roundingStrategy:                 &BankersRoundingStrategy{},
+			expectedIndividualExpenseAmounts: [2]float64{0.04, 0.14},
+		},
+		"favour payer - first participant pays": {
+			roundingStrategy:                 &FavourPayerRoundingStrategy{PayerName: "Magui"},
+			expectedIndividualExpenseAmounts: [2]float64{0.04, 0.14},
+		},
+		"favour payer - second participant pays": {
+			roundingStrategy:                 &FavourPayerRoundingStrategy{PayerName: "Jão"},
+			expectedIndividualExpenseAmounts: [2]float64{0.05, 0.13},
+		},
+		"ledger - rounding in favour of the first participant": {
+			roundingStrategy:                 &LedgerRoundingStrategy{Ledger: ledger},
+			expectedIndividualExpenseAmounts: [2]float64{0.05, 0.13},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			combinedMonthlyExpenses := CombinedMonthlyExpenses{
+				SharedMonthlyExpenses: &MonthlyExpenses{
+					Expenses: map[string]*MonthlyExpense{
+						"Water": {
+							CategoryId: to.StringPtr("water"),
+							Amount:     decimal.NewFromFloat(0.18),
+							SplitRule: &SplitRule{
+								Rule:    SplitRulePercentages,
+								Weights: map[string]decimal.Decimal{"Magui": decimal.NewFromInt(25), "Jão": decimal.NewFromInt(75)},
+							},
+						},
+					},
+				},
+				IndividualMonthlyExpenses: []*MonthlyExpenses{
+					{ParticipantName: "Magui", Expenses: map[string]*MonthlyExpense{}},
+					{ParticipantName: "Jão", Expenses: map[string]*MonthlyExpense{}},
+				},
+				RoundingStrategy: testCase.roundingStrategy,
+			}
+
+			assert.NoError(t, combinedMonthlyExpenses.SplitSharedMonthlyExpenses())
+
+			for participantIndex, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+				expectedIndividualExpenseAmount := decimal.NewFromFloat(testCase.expectedIndividualExpenseAmounts[participantIndex])
+				actualIndividualExpenseAmount := individualMonthlyExpenses.Expenses["Water"].Amount
+
+				assert.True(t, expectedIndividualExpenseAmount.Equal(actualIndividualExpenseAmount),
+					fmt.Sprintf("Expected individual share of %s to be %s, but got %s",
+						individualMonthlyExpenses.ParticipantName, expectedIndividualExpenseAmount.String(), actualIndividualExpenseAmount.String()))
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// SetupProgress represents how far setting up the backend got, with the last step completed
+// The total number of steps is only known once the household configuration is loaded, and is 0 until then
+type SetupProgress struct {
+	Message   string `json:"message"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+}
+
+// GetSetupProgress returns how far setting up the backend got, e.g. for a window opened while it is still being set up
+func (backend *Backend) GetSetupProgress() SetupProgress {
+	backend.progressMutex.Lock()
+	defer backend.progressMutex.Unlock()
+
+	return backend.setupProgress
+}
+
+// startSetupProgress resets the setup progress, before setting up the backend again
+func (backend *Backend) startSetupProgress(message string) {
+	backend.progressMutex.Lock()
+	backend.setupProgress = SetupProgress{Message: message}
+	backend.progressMutex.Unlock()
+
+	backend.emitSetupProgress()
+}
+
+// setSetupProgressTotal sets the total number of setup steps, once they are known
+func (backend *Backend) setSetupProgressTotal(total int) {
+	backend.progressMutex.Lock()
+	defer backend.progressMutex.Unlock()
+
+	backend.setupProgress.Total = total
+}
+
+// reportSetupProgress completes a setup step, emitting the "setupProgress" event with the message describing it
+// Steps may complete concurrently, e.g. as the YNAB budgets are synced
+func (backend *Backend) reportSetupProgress(message string) {
+	backend.progressMutex.Lock()
+	backend.setupProgress.Message = message
+	backend.setupProgress.Completed++
+	if backend.setupProgress.Total > 0 && backend.setupProgress.Completed > backend.setupProgress.Total {
+		backend.setupProgress.Completed = backend.setupProgress.Total
+	}
+	backend.progressMutex.Unlock()
+
+	backend.emitSetupProgress()
+}
+
+// finishSetupProgress completes every setup step, including the ones skipped because an earlier step failed
+func (backend *Backend) finishSetupProgress() {
+	backend.progressMutex.Lock()
+	backend.setupProgress.Message = "Setup complete"
+	backend.setupProgress.Total = max(backend.setupProgress.Total, backend.setupProgress.Completed)
+	backend.setupProgress.Completed = backend.setupProgress.Total
+	backend.progressMutex.Unlock()
+
+	backend.emitSetupProgress()
+}
+
+// emitSetupProgress emits the "setupProgress" event with how far setting up the backend got
+func (backend *Backend) emitSetupProgress() {
+	if backend.Context == nil {
+		return
+	}
+
+	runtime.EventsEmit(backend.Context, "setupProgress", backend.GetSetupProgress())
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Split rules supported by SplitRule
+const (
+	SplitRuleEqual       string = "equal"
+	SplitRulePercentages string = "percentages"
+	SplitRuleFixed       string = "fixed"
+	SplitRuleIncome      string = "income"
+)
+
+// SplitRule represents how a shared monthly expense is split among the participants
+// Percentages and income-proportional splits weight each participant by name, while fixed splits charge a fixed amount to some participants and split the remainder equally among the others
+type SplitRule struct {
+	Rule    string                     `json:"rule" mapstructure:"rule"`
+	Weights map[string]decimal.Decimal `json:"weights" mapstructure:"weights"`
+	Amounts map[string]decimal.Decimal `json:"amounts" mapstructure:"amounts"`
+}
+
+// GetExactShares calculates the unrounded share of each participant of a shared monthly expense amount
+// The shares always sum exactly to the amount
+func (splitRule *SplitRule) GetExactShares(amount decimal.Decimal, participantNames []string) ([]decimal.Decimal, error) {
+	rule := SplitRuleEqual
+	if splitRule != nil && splitRule.Rule != "" {
+		rule = splitRule.Rule
+	}
+
+	weights := make([]decimal.Decimal, len(participantNames))
+	remainingAmount := amount
+
+	exactShares := make([]decimal.Decimal, len(participantNames))
+
+	switch rule {
+	case SplitRuleEqual:
+		for index := range participantNames {
+			weights[index] = decimal.NewFromInt(1)
+		}
+	case SplitRulePercentages, SplitRuleIncome:
+		for index, participantName := range participantNames {
+			weights[index] = splitRule.Weights[participantName]
+		}
+	case SplitRuleFixed:
+		for index, participantName := range participantNames {
+			if fixedAmount, ok := splitRule.Amounts[participantName]; ok {
+				exactShares[index] = fixedAmount
+				remainingAmount = remainingAmount.Sub(fixedAmount)
+			} else {
+				weights[index] = decimal.NewFromInt(1)
+			}
+		}
+
+		if remainingAmount.IsNegative() {
+			return nil, fmt.Errorf("the fixed amounts add up to more than the shared amount of %s", amount.StringFixed(2))
+		}
+	default:
+		return nil, fmt.Errorf("split rule %q is not supported", rule)
+	}
+
+	totalWeight := decimal.Sum(decimal.Zero, weights...)
+	if !totalWeight.IsPositive() {
+		return nil, fmt.Errorf("the %s split rule does not weight any participant", rule)
+	}
+
+	// The last weighted participant takes whatever is left so that the shares sum exactly to the amount despite the division precision
+	lastWeightedIndex := 0
+	for index, weight := range weights {
+		if weight.IsPositive() {
+			lastWeightedIndex = index
+		}
+	}
+
+	allocatedAmount := decimal.Zero
+
+	for index, weight := range weights {
+		if !weight.IsPositive() {
+			continue
+		}
+
+		if index == lastWeightedIndex {
+			exactShares[index] = remainingAmount.Sub(allocatedAmount)
+			break
+		}
+
+		exactShares[index] = remainingAmount.Mul(weight).Div(totalWeight)
+		allocatedAmount = allocatedAmount.Add(exactShares[index])
+	}
+
+	return exactShares, nil
+}
+
+// SplitRuleFromConfig creates the split rule of a monthly expense declared in the household configuration
+func SplitRuleFromConfig(split SplitConfig, participants []ParticipantConfig) *SplitRule {
+	splitRule := &SplitRule{Rule: split.Rule}
+
+	switch split.Rule {
+	case "":
+		splitRule.Rule = SplitRuleEqual
+	case SplitRulePercentages:
+		splitRule.Weights = split.Percentages
+	case SplitRuleFixed:
+		splitRule.Amounts = split.Amounts
+	case SplitRuleIncome:
+		splitRule.Weights = make(map[string]decimal.Decimal, len(participants))
+		for _, participant := range participants {
+			splitRule.Weights[participant.Name] = participant.Income
+		}
+	}
+
+	return splitRule
+}
@@ -1,9 +1,16 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 )
 
+// Types of YNAB transactions GetTransactions may be restricted to
+const (
+	TransactionTypeUnapproved    string = "unapproved"
+	TransactionTypeUncategorized string = "uncategorized"
+)
+
 // TransactionSummary represents the summary of a YNAB transaction
 // This struct corresponds to the data structure defined in the YNAB API documentation
 type TransactionSummary struct {
@@ -80,9 +87,143 @@ type SaveSubTransaction struct {
 	Memo       *string `json:"memo"`
 }
 
+// SaveTransactionsResponse represents the response to creating new YNAB transactions
+// Transactions whose import id already exists in the account are not created, and their import ids are returned as duplicates instead
+// This struct corresponds to the data structure defined in the YNAB API documentation
+type SaveTransactionsResponse struct {
+	TransactionIds     []string            `json:"transaction_ids"`
+	Transactions       []TransactionDetail `json:"transactions"`
+	DuplicateImportIds []string            `json:"duplicate_import_ids"`
+	ServerKnowledge    int64               `json:"server_knowledge"`
+}
+
+// TransactionDetails represents a collection of YNAB transactions with their details
+type TransactionDetails []TransactionDetail
+
+// SaveTransactionWithId represents the schema for updating an existing YNAB transaction, identified by its id
+// Only the fields that are set are updated, the others are left as they are
+// This struct corresponds to the data structure defined in the YNAB API documentation
+type SaveTransactionWithId struct {
+	Id         string  `json:"id"`
+	AccountId  *string `json:"account_id,omitempty"`
+	Date       *string `json:"date,omitempty"`
+	Amount     *int64  `json:"amount,omitempty"`
+	PayeeId    *string `json:"payee_id,omitempty"`
+	PayeeName  *string `json:"payee_name,omitempty"`
+	CategoryId *string `json:"category_id,omitempty"`
+	Memo       *string `json:"memo,omitempty"`
+	Cleared    *string `json:"cleared,omitempty"`
+	Approved   *bool   `json:"approved,omitempty"`
+	FlagColor  *string `json:"flag_color,omitempty"`
+}
+
+// TransactionsFilter represents the filters YNAB transactions are fetched with, each of them optional
+// SinceDate is formatted as YYYY-MM-DD, Type is either TransactionTypeUnapproved or TransactionTypeUncategorized, and the transactions may be restricted to an account, a category or both
+type TransactionsFilter struct {
+	SinceDate             string `json:"since_date"`
+	Type                  string `json:"type"`
+	AccountId             string `json:"account_id"`
+	CategoryId            string `json:"category_id"`
+	LastKnowledgeOfServer int64  `json:"last_knowledge_of_server"`
+}
+
+// GetTransactions fetches the YNAB transactions of a YNAB budget matching the filter, along with the current server knowledge
+// Transactions restricted to an account are fetched from the account, and then restricted to the category of the filter, if any, as YNAB cannot filter by both
+// Transactions restricted to a category only are returned as YNAB returns them for a category, i.e. with each sub-transaction of the category as a transaction of its own
+// GET https://api.ynab.com/v1/budgets/{budget_id}/transactions
+// GET https://api.ynab.com/v1/budgets/{budget_id}/accounts/{account_id}/transactions
+// GET https://api.ynab.com/v1/budgets/{budget_id}/categories/{category_id}/transactions
+func (client *APIClient) GetTransactions(ctx context.Context, budgetId string, filter TransactionsFilter) (TransactionDetails, int64, error) {
+	transactionsResponse := struct {
+		Data struct {
+			Transactions    TransactionDetails `json:"transactions"`
+			ServerKnowledge int64              `json:"server_knowledge"`
+		} `json:"data"`
+	}{}
+
+	path := fmt.Sprintf("budgets/%s/transactions", budgetId)
+	switch {
+	case filter.AccountId != "":
+		path = fmt.Sprintf("budgets/%s/accounts/%s/transactions", budgetId, filter.AccountId)
+	case filter.CategoryId != "":
+		path = fmt.Sprintf("budgets/%s/categories/%s/transactions", budgetId, filter.CategoryId)
+	}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetQueryParams(filter.getQueryParams()).
+		SetResult(&transactionsResponse).
+		Get(path)
+
+	if err = client.ValidateResponse(response, err); err != nil {
+		return nil, 0, err
+	}
+
+	transactions := transactionsResponse.Data.Transactions
+	if filter.AccountId != "" && filter.CategoryId != "" {
+		transactions = transactions.filterByCategory(filter.CategoryId)
+	}
+
+	return transactions, transactionsResponse.Data.ServerKnowledge, nil
+}
+
+// getQueryParams returns the query parameters of the filters YNAB applies itself
+func (filter TransactionsFilter) getQueryParams() map[string]string {
+	queryParams := getDeltaQueryParams(filter.LastKnowledgeOfServer)
+	if filter.SinceDate != "" {
+		queryParams["since_date"] = filter.SinceDate
+	}
+	if filter.Type != "" {
+		queryParams["type"] = filter.Type
+	}
+
+	return queryParams
+}
+
+// filterByCategory returns the transactions of a category, including split transactions with a sub-transaction of the category
+func (transactions TransactionDetails) filterByCategory(categoryId string) TransactionDetails {
+	filteredTransactions := TransactionDetails{}
+	for _, transaction := range transactions {
+		if transaction.CategoryId == categoryId {
+			filteredTransactions = append(filteredTransactions, transaction)
+			continue
+		}
+
+		for _, subTransaction := range transaction.SubTransactions {
+			if subTransaction.CategoryId == categoryId && !subTransaction.Deleted {
+				filteredTransactions = append(filteredTransactions, transaction)
+				break
+			}
+		}
+	}
+
+	return filteredTransactions
+}
+
+// GetTransaction fetches a YNAB transaction of a YNAB budget by id
+// GET https://api.ynab.com/v1/budgets/{budget_id}/transactions/{transaction_id}
+func (client *APIClient) GetTransaction(ctx context.Context, budgetId string, transactionId string) (TransactionDetail, error) {
+	transactionResponse := struct {
+		Data struct {
+			Transaction TransactionDetail `json:"transaction"`
+		} `json:"data"`
+	}{}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetResult(&transactionResponse).
+		Get(fmt.Sprintf("budgets/%s/transactions/%s", budgetId, transactionId))
+
+	if err = client.ValidateResponse(response, err); err != nil {
+		return TransactionDetail{}, err
+	}
+
+	return transactionResponse.Data.Transaction, nil
+}
+
 // CreateTransaction creates a new YNAB transaction for a YNAB budget
 // POST https://api.ynab.com/v1/budgets/{budget_id}/transactions
-func (client *APIClient) CreateTransaction(budgetId string, transaction SaveTransaction) (TransactionDetail, error) {
+func (client *APIClient) CreateTransaction(ctx context.Context, budgetId string, transaction SaveTransaction) (TransactionDetail, error) {
 	transactionBody := struct {
 		Transaction SaveTransaction `json:"transaction"`
 	}{
@@ -97,6 +238,7 @@ func (client *APIClient) CreateTransaction(budgetId string, transaction SaveTran
 	}{}
 
 	response, err := client.Client.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(transactionBody).
 		SetResult(&transactionResponse).
@@ -111,7 +253,7 @@ func (client *APIClient) CreateTransaction(budgetId string, transaction SaveTran
 
 // CreateTransactions creates new YNAB transactions for a YNAB budget
 // POST https://api.ynab.com/v1/budgets/{budget_id}/transactions
-func (client *APIClient) CreateTransactions(budgetId string, transactions []SaveTransaction) ([]TransactionDetail, error) {
+func (client *APIClient) CreateTransactions(ctx context.Context, budgetId string, transactions []SaveTransaction) (SaveTransactionsResponse, error) {
 	transactionsBody := struct {
 		Transactions []SaveTransaction `json:"transactions"`
 	}{
@@ -119,21 +261,82 @@ func (client *APIClient) CreateTransactions(budgetId string, transactions []Save
 	}
 
 	transactionsResponse := struct {
-		Data struct {
-			TransactionIds []string            `json:"transaction_ids"`
-			Transactions   []TransactionDetail `json:"transactions"`
-		} `json:"data"`
+		Data SaveTransactionsResponse `json:"data"`
 	}{}
 
 	response, err := client.Client.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(transactionsBody).
 		SetResult(&transactionsResponse).
 		Post(fmt.Sprintf("budgets/%s/transactions", budgetId))
 
 	if err = client.ValidateResponse(response, err); err != nil {
-		return nil, err
+		return SaveTransactionsResponse{}, err
+	}
+
+	return transactionsResponse.Data, nil
+}
+
+// UpdateTransaction updates an existing YNAB transaction of a YNAB budget, leaving the fields that are not set as they are
+// PATCH https://api.ynab.com/v1/budgets/{budget_id}/transactions
+func (client *APIClient) UpdateTransaction(ctx context.Context, budgetId string, transaction SaveTransactionWithId) (TransactionDetail, error) {
+	response, err := client.UpdateTransactions(ctx, budgetId, []SaveTransactionWithId{transaction})
+	if err != nil {
+		return TransactionDetail{}, err
+	}
+
+	if len(response.Transactions) == 0 {
+		return TransactionDetail{}, fmt.Errorf("%w: transaction %s was not updated", ErrNotFound, transaction.Id)
+	}
+
+	return response.Transactions[0], nil
+}
+
+// UpdateTransactions updates existing YNAB transactions of a YNAB budget, leaving the fields that are not set as they are
+// PATCH https://api.ynab.com/v1/budgets/{budget_id}/transactions
+func (client *APIClient) UpdateTransactions(ctx context.Context, budgetId string, transactions []SaveTransactionWithId) (SaveTransactionsResponse, error) {
+	transactionsBody := struct {
+		Transactions []SaveTransactionWithId `json:"transactions"`
+	}{
+		Transactions: transactions,
+	}
+
+	transactionsResponse := struct {
+		Data SaveTransactionsResponse `json:"data"`
+	}{}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(transactionsBody).
+		SetResult(&transactionsResponse).
+		Patch(fmt.Sprintf("budgets/%s/transactions", budgetId))
+
+	if err = client.ValidateResponse(response, err); err != nil {
+		return SaveTransactionsResponse{}, err
+	}
+
+	return transactionsResponse.Data, nil
+}
+
+// DeleteTransaction deletes an existing YNAB transaction from a YNAB budget
+// DELETE https://api.ynab.com/v1/budgets/{budget_id}/transactions/{transaction_id}
+func (client *APIClient) DeleteTransaction(ctx context.Context, budgetId string, transactionId string) (TransactionDetail, error) {
+	transactionResponse := struct {
+		Data struct {
+			Transaction TransactionDetail `json:"transaction"`
+		} `json:"data"`
+	}{}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetResult(&transactionResponse).
+		Delete(fmt.Sprintf("budgets/%s/transactions/%s", budgetId, transactionId))
+
+	if err = client.ValidateResponse(response, err); err != nil {
+		return TransactionDetail{}, err
 	}
 
-	return transactionsResponse.Data.Transactions, nil
+	return transactionResponse.Data.Transaction, nil
 }
@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+
+	"ynab-monthly-expenses-manager/backend/ynabtest"
+)
+
+func TestTransactionLifecycle(t *testing.T) {
+	ynab := ynabtest.NewServer(t, testAccessToken)
+	budget := ynab.AddBudget("Casa")
+	sharedAccountId := budget.AddAccount("Millennium bcp")
+	otherAccountId := budget.AddAccount("CGD")
+	waterCategoryId := budget.AddCategory("Obligatory Monthly Expenses", "Water")
+	electricityCategoryId := budget.AddCategory("Obligatory Monthly Expenses", "Electricity")
+
+	client := &APIClient{Client: resty.New().SetBaseURL(ynab.BaseURL()).SetAuthToken(testAccessToken)}
+
+	response, err := client.CreateTransactions(context.Background(), budget.Id, []SaveTransaction{
+		{AccountId: &sharedAccountId, Date: "2024-01-05", Amount: -60250, CategoryId: &waterCategoryId, Approved: true},
+		{AccountId: &sharedAccountId, Date: "2024-02-05", Amount: -130510, CategoryId: &electricityCategoryId},
+		{AccountId: &otherAccountId, Date: "2024-02-05", Amount: -61000, CategoryId: &waterCategoryId},
+		{AccountId: &otherAccountId, Date: "2024-02-06", Amount: -1000},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, response.TransactionIds, 4)
+
+	testCases := map[string]struct {
+		filter          TransactionsFilter
+		expectedAmounts []int64
+	}{
+		"every transaction": {
+			expectedAmounts: []int64{-60250, -130510, -61000, -1000},
+		},
+		"since a date": {
+			filter:          TransactionsFilter{SinceDate: "2024-02-01"},
+			expectedAmounts: []int64{-130510, -61000, -1000},
+		},
+		"unapproved": {
+			filter:          TransactionsFilter{Type: TransactionTypeUnapproved},
+			expectedAmounts: []int64{-130510, -61000, -1000},
+		},
+		"uncategorized": {
+			filter:          TransactionsFilter{Type: TransactionTypeUncategorized},
+			expectedAmounts: []int64{-1000},
+		},
+		"of an account": {
+			filter:          TransactionsFilter{AccountId: sharedAccountId},
+			expectedAmounts: []int64{-60250, -130510},
+		},
+		"of a category": {
+			filter:          TransactionsFilter{CategoryId: waterCategoryId},
+			expectedAmounts: []int64{-60250, -61000},
+		},
+		"of a category in an account since a date": {
+			filter:          TransactionsFilter{AccountId: otherAccountId, CategoryId: waterCategoryId, SinceDate: "2024-02-01"},
+			expectedAmounts: []int64{-61000},
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			transactions, serverKnowledge, err := client.GetTransactions(context.Background(), budget.Id, testCase.filter)
+
+			assert.NoError(t, err)
+			assert.Positive(t, serverKnowledge)
+
+			var amounts []int64
+			for _, transaction := range transactions {
+				amounts = append(amounts, transaction.Amount)
+			}
+			assert.Equal(t, testCase.expectedAmounts, amounts)
+		})
+	}
+
+	_, serverKnowledge, err := client.GetTransactions(context.Background(), budget.Id, TransactionsFilter{})
+	assert.NoError(t, err)
+
+	updatedTransaction, err := client.UpdateTransaction(context.Background(), budget.Id, SaveTransactionWithId{
+		Id:     response.TransactionIds[1],
+		Amount: to.Int64Ptr(-131000),
+		Memo:   to.StringPtr("Corrected"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-131000), updatedTransaction.Amount)
+	assert.Equal(t, "Corrected", updatedTransaction.Memo)
+	assert.Equal(t, electricityCategoryId, updatedTransaction.CategoryId, "Expected the fields that are not set to be left as they are")
+
+	_, err = client.DeleteTransaction(context.Background(), budget.Id, response.TransactionIds[3])
+	assert.NoError(t, err)
+
+	changedTransactions, _, err := client.GetTransactions(context.Background(), budget.Id, TransactionsFilter{LastKnowledgeOfServer: serverKnowledge})
+	assert.NoError(t, err)
+	assert.Len(t, changedTransactions, 2, "Expected only the updated and deleted transactions to be fetched")
+	assert.True(t, changedTransactions[1].Deleted)
+
+	deletedTransaction, err := client.GetTransaction(context.Background(), budget.Id, response.TransactionIds[3])
+	assert.NoError(t, err)
+	assert.True(t, deletedTransaction.Deleted)
+
+	_, err = client.UpdateTransactions(context.Background(), budget.Id, []SaveTransactionWithId{{Id: "unknown", Approved: to.BoolPtr(true)}})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
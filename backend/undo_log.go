@@ -0,0 +1,350 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// UndoLogFileName is the name of the file, in the application directory, where the transactions created by the last import are persisted so that it can be undone
+const UndoLogFileName string = "undo_log.json"
+
+// ErrNothingToUndo is returned when undoing the last import while no import was recorded, or the last one was already undone
+var ErrNothingToUndo = errors.New("there is no import to undo")
+
+// ErrUndoRefused is returned when undoing the last import is refused because some of its transactions were reconciled or edited in YNAB since
+var ErrUndoRefused = errors.New("the import cannot be undone")
+
+// ImportedTransaction represents a YNAB transaction created by an import, as it was created, so that undoing the import can tell if it was edited since
+type ImportedTransaction struct {
+	Id              string                   `json:"id"`
+	Description     string                   `json:"description"`
+	Date            string                   `json:"date"`
+	Amount          int64                    `json:"amount"`
+	Memo            string                   `json:"memo"`
+	AccountId       string                   `json:"account_id"`
+	PayeeId         string                   `json:"payee_id"`
+	CategoryId      string                   `json:"category_id"`
+	SubTransactions []ImportedSubTransaction `json:"subtransactions"`
+}
+
+// ImportedSubTransaction represents a sub-transaction of a YNAB transaction created by an import, as it was created
+type ImportedSubTransaction struct {
+	Amount     int64  `json:"amount"`
+	Memo       string `json:"memo"`
+	CategoryId string `json:"category_id"`
+}
+
+// ImportedBudget represents the YNAB transactions an import created in a YNAB budget
+type ImportedBudget struct {
+	BudgetId        string                `json:"budget_id"`
+	ParticipantName string                `json:"participant_name"`
+	Transactions    []ImportedTransaction `json:"transactions"`
+}
+
+// ImportRecord represents the YNAB transactions created by an import of the monthly expenses of a month, in each YNAB budget
+// Transactions that were already present before the import are not part of it, so undoing the import never deletes them
+type ImportRecord struct {
+	Month      string           `json:"month"`
+	ImportedAt time.Time        `json:"imported_at"`
+	Budgets    []ImportedBudget `json:"budgets"`
+}
+
+// UndoResult represents the outcome of undoing an import, with the transactions deleted and left behind in each YNAB budget
+// Undoing is refused, without deleting anything, if any of the transactions was reconciled or edited in YNAB since, and the reasons are listed as problems
+type UndoResult struct {
+	Success  bool                 `json:"success"`
+	Error    string               `json:"error"`
+	Month    string               `json:"month"`
+	Refused  bool                 `json:"refused"`
+	Problems []string             `json:"problems"`
+	Budgets  []BudgetImportResult `json:"budgets"`
+}
+
+// UndoLog represents the persisted record of the transactions created by the last import, until it is undone or another import replaces it
+type UndoLog struct {
+	Path       string        `json:"-"`
+	LastImport *ImportRecord `json:"last_import"`
+	mutex      sync.Mutex
+}
+
+// UndoLogPath returns the location of the undo log file
+func UndoLogPath() (string, error) {
+	applicationDirectory, err := ApplicationDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(applicationDirectory, UndoLogFileName), nil
+}
+
+// LoadUndoLog reads the undo log persisted at the given location, returning an empty undo log if it does not exist yet
+func LoadUndoLog(undoLogPath string) (*UndoLog, error) {
+	undoLog := &UndoLog{Path: undoLogPath}
+
+	data, err := os.ReadFile(undoLogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return undoLog, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading undo log: %w", err)
+	}
+
+	if err = json.Unmarshal(data, undoLog); err != nil {
+		return nil, fmt.Errorf("decoding undo log %s: %w", undoLogPath, err)
+	}
+
+	return undoLog, nil
+}
+
+// Record replaces the last import with the transactions created by a successful import of a month, and persists it
+// An import that created no transactions, e.g. because they were all already present, leaves the last import as it was
+func (undoLog *UndoLog) Record(month string, importResult ImportResult) error {
+	undoLog.mutex.Lock()
+	defer undoLog.mutex.Unlock()
+
+	importRecord := &ImportRecord{Month: month, ImportedAt: time.Now(), Budgets: []ImportedBudget{}}
+	createdTransactionsCount := 0
+
+	for _, budgetImportResult := range importResult.Budgets {
+		importRecord.Budgets = append(importRecord.Budgets, ImportedBudget{
+			BudgetId:        budgetImportResult.BudgetId,
+			ParticipantName: budgetImportResult.ParticipantName,
+			Transactions:    slices.Clone(budgetImportResult.importedTransactions),
+		})
+		createdTransactionsCount += len(budgetImportResult.importedTransactions)
+	}
+
+	if createdTransactionsCount == 0 {
+		return nil
+	}
+
+	undoLog.LastImport = importRecord
+
+	return undoLog.save()
+}
+
+// GetLastImport returns the transactions created by the last import, or nil if there is no import to undo
+func (undoLog *UndoLog) GetLastImport() *ImportRecord {
+	undoLog.mutex.Lock()
+	defer undoLog.mutex.Unlock()
+
+	if undoLog.LastImport == nil {
+		return nil
+	}
+
+	lastImport := *undoLog.LastImport
+	lastImport.Budgets = slices.Clone(lastImport.Budgets)
+
+	return &lastImport
+}
+
+// Forget removes the given transactions from the last import, once they were deleted from YNAB, and persists the undo log
+// Once every transaction of the last import is forgotten, there is no import to undo anymore
+func (undoLog *UndoLog) Forget(transactionIds []string) error {
+	undoLog.mutex.Lock()
+	defer undoLog.mutex.Unlock()
+
+	if undoLog.LastImport == nil {
+		return nil
+	}
+
+	remainingTransactionsCount := 0
+	budgets := make([]ImportedBudget, 0, len(undoLog.LastImport.Budgets))
+
+	for _, importedBudget := range undoLog.LastImport.Budgets {
+		remainingTransactions := []ImportedTransaction{}
+		for _, importedTransaction := range importedBudget.Transactions {
+			if !slices.Contains(transactionIds, importedTransaction.Id) {
+				remainingTransactions = append(remainingTransactions, importedTransaction)
+			}
+		}
+
+		importedBudget.Transactions = remainingTransactions
+		budgets = append(budgets, importedBudget)
+		remainingTransactionsCount += len(remainingTransactions)
+	}
+
+	undoLog.LastImport.Budgets = budgets
+	if remainingTransactionsCount == 0 {
+		undoLog.LastImport = nil
+	}
+
+	return undoLog.save()
+}
+
+// save persists the undo log
+func (undoLog *UndoLog) save() error {
+	data, err := json.MarshalIndent(undoLog, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(undoLog.Path), 0700); err != nil {
+		return fmt.Errorf("creating undo log directory: %w", err)
+	}
+
+	if err = writeFileAtomically(undoLog.Path, data, 0600); err != nil {
+		return fmt.Errorf("writing undo log: %w", err)
+	}
+
+	return nil
+}
+
+// Undo deletes the transactions created by the import from every YNAB budget, in the reverse order they were created
+// Every transaction is checked first, and undoing is refused without deleting anything if any was reconciled or edited in YNAB since
+// Transactions YNAB no longer has, e.g. because they were deleted by hand meanwhile, are as good as deleted
+func (importRecord *ImportRecord) Undo(ctx context.Context, budgetService BudgetService) (UndoResult, error) {
+	undoResult := UndoResult{Month: importRecord.Month, Problems: []string{}, Budgets: []BudgetImportResult{}}
+	importResult := ImportResult{Budgets: []BudgetImportResult{}}
+
+	for _, importedBudget := range importRecord.Budgets {
+		budgetImportResult := BudgetImportResult{
+			BudgetId:                 importedBudget.BudgetId,
+			ParticipantName:          importedBudget.ParticipantName,
+			Status:                   BudgetImportStatusCreated,
+			CreatedTransactionIds:    []string{},
+			RolledBackTransactionIds: []string{},
+			DuplicateImportIds:       []string{},
+			AlreadyPresent:           []string{},
+			ReplacedTransactionIds:   []string{},
+		}
+
+		for _, importedTransaction := range importedBudget.Transactions {
+			transaction, err := budgetService.GetTransaction(ctx, importedBudget.BudgetId, importedTransaction.Id)
+			if errors.Is(err, ErrNotFound) || (err == nil && transaction.Deleted) {
+				budgetImportResult.RolledBackTransactionIds = append(budgetImportResult.RolledBackTransactionIds, importedTransaction.Id)
+				continue
+			}
+			if err != nil {
+				err = fmt.Errorf("checking transaction %s in budget %s: %w", importedTransaction.Id, importedBudget.BudgetId, err)
+				undoResult.Error = err.Error()
+				return undoResult, err
+			}
+
+			if problem := importedTransaction.getUndoProblem(transaction); problem != "" {
+				undoResult.Problems = append(undoResult.Problems, fmt.Sprintf("%s in %s %s", importedTransaction.Description, getBudgetOwner(importedBudget.ParticipantName), problem))
+			}
+			budgetImportResult.CreatedTransactionIds = append(budgetImportResult.CreatedTransactionIds, importedTransaction.Id)
+		}
+
+		if len(budgetImportResult.CreatedTransactionIds) == 0 {
+			budgetImportResult.Status = BudgetImportStatusRolledBack
+		}
+		importResult.Budgets = append(importResult.Budgets, budgetImportResult)
+	}
+
+	if len(undoResult.Problems) > 0 {
+		undoResult.Refused = true
+		undoResult.Error = fmt.Sprintf("%s: %s", ErrUndoRefused.Error(), strings.Join(undoResult.Problems, ", "))
+		return undoResult, ErrUndoRefused
+	}
+
+	err := importResult.rollback(ctx, budgetService)
+	undoResult.Budgets = importResult.Budgets
+	if err != nil {
+		undoResult.Error = err.Error()
+		return undoResult, err
+	}
+
+	undoResult.Success = true
+
+	return undoResult, nil
+}
+
+// GetDeletedTransactionIds returns the ids of the transactions no longer in YNAB after undoing an import, whether they were deleted by undoing it or before
+func (undoResult UndoResult) GetDeletedTransactionIds() []string {
+	var transactionIds []string
+	for _, budgetImportResult := range undoResult.Budgets {
+		transactionIds = append(transactionIds, budgetImportResult.RolledBackTransactionIds...)
+	}
+
+	return transactionIds
+}
+
+// getUndoProblem describes why the transaction created by an import cannot be deleted anymore, given the transaction as YNAB has it now, or returns an empty string if it can
+// Clearing or approving the transaction in YNAB is expected, but reconciling or editing it is not
+func (importedTransaction ImportedTransaction) getUndoProblem(transaction TransactionDetail) string {
+	if transaction.Cleared == "reconciled" {
+		return "was reconciled"
+	}
+
+	if importedTransaction.Date != transaction.Date ||
+		importedTransaction.Amount != transaction.Amount ||
+		importedTransaction.Memo != transaction.Memo ||
+		importedTransaction.AccountId != transaction.AccountId ||
+		importedTransaction.PayeeId != transaction.PayeeId ||
+		importedTransaction.CategoryId != transaction.CategoryId {
+		return "was edited"
+	}
+
+	subTransactions := []ImportedSubTransaction{}
+	for _, subTransaction := range transaction.SubTransactions {
+		if !subTransaction.Deleted {
+			subTransactions = append(subTransactions, ImportedSubTransaction{Amount: subTransaction.Amount, Memo: subTransaction.Memo, CategoryId: subTransaction.CategoryId})
+		}
+	}
+	// YNAB does not keep the order sub-transactions were created in, so only their content is compared
+	importedSubTransactions := slices.Clone(importedTransaction.SubTransactions)
+	sortSubTransactions(importedSubTransactions)
+	sortSubTransactions(subTransactions)
+	if !slices.Equal(importedSubTransactions, subTransactions) {
+		return "was edited"
+	}
+
+	return ""
+}
+
+// newImportedTransaction records a YNAB transaction as an import created it, described as in the import plan
+func newImportedTransaction(transaction TransactionDetail, description string) ImportedTransaction {
+	importedTransaction := ImportedTransaction{
+		Id:              transaction.Id,
+		Description:     description,
+		Date:            transaction.Date,
+		Amount:          transaction.Amount,
+		Memo:            transaction.Memo,
+		AccountId:       transaction.AccountId,
+		PayeeId:         transaction.PayeeId,
+		CategoryId:      transaction.CategoryId,
+		SubTransactions: []ImportedSubTransaction{},
+	}
+
+	for _, subTransaction := range transaction.SubTransactions {
+		if !subTransaction.Deleted {
+			importedTransaction.SubTransactions = append(importedTransaction.SubTransactions,
+				ImportedSubTransaction{Amount: subTransaction.Amount, Memo: subTransaction.Memo, CategoryId: subTransaction.CategoryId})
+		}
+	}
+
+	return importedTransaction
+}
+
+// sortSubTransactions sorts the sub-transactions of an imported transaction by category id, then amount, then memo
+func sortSubTransactions(subTransactions []ImportedSubTransaction) {
+	slices.SortFunc(subTransactions, func(a, b ImportedSubTransaction) bool {
+		if a.CategoryId != b.CategoryId {
+			return a.CategoryId < b.CategoryId
+		}
+		if a.Amount != b.Amount {
+			return a.Amount < b.Amount
+		}
+		return a.Memo < b.Memo
+	})
+}
+
+// getBudgetOwner describes a YNAB budget by the participant it belongs to, or as the shared budget
+func getBudgetOwner(participantName string) string {
+	if participantName == "" {
+		return "the shared budget"
+	}
+
+	return participantName + "'s budget"
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUndoProblem(t *testing.T) {
+	importedTransaction := ImportedTransaction{
+		Id:        "transaction-id",
+		Date:      "2024-02-05",
+		Amount:    -190760,
+		AccountId: "account-id",
+		PayeeId:   "payee-id",
+		SubTransactions: []ImportedSubTransaction{
+			{Amount: -130510, Memo: "Electricity", CategoryId: "electricity-category-id"},
+			{Amount: -60250, Memo: "Water", CategoryId: "water-category-id"},
+		},
+	}
+
+	testCases := map[string]struct {
+		cleared         string
+		subTransactions []SubTransaction
+		expectedProblem string
+	}{
+		"untouched transaction": {
+			subTransactions: []SubTransaction{
+				{Amount: -130510, Memo: "Electricity", CategoryId: "electricity-category-id"},
+				{Amount: -60250, Memo: "Water", CategoryId: "water-category-id"},
+			},
+		},
+		"sub-transactions in another order": {
+			subTransactions: []SubTransaction{
+				{Amount: -60250, Memo: "Water", CategoryId: "water-category-id"},
+				{Amount: -130510, Memo: "Electricity", CategoryId: "electricity-category-id"},
+			},
+		},
+		"deleted sub-transaction left behind": {
+			subTransactions: []SubTransaction{
+				{Amount: -60250, Memo: "Water", CategoryId: "water-category-id"},
+				{Amount: -1000, CategoryId: "water-category-id", Deleted: true},
+				{Amount: -130510, Memo: "Electricity", CategoryId: "electricity-category-id"},
+			},
+		},
+		"reconciled transaction": {
+			cleared: "reconciled",
+			subTransactions: []SubTransaction{
+				{Amount: -130510, Memo: "Electricity", CategoryId: "electricity-category-id"},
+				{Amount: -60250, Memo: "Water", CategoryId: "water-category-id"},
+			},
+			expectedProblem: "was reconciled",
+		},
+		"edited sub-transaction": {
+			subTransactions: []SubTransaction{
+				{Amount: -60250, Memo: "Water", CategoryId: "electricity-category-id"},
+				{Amount: -130510, Memo: "Electricity", CategoryId: "water-category-id"},
+			},
+			expectedProblem: "was edited",
+		},
+		"removed sub-transaction": {
+			subTransactions: []SubTransaction{
+				{Amount: -130510, Memo: "Electricity", CategoryId: "electricity-category-id"},
+			},
+			expectedProblem: "was edited",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			transaction := TransactionDetail{
+				TransactionSummary: TransactionSummary{
+					Id:        importedTransaction.Id,
+					Date:      importedTransaction.Date,
+					Amount:    importedTransaction.Amount,
+					AccountId: importedTransaction.AccountId,
+					PayeeId:   importedTransaction.PayeeId,
+					Cleared:   testCase.cleared,
+				},
+				SubTransactions: testCase.subTransactions,
+			}
+
+			assert.Equal(t, testCase.expectedProblem, importedTransaction.getUndoProblem(transaction))
+		})
+	}
+}
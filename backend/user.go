@@ -0,0 +1,30 @@
+package backend
+
+import "context"
+
+// User represents the YNAB user the access token belongs to
+// This struct corresponds to the data structure defined in the YNAB API documentation
+type User struct {
+	Id string `json:"id"`
+}
+
+// GetUser fetches the YNAB user the access token belongs to, which validates the access token
+// GET https://api.ynab.com/v1/user
+func (client *APIClient) GetUser(ctx context.Context) (User, error) {
+	userResponse := struct {
+		Data struct {
+			User User `json:"user"`
+		} `json:"data"`
+	}{}
+
+	response, err := client.Client.R().
+		SetContext(ctx).
+		SetResult(&userResponse).
+		Get("user")
+
+	if err = client.ValidateResponse(response, err); err != nil {
+		return User{}, err
+	}
+
+	return userResponse.Data.User, nil
+}
@@ -0,0 +1,606 @@
+// Package ynabtest provides an in-process fake of the YNAB API for tests, holding budgets, accounts, categories, payees and transactions in memory
+package ynabtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is the number of requests the fake YNAB API accepts before responding with 429, as the YNAB API does per hour
+const RateLimit int = 200
+
+// Account represents a YNAB account held by the fake YNAB API
+type Account struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	OnBudget  bool   `json:"on_budget"`
+	Closed    bool   `json:"closed"`
+	Deleted   bool   `json:"deleted"`
+	knowledge int64
+}
+
+// Category represents a YNAB category held by the fake YNAB API
+type Category struct {
+	Id                string `json:"id"`
+	CategoryGroupId   string `json:"category_group_id"`
+	CategoryGroupName string `json:"category_group_name"`
+	Name              string `json:"name"`
+	Hidden            bool   `json:"hidden"`
+	Deleted           bool   `json:"deleted"`
+	knowledge         int64
+}
+
+// Payee represents a YNAB payee held by the fake YNAB API
+type Payee struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Deleted   bool   `json:"deleted"`
+	knowledge int64
+}
+
+// SubTransaction represents a sub-transaction of a YNAB transaction held by the fake YNAB API
+type SubTransaction struct {
+	Id            string  `json:"id"`
+	TransactionId string  `json:"transaction_id"`
+	Amount        int64   `json:"amount"`
+	Memo          *string `json:"memo"`
+	PayeeId       *string `json:"payee_id"`
+	PayeeName     *string `json:"payee_name"`
+	CategoryId    *string `json:"category_id"`
+	Deleted       bool    `json:"deleted"`
+}
+
+// Transaction represents a YNAB transaction held by the fake YNAB API
+type Transaction struct {
+	Id              string           `json:"id"`
+	Date            string           `json:"date"`
+	Amount          int64            `json:"amount"`
+	Memo            *string          `json:"memo"`
+	Cleared         string           `json:"cleared"`
+	Approved        bool             `json:"approved"`
+	FlagColor       *string          `json:"flag_color"`
+	AccountId       string           `json:"account_id"`
+	AccountName     string           `json:"account_name"`
+	PayeeId         *string          `json:"payee_id"`
+	PayeeName       *string          `json:"payee_name"`
+	CategoryId      *string          `json:"category_id"`
+	ImportId        *string          `json:"import_id"`
+	Deleted         bool             `json:"deleted"`
+	SubTransactions []SubTransaction `json:"subtransactions"`
+	knowledge       int64
+}
+
+// Budget represents a YNAB budget held by the fake YNAB API, with its accounts, categories, payees and transactions
+type Budget struct {
+	Id             string
+	Name           string
+	LastModifiedOn time.Time
+	Accounts       []*Account
+	Categories     []*Category
+	Payees         []*Payee
+	Transactions   []*Transaction
+	server         *Server
+}
+
+// failure represents a response with an error status code the fake YNAB API gives instead of handling the matching requests
+type failure struct {
+	method     string
+	pathPrefix string
+	statusCode int
+	times      int
+}
+
+// Server represents the fake YNAB API, served over HTTP on a local address by an httptest.Server
+// Every request must be authenticated with its access token, and every change increments its server knowledge, so delta requests only get what changed
+type Server struct {
+	*httptest.Server
+	AccessToken     string
+	Budgets         []*Budget
+	Requests        []string
+	serverKnowledge int64
+	lastId          int
+	failures        []*failure
+	mutex           sync.Mutex
+}
+
+// NewServer starts a fake YNAB API accepting the given access token
+// The server is closed when the test ends
+func NewServer(t interface{ Cleanup(func()) }, accessToken string) *Server {
+	server := &Server{AccessToken: accessToken}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// BaseURL returns the base URL of the fake YNAB API, to use in place of https://api.ynab.com/v1
+func (server *Server) BaseURL() string {
+	return server.Server.URL + "/v1"
+}
+
+// AddBudget adds an empty YNAB budget
+func (server *Server) AddBudget(name string) *Budget {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	budget := &Budget{Id: server.nextId("budget"), Name: name, server: server}
+	budget.touch()
+	server.Budgets = append(server.Budgets, budget)
+
+	return budget
+}
+
+// AddAccount adds an open on-budget account to the budget and returns its id
+func (budget *Budget) AddAccount(name string) string {
+	budget.server.mutex.Lock()
+	defer budget.server.mutex.Unlock()
+
+	account := &Account{Id: budget.server.nextId("account"), Name: name, Type: "checking", OnBudget: true, knowledge: budget.touch()}
+	budget.Accounts = append(budget.Accounts, account)
+
+	return account.Id
+}
+
+// CloseAccount closes an account of the budget
+func (budget *Budget) CloseAccount(name string) {
+	budget.server.mutex.Lock()
+	defer budget.server.mutex.Unlock()
+
+	for _, account := range budget.Accounts {
+		if account.Name == name {
+			account.Closed = true
+			account.knowledge = budget.touch()
+		}
+	}
+}
+
+// AddCategory adds a category to a category group of the budget, creating the group if needed, and returns its id
+func (budget *Budget) AddCategory(categoryGroupName string, name string) string {
+	budget.server.mutex.Lock()
+	defer budget.server.mutex.Unlock()
+
+	categoryGroupId := ""
+	for _, category := range budget.Categories {
+		if category.CategoryGroupName == categoryGroupName {
+			categoryGroupId = category.CategoryGroupId
+		}
+	}
+	if categoryGroupId == "" {
+		categoryGroupId = budget.server.nextId("category-group")
+	}
+
+	category := &Category{
+		Id:                budget.server.nextId("category"),
+		CategoryGroupId:   categoryGroupId,
+		CategoryGroupName: categoryGroupName,
+		Name:              name,
+		knowledge:         budget.touch(),
+	}
+	budget.Categories = append(budget.Categories, category)
+
+	return category.Id
+}
+
+// DeleteCategory deletes a category of the budget
+func (budget *Budget) DeleteCategory(name string) {
+	budget.server.mutex.Lock()
+	defer budget.server.mutex.Unlock()
+
+	for _, category := range budget.Categories {
+		if category.Name == name {
+			category.Deleted = true
+			category.knowledge = budget.touch()
+		}
+	}
+}
+
+// GetTransactions returns the transactions of the budget which are not deleted
+func (budget *Budget) GetTransactions() []Transaction {
+	budget.server.mutex.Lock()
+	defer budget.server.mutex.Unlock()
+
+	var transactions []Transaction
+	for _, transaction := range budget.Transactions {
+		if !transaction.Deleted {
+			transactions = append(transactions, *transaction)
+		}
+	}
+
+	return transactions
+}
+
+// Fail makes the fake YNAB API respond with the given status code to the next requests with the given method and path prefix, relative to the base URL, e.g. "budgets/"
+func (server *Server) Fail(method string, pathPrefix string, statusCode int, times int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.failures = append(server.failures, &failure{method: method, pathPrefix: pathPrefix, statusCode: statusCode, times: times})
+}
+
+// GetRequests returns the method and path of every request handled so far, e.g. "GET budgets"
+func (server *Server) GetRequests() []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return append([]string{}, server.Requests...)
+}
+
+// touch marks the budget as modified, returning the new server knowledge
+func (budget *Budget) touch() int64 {
+	budget.server.serverKnowledge++
+	budget.LastModifiedOn = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(budget.server.serverKnowledge) * time.Second)
+
+	return budget.server.serverKnowledge
+}
+
+// nextId generates a unique id with the given prefix
+func (server *Server) nextId(prefix string) string {
+	server.lastId++
+
+	return fmt.Sprintf("%s-%d", prefix, server.lastId)
+}
+
+// handle authenticates, records and routes every request to the fake YNAB API
+func (server *Server) handle(writer http.ResponseWriter, request *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	path := strings.Trim(strings.TrimPrefix(request.URL.Path, "/v1"), "/")
+	server.Requests = append(server.Requests, request.Method+" "+path)
+
+	writer.Header().Set("X-Rate-Limit", fmt.Sprintf("%d/%d", len(server.Requests), RateLimit))
+
+	if request.Header.Get("Authorization") != "Bearer "+server.AccessToken {
+		writeError(writer, http.StatusUnauthorized, "401", "unauthorized", "Unauthorized")
+		return
+	}
+
+	if len(server.Requests) > RateLimit {
+		writeError(writer, http.StatusTooManyRequests, "429", "too_many_requests", "Too many requests")
+		return
+	}
+
+	for _, failure := range server.failures {
+		if failure.times > 0 && failure.method == request.Method && strings.HasPrefix(path, failure.pathPrefix) {
+			failure.times--
+			writeError(writer, failure.statusCode, strconv.Itoa(failure.statusCode), strings.ToLower(strings.ReplaceAll(http.StatusText(failure.statusCode), " ", "_")), http.StatusText(failure.statusCode))
+			return
+		}
+	}
+
+	segments := strings.Split(path, "/")
+
+	switch {
+	case path == "user" && request.Method == http.MethodGet:
+		writeData(writer, http.StatusOK, map[string]interface{}{"user": map[string]string{"id": "user"}})
+	case path == "budgets" && request.Method == http.MethodGet:
+		server.handleGetBudgets(writer)
+	case len(segments) >= 3 && segments[0] == "budgets":
+		budget := server.getBudget(segments[1])
+		if budget == nil {
+			writeError(writer, http.StatusNotFound, "404.2", "resource_not_found", "Resource not found")
+			return
+		}
+		budget.handle(writer, request, segments[2:])
+	default:
+		writeError(writer, http.StatusNotFound, "404.1", "not_found", "Not found")
+	}
+}
+
+// handleGetBudgets serves the budget summaries
+func (server *Server) handleGetBudgets(writer http.ResponseWriter) {
+	budgets := make([]map[string]interface{}, 0, len(server.Budgets))
+	for _, budget := range server.Budgets {
+		budgets = append(budgets, map[string]interface{}{
+			"id":               budget.Id,
+			"name":             budget.Name,
+			"last_modified_on": budget.LastModifiedOn.Format(time.RFC3339),
+			"currency_format": map[string]interface{}{
+				"iso_code": "EUR", "example_format": "123 456,78", "decimal_digits": 2, "decimal_separator": ",",
+				"symbol_first": false, "group_separator": " ", "currency_symbol": "€", "display_symbol": true,
+			},
+		})
+	}
+
+	writeData(writer, http.StatusOK, map[string]interface{}{"budgets": budgets})
+}
+
+// getBudget finds a budget by id
+func (server *Server) getBudget(budgetId string) *Budget {
+	for _, budget := range server.Budgets {
+		if budget.Id == budgetId {
+			return budget
+		}
+	}
+
+	return nil
+}
+
+// handle routes a request for a resource of the budget, given the path segments after the budget id
+func (budget *Budget) handle(writer http.ResponseWriter, request *http.Request, segments []string) {
+	lastKnowledgeOfServer, _ := strconv.ParseInt(request.URL.Query().Get("last_knowledge_of_server"), 10, 64)
+
+	switch {
+	case segments[0] == "accounts" && len(segments) == 1 && request.Method == http.MethodGet:
+		accounts := []*Account{}
+		for _, account := range budget.Accounts {
+			if account.knowledge > lastKnowledgeOfServer {
+				accounts = append(accounts, account)
+			}
+		}
+		writeData(writer, http.StatusOK, map[string]interface{}{"accounts": accounts, "server_knowledge": budget.server.serverKnowledge})
+	case segments[0] == "categories" && len(segments) == 1 && request.Method == http.MethodGet:
+		budget.handleGetCategories(writer, lastKnowledgeOfServer)
+	case segments[0] == "payees" && len(segments) == 1 && request.Method == http.MethodGet:
+		payees := []*Payee{}
+		for _, payee := range budget.Payees {
+			if payee.knowledge > lastKnowledgeOfServer {
+				payees = append(payees, payee)
+			}
+		}
+		writeData(writer, http.StatusOK, map[string]interface{}{"payees": payees, "server_knowledge": budget.server.serverKnowledge})
+	case segments[0] == "transactions" && len(segments) == 1 && request.Method == http.MethodGet:
+		budget.handleGetTransactions(writer, request, lastKnowledgeOfServer, "", "")
+	case segments[0] == "accounts" && len(segments) == 3 && segments[2] == "transactions" && request.Method == http.MethodGet:
+		budget.handleGetTransactions(writer, request, lastKnowledgeOfServer, segments[1], "")
+	case segments[0] == "categories" && len(segments) == 3 && segments[2] == "transactions" && request.Method == http.MethodGet:
+		budget.handleGetTransactions(writer, request, lastKnowledgeOfServer, "", segments[1])
+	case segments[0] == "transactions" && len(segments) == 1 && request.Method == http.MethodPost:
+		budget.handleCreateTransactions(writer, request)
+	case segments[0] == "transactions" && len(segments) == 1 && request.Method == http.MethodPatch:
+		budget.handleUpdateTransactions(writer, request)
+	case segments[0] == "transactions" && len(segments) == 2 && request.Method == http.MethodGet:
+		transaction := budget.getTransaction(segments[1])
+		if transaction == nil {
+			writeError(writer, http.StatusNotFound, "404.2", "resource_not_found", "Resource not found")
+			return
+		}
+		writeData(writer, http.StatusOK, map[string]interface{}{"transaction": transaction, "server_knowledge": budget.server.serverKnowledge})
+	case segments[0] == "transactions" && len(segments) == 2 && request.Method == http.MethodDelete:
+		transaction := budget.getTransaction(segments[1])
+		if transaction == nil {
+			writeError(writer, http.StatusNotFound, "404.2", "resource_not_found", "Resource not found")
+			return
+		}
+		transaction.Deleted = true
+		transaction.knowledge = budget.touch()
+		writeData(writer, http.StatusOK, map[string]interface{}{"transaction": transaction, "server_knowledge": budget.server.serverKnowledge})
+	default:
+		writeError(writer, http.StatusNotFound, "404.1", "not_found", "Not found")
+	}
+}
+
+// handleGetCategories serves the category groups with their categories changed since the given server knowledge
+func (budget *Budget) handleGetCategories(writer http.ResponseWriter, lastKnowledgeOfServer int64) {
+	categoryGroups := []map[string]interface{}{}
+	categoryGroupIndexes := make(map[string]int)
+
+	for _, category := range budget.Categories {
+		if category.knowledge <= lastKnowledgeOfServer {
+			continue
+		}
+
+		index, ok := categoryGroupIndexes[category.CategoryGroupId]
+		if !ok {
+			index = len(categoryGroups)
+			categoryGroupIndexes[category.CategoryGroupId] = index
+			categoryGroups = append(categoryGroups, map[string]interface{}{
+				"id":         category.CategoryGroupId,
+				"name":       category.CategoryGroupName,
+				"categories": []*Category{},
+			})
+		}
+
+		categoryGroups[index]["categories"] = append(categoryGroups[index]["categories"].([]*Category), category)
+	}
+
+	writeData(writer, http.StatusOK, map[string]interface{}{"category_groups": categoryGroups, "server_knowledge": budget.server.serverKnowledge})
+}
+
+// handleGetTransactions serves the transactions changed since the given server knowledge, optionally restricted to an account or a category, and filtered by the since_date and type query parameters
+// Deleted transactions are only served when fetching the changes since a server knowledge
+func (budget *Budget) handleGetTransactions(writer http.ResponseWriter, request *http.Request, lastKnowledgeOfServer int64, accountId string, categoryId string) {
+	sinceDate, transactionType := request.URL.Query().Get("since_date"), request.URL.Query().Get("type")
+
+	transactions := []*Transaction{}
+	for _, transaction := range budget.Transactions {
+		switch {
+		case transaction.knowledge <= lastKnowledgeOfServer:
+		case transaction.Deleted && lastKnowledgeOfServer == 0:
+		case sinceDate != "" && transaction.Date < sinceDate:
+		case transactionType == "unapproved" && transaction.Approved:
+		case transactionType == "uncategorized" && transaction.CategoryId != nil:
+		case accountId != "" && transaction.AccountId != accountId:
+		case categoryId != "" && (transaction.CategoryId == nil || *transaction.CategoryId != categoryId):
+		default:
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	writeData(writer, http.StatusOK, map[string]interface{}{"transactions": transactions, "server_knowledge": budget.server.serverKnowledge})
+}
+
+// handleUpdateTransactions updates the given fields of several transactions, leaving the fields that are not given as they are
+// None of them is updated if any does not exist
+func (budget *Budget) handleUpdateTransactions(writer http.ResponseWriter, request *http.Request) {
+	body := struct {
+		Transactions []map[string]json.RawMessage `json:"transactions"`
+	}{}
+
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "400", "bad_request", err.Error())
+		return
+	}
+
+	updatedTransactions := make([]*Transaction, 0, len(body.Transactions))
+	for _, fields := range body.Transactions {
+		var transactionId string
+		_ = json.Unmarshal(fields["id"], &transactionId)
+
+		transaction := budget.getTransaction(transactionId)
+		if transaction == nil || transaction.Deleted {
+			writeError(writer, http.StatusNotFound, "404.2", "resource_not_found", fmt.Sprintf("transaction %s does not exist", transactionId))
+			return
+		}
+		updatedTransactions = append(updatedTransactions, transaction)
+	}
+
+	transactionIds := []string{}
+	for index, transaction := range updatedTransactions {
+		fields := body.Transactions[index]
+		delete(fields, "id")
+
+		// Decoding the given fields onto the transaction only overwrites the fields that are given
+		encodedFields, _ := json.Marshal(fields)
+		if err := json.Unmarshal(encodedFields, transaction); err != nil {
+			writeError(writer, http.StatusBadRequest, "400", "bad_request", err.Error())
+			return
+		}
+
+		transaction.knowledge = budget.touch()
+		transactionIds = append(transactionIds, transaction.Id)
+	}
+
+	writeData(writer, http.StatusOK, map[string]interface{}{
+		"transaction_ids":  transactionIds,
+		"transactions":     updatedTransactions,
+		"server_knowledge": budget.server.serverKnowledge,
+	})
+}
+
+// handleCreateTransactions creates a single transaction or several transactions, skipping those whose import id already exists in their account
+func (budget *Budget) handleCreateTransactions(writer http.ResponseWriter, request *http.Request) {
+	body := struct {
+		Transaction  *Transaction  `json:"transaction"`
+		Transactions []Transaction `json:"transactions"`
+	}{}
+
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "400", "bad_request", err.Error())
+		return
+	}
+
+	transactions := body.Transactions
+	if body.Transaction != nil {
+		transactions = []Transaction{*body.Transaction}
+	}
+
+	createdTransactions := []*Transaction{}
+	transactionIds := []string{}
+	duplicateImportIds := []string{}
+
+	for _, transaction := range transactions {
+		account := budget.getAccount(transaction.AccountId)
+		if account == nil {
+			writeError(writer, http.StatusBadRequest, "400", "bad_request", fmt.Sprintf("account %s does not exist", transaction.AccountId))
+			return
+		}
+
+		if transaction.ImportId != nil && budget.hasImportId(transaction.AccountId, *transaction.ImportId) {
+			duplicateImportIds = append(duplicateImportIds, *transaction.ImportId)
+			continue
+		}
+
+		createdTransaction := transaction
+		createdTransaction.Id = budget.server.nextId("transaction")
+		createdTransaction.AccountName = account.Name
+		createdTransaction.knowledge = budget.touch()
+
+		if createdTransaction.PayeeName != nil && createdTransaction.PayeeId == nil {
+			payeeId := budget.getOrCreatePayee(*createdTransaction.PayeeName)
+			createdTransaction.PayeeId = &payeeId
+		}
+
+		for index := range createdTransaction.SubTransactions {
+			createdTransaction.SubTransactions[index].Id = budget.server.nextId("subtransaction")
+			createdTransaction.SubTransactions[index].TransactionId = createdTransaction.Id
+		}
+
+		budget.Transactions = append(budget.Transactions, &createdTransaction)
+		createdTransactions = append(createdTransactions, &createdTransaction)
+		transactionIds = append(transactionIds, createdTransaction.Id)
+	}
+
+	data := map[string]interface{}{
+		"transaction_ids":      transactionIds,
+		"duplicate_import_ids": duplicateImportIds,
+		"server_knowledge":     budget.server.serverKnowledge,
+	}
+	if body.Transaction != nil && len(createdTransactions) == 1 {
+		data["transaction"] = createdTransactions[0]
+	} else {
+		data["transactions"] = createdTransactions
+	}
+
+	writeData(writer, http.StatusCreated, data)
+}
+
+// getAccount finds an account of the budget by id
+func (budget *Budget) getAccount(accountId string) *Account {
+	for _, account := range budget.Accounts {
+		if account.Id == accountId && !account.Deleted {
+			return account
+		}
+	}
+
+	return nil
+}
+
+// getTransaction finds a transaction of the budget by id
+func (budget *Budget) getTransaction(transactionId string) *Transaction {
+	for _, transaction := range budget.Transactions {
+		if transaction.Id == transactionId {
+			return transaction
+		}
+	}
+
+	return nil
+}
+
+// hasImportId checks if a transaction of an account already has the import id
+// Like the YNAB API, a deleted transaction still holds its import id, so a transaction with the same import id is never created again
+func (budget *Budget) hasImportId(accountId string, importId string) bool {
+	for _, transaction := range budget.Transactions {
+		if transaction.AccountId == accountId && transaction.ImportId != nil && *transaction.ImportId == importId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getOrCreatePayee returns the id of the payee with the given name, creating it if needed
+func (budget *Budget) getOrCreatePayee(name string) string {
+	for _, payee := range budget.Payees {
+		if payee.Name == name {
+			return payee.Id
+		}
+	}
+
+	payee := &Payee{Id: budget.server.nextId("payee"), Name: name, knowledge: budget.server.serverKnowledge}
+	budget.Payees = append(budget.Payees, payee)
+
+	return payee.Id
+}
+
+// writeData writes a successful response wrapped in the YNAB data envelope
+func writeData(writer http.ResponseWriter, statusCode int, data interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(map[string]interface{}{"data": data})
+}
+
+// writeError writes an error response wrapped in the YNAB error envelope
+func writeError(writer http.ResponseWriter, statusCode int, id string, name string, detail string) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(map[string]interface{}{"error": map[string]string{"id": id, "name": name, "detail": detail}})
+}
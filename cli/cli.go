@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	backendpkg "ynab-monthly-expenses-manager/backend"
+)
+
+// Output formats supported by every command
+const (
+	OutputFormatText string = "text"
+	OutputFormatJSON string = "json"
+)
+
+// usage is printed when no command, an unknown command or the help command is given
+const usage string = `Usage: ynab-monthly-expenses-cli <command> [flags]
+
+Commands:
+  split     Split the shared monthly expenses among the participants
+  plan      Show the YNAB transactions that importing the monthly expenses would create
+  import    Import the monthly expenses of the current month into YNAB
+  undo      Delete the transactions created by the last import from YNAB, unless they were reconciled or edited since
+  history   Show the splits and imports recorded for previous months and the rounding of their shares
+  serve     Serve the local HTTP API, described at /openapi.yaml
+  login     Authorize a YNAB login with the OAuth application declared in the configuration
+  outbox    Show the imports queued while YNAB could not be reached, submit them now with -submit, or resolve their possible duplicates with -resolve
+  doctor    Check the configuration, the access token and the YNAB budgets, accounts and categories
+
+Amounts are given per category with repeated -amount flags, e.g. -amount "Water=60.25",
+or as a JSON object of amounts by category name with -input, read from a file or from stdin if "-".
+
+Run 'ynab-monthly-expenses-cli <command> -h' for the flags of each command.
+`
+
+// command represents a CLI subcommand, which writes its result to the given writer
+type command func(options *options, backend *backendpkg.Backend, stdout io.Writer) error
+
+// commands maps the name of each CLI subcommand to its implementation, whether it needs the monthly expenses amounts, whether it needs the YNAB API and whether it runs despite the backend not being set up
+var commands = map[string]struct {
+	run               command
+	needsAmounts      bool
+	needsYNAB         bool
+	ignoresSetupError bool
+}{
+	"split":   {run: runSplit, needsAmounts: true},
+	"plan":    {run: runPlan, needsAmounts: true},
+	"import":  {run: runImport, needsAmounts: true, needsYNAB: true},
+	"undo":    {run: runUndo, needsYNAB: true},
+	"history": {run: runHistory},
+	"serve":   {run: runServe},
+	"login":   {run: runLogin},
+	"outbox":  {run: runOutbox},
+	"doctor":  {run: runDoctor, ignoresSetupError: true},
+}
+
+// options represents the flags common to every CLI subcommand
+type options struct {
+	format     string
+	month      string
+	address    string
+	submit     bool
+	discard    string
+	resolve    string
+	duplicates string
+	amounts    map[string]decimal.Decimal
+}
+
+// amountFlags collects the repeated -amount flags as category name and amount pairs
+type amountFlags map[string]decimal.Decimal
+
+// String returns the amounts given so far, as required by flag.Value
+func (amounts amountFlags) String() string {
+	pairs := make([]string, 0, len(amounts))
+	for categoryName, amount := range amounts {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", categoryName, amount.String()))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Set parses an amount flag formatted as "<category>=<amount>"
+func (amounts amountFlags) Set(value string) error {
+	categoryName, amountValue, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("amount %q must be formatted as <category>=<amount>", value)
+	}
+
+	amount, err := decimal.NewFromString(strings.TrimSpace(amountValue))
+	if err != nil {
+		return fmt.Errorf("amount of category %q: %w", categoryName, err)
+	}
+
+	amounts[strings.TrimSpace(categoryName)] = amount
+
+	return nil
+}
+
+// Run runs the CLI with the given arguments, excluding the program name, and returns the process exit code
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	commandName := args[0]
+	cmd, ok := commands[commandName]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", commandName, usage)
+		return 2
+	}
+
+	options, err := parseOptions(commandName, args[1:], cmd.needsAmounts, stdin, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", commandName, err)
+		return 2
+	}
+
+	backend := backendpkg.SetupBackend()
+	if backend.SetupError != nil && !cmd.ignoresSetupError {
+		if cmd.needsYNAB || !isAccessTokenError(backend.SetupError) {
+			fmt.Fprintf(stderr, "%s: %v\n", commandName, backend.SetupError)
+			return 1
+		}
+		if commandName == "serve" {
+			fmt.Fprintf(stderr, "warning: %v, importing into YNAB is not available\n", backend.SetupError)
+		}
+	}
+
+	if err = cmd.run(options, backend, stdout); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", commandName, err)
+		return 1
+	}
+
+	return 0
+}
+
+// isAccessTokenError checks if the backend could not be set up only because the YNAB Personal Access Token is missing or locked, which commands not using the YNAB API can ignore
+func isAccessTokenError(err error) bool {
+	return errors.Is(err, backendpkg.ErrAccessTokenMissing) || errors.Is(err, backendpkg.ErrVaultLocked) || errors.Is(err, backendpkg.ErrVaultPassphrase)
+}
+
+// parseOptions parses the flags of a CLI subcommand, reading the monthly expenses amounts from the flags or the input file if the subcommand needs them
+func parseOptions(commandName string, args []string, needsAmounts bool, stdin io.Reader, stderr io.Writer) (*options, error) {
+	flags := flag.NewFlagSet(commandName, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	options := &options{}
+	amounts := amountFlags{}
+	var inputPath string
+
+	flags.StringVar(&options.format, "format", OutputFormatText, "output format, text or json")
+	if needsAmounts {
+		flags.Var(amounts, "amount", "amount of a category formatted as <category>=<amount>, may be repeated")
+		flags.StringVar(&inputPath, "input", "", "JSON file with the amounts by category name, or - to read it from stdin")
+	}
+	switch commandName {
+	case "import":
+		flags.StringVar(&options.duplicates, "duplicates", "", "how to resolve the transactions already entered in YNAB this month that the import would duplicate, skip, replace or proceed, nothing is imported if any is found when empty")
+	case "history":
+		flags.StringVar(&options.month, "month", "", "month formatted as YYYY-MM, every month if empty")
+	case "serve":
+		flags.StringVar(&options.address, "address", "", "address to listen on, overriding server.address in the configuration")
+	case "outbox":
+		flags.BoolVar(&options.submit, "submit", false, "submit the queued imports now")
+		flags.StringVar(&options.discard, "discard", "", "month formatted as YYYY-MM of the queued import to discard without submitting it")
+		flags.StringVar(&options.resolve, "resolve", "", "month formatted as YYYY-MM of the queued import whose possible duplicates to resolve with -duplicates, submitting it again")
+		flags.StringVar(&options.duplicates, "duplicates", "", "how to resolve the possible duplicates of the queued import given with -resolve, skip, replace or proceed")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %s", strings.Join(flags.Args(), " "))
+	}
+
+	if options.format != OutputFormatText && options.format != OutputFormatJSON {
+		return nil, fmt.Errorf("output format %q is not supported", options.format)
+	}
+
+	if !backendpkg.IsValidDuplicateResolution(options.duplicates) {
+		return nil, fmt.Errorf("duplicate resolution %q is not supported", options.duplicates)
+	}
+
+	switch {
+	case options.submit && options.discard != "":
+		return nil, errors.New("a queued import cannot be discarded while submitting the queued imports")
+	case options.resolve != "" && options.discard != "":
+		return nil, errors.New("a queued import cannot be discarded while resolving its possible duplicates")
+	case options.resolve != "" && options.duplicates == "":
+		return nil, errors.New("the possible duplicates must be resolved with -duplicates skip, replace or proceed")
+	}
+
+	if !needsAmounts {
+		return options, nil
+	}
+
+	if inputPath != "" && len(amounts) > 0 {
+		return nil, errors.New("amounts must be given either with -amount or with -input, not both")
+	}
+
+	if inputPath == "" {
+		if len(amounts) == 0 {
+			return nil, errors.New("no amounts given, use -amount or -input")
+		}
+		options.amounts = amounts
+		return options, nil
+	}
+
+	input := stdin
+	if inputPath != "-" {
+		file, err := os.Open(inputPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	inputAmounts, err := readAmounts(input)
+	if err != nil {
+		return nil, fmt.Errorf("reading amounts from %s: %w", inputPath, err)
+	}
+	options.amounts = inputAmounts
+
+	return options, nil
+}
+
+// readAmounts decodes a JSON object of amounts by category name, where amounts may be given either as numbers or as strings
+func readAmounts(input io.Reader) (map[string]decimal.Decimal, error) {
+	var amounts map[string]decimal.Decimal
+
+	if err := json.NewDecoder(input).Decode(&amounts); err != nil {
+		return nil, err
+	}
+
+	return amounts, nil
+}
+
+// writeJSON writes a value as indented JSON
+func writeJSON(stdout io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
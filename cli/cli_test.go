@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	backendpkg "ynab-monthly-expenses-manager/backend"
+)
+
+const demoConfig = `
+version: 1
+shared:
+  budget: "Casa"
+  account: "Millennium bcp"
+participants:
+  - name: "Magui"
+    budget: "Magui"
+    account: "CGD"
+  - name: "Jão"
+categories:
+  group: "Obligatory Monthly Expenses"
+  expenses:
+    - name: "Electricity"
+      payee: "EDP"
+    - name: "Water"
+      payee: "EPAL"
+`
+
+// setupDemoHousehold points the application directory to a temporary one, holding the given configuration, and makes the CLI import into a MemoryBudgetService seeded from it instead of YNAB
+func setupDemoHousehold(t *testing.T, config string) {
+	applicationDirectory := t.TempDir()
+	for _, variable := range []string{"HOME", "XDG_CONFIG_HOME", "AppData"} {
+		t.Setenv(variable, applicationDirectory)
+	}
+
+	configPath := filepath.Join(applicationDirectory, "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+
+	t.Setenv(backendpkg.ConfigPathEnvironmentVariable, configPath)
+	t.Setenv(backendpkg.DemoEnvironmentVariable, "1")
+}
+
+func TestParseOptions(t *testing.T) {
+	inputPath := filepath.Join(t.TempDir(), "amounts.json")
+	assert.NoError(t, os.WriteFile(inputPath, []byte(`{"Water": 60.25, "Electricity": "130.52"}`), 0600))
+
+	testCases := map[string]struct {
+		args            []string
+		stdin           string
+		expectedAmounts map[string]float64
+		expectedError   string
+	}{
+		"amount flags": {
+			args:            []string{"-amount", "Water=60.25", "-amount", "TV / Internet / Phone = 85.90"},
+			expectedAmounts: map[string]float64{"Water": 60.25, "TV / Internet / Phone": 85.90},
+		},
+		"input file": {
+			args:            []string{"-input", inputPath},
+			expectedAmounts: map[string]float64{"Water": 60.25, "Electricity": 130.52},
+		},
+		"stdin": {
+			args:            []string{"-input", "-", "-format", "json"},
+			stdin:           `{"Water": "60.25"}`,
+			expectedAmounts: map[string]float64{"Water": 60.25},
+		},
+		"no amounts": {
+			args:          []string{},
+			expectedError: "no amounts given",
+		},
+		"amount flags and input file": {
+			args:          []string{"-amount", "Water=60.25", "-input", inputPath},
+			expectedError: "not both",
+		},
+		"malformed amount flag": {
+			args:          []string{"-amount", "Water"},
+			expectedError: "must be formatted as <category>=<amount>",
+		},
+		"invalid amount": {
+			args:          []string{"-amount", "Water=sixty"},
+			expectedError: `amount of category "Water"`,
+		},
+		"malformed stdin": {
+			args:          []string{"-input", "-"},
+			stdin:         `["60.25"]`,
+			expectedError: "reading amounts from -",
+		},
+		"unsupported output format": {
+			args:          []string{"-amount", "Water=60.25", "-format", "yaml"},
+			expectedError: `output format "yaml" is not supported`,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			options, err := parseOptions("split", testCase.args, true, strings.NewReader(testCase.stdin), io.Discard)
+
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, options.amounts, len(testCase.expectedAmounts))
+			for categoryName, expectedAmount := range testCase.expectedAmounts {
+				assert.True(t, decimal.NewFromFloat(expectedAmount).Equal(options.amounts[categoryName]),
+					fmt.Sprintf("Expected amount of category '%s' to be %v, but got %s", categoryName, expectedAmount, options.amounts[categoryName].String()))
+			}
+		})
+	}
+}
+
+func TestRunWithoutCommand(t *testing.T) {
+	var stderr strings.Builder
+
+	assert.Equal(t, 2, Run([]string{}, strings.NewReader(""), io.Discard, &stderr))
+	assert.Contains(t, stderr.String(), "Usage:")
+
+	stderr.Reset()
+
+	assert.Equal(t, 2, Run([]string{"bogus"}, strings.NewReader(""), io.Discard, &stderr))
+	assert.Contains(t, stderr.String(), `unknown command "bogus"`)
+}
+
+func TestRunCommands(t *testing.T) {
+	month := time.Now().Format("2006-01")
+	amounts := []string{"-amount", "Electricity=130.51", "-amount", "Water=60.25"}
+	importCommand := append([]string{"import"}, amounts...)
+
+	testCases := map[string]struct {
+		config           string
+		before           [][]string
+		args             []string
+		expectedExitCode int
+		expectedOutput   []string
+		expectedError    string
+	}{
+		"split": {
+			args:           append([]string{"split"}, amounts...),
+			expectedOutput: []string{"Electricity  130.51  65.26  65.25", "Water   60.25  30.12  30.13", "Total  190.76  95.38  95.38"},
+		},
+		"split as JSON": {
+			args:           append([]string{"split", "-format", "json"}, amounts...),
+			expectedOutput: []string{`"payee_name": "EDP"`, `"amount": "130.51"`, `"amount": "65.26"`},
+		},
+		"split with a missing amount": {
+			args:             []string{"split", "-amount", "Electricity=130.51"},
+			expectedExitCode: 1,
+			expectedError:    `missing amount for category "Water"`,
+		},
+		"plan": {
+			args:           append([]string{"plan"}, amounts...),
+			expectedOutput: []string{"Import plan for " + month, "-130,51€", "Magui's budget", "Magui's share"},
+		},
+		"plan as JSON": {
+			args:           append([]string{"plan", "-format", "json"}, amounts...),
+			expectedOutput: []string{`"month": "` + month + `"`, `"formatted_amount": "-130,51€"`},
+		},
+		"import": {
+			args:           importCommand,
+			expectedOutput: []string{"created, 4 transactions created", "created, 1 transactions created", "Import succeeded"},
+		},
+		"import as JSON": {
+			args:           append([]string{"import", "-format", "json"}, amounts...),
+			expectedOutput: []string{`"success": true`, `"status": "created"`},
+		},
+		"undo": {
+			before:         [][]string{importCommand},
+			args:           []string{"undo"},
+			expectedOutput: []string{"4 transactions deleted", "1 transactions deleted", "Import of " + month + " undone"},
+		},
+		"undo as JSON": {
+			before:         [][]string{importCommand},
+			args:           []string{"undo", "-format", "json"},
+			expectedOutput: []string{`"success": true`, `"month": "` + month + `"`, `"status": "rolled_back"`},
+		},
+		"undo without an import": {
+			args:             []string{"undo"},
+			expectedExitCode: 1,
+			expectedError:    "there is no import to undo",
+		},
+		"history": {
+			before:         [][]string{importCommand},
+			args:           []string{"history"},
+			expectedOutput: []string{month, "succeeded, 5 transactions created", "Cumulative rounding in each participant's favour"},
+		},
+		"history as JSON": {
+			before:         [][]string{importCommand},
+			args:           []string{"history", "-month", month, "-format", "json"},
+			expectedOutput: []string{`"kind": "split"`, `"kind": "import"`, `"month": "` + month + `"`},
+		},
+		"outbox": {
+			args:           []string{"outbox"},
+			expectedOutput: []string{"No queued imports"},
+		},
+		"outbox as JSON": {
+			args:           []string{"outbox", "-format", "json"},
+			expectedOutput: []string{"[]"},
+		},
+		"outbox resolving an import that is not waiting for it": {
+			args:             []string{"outbox", "-resolve", "2000-01", "-duplicates", "skip"},
+			expectedExitCode: 1,
+			expectedError:    "no queued import is waiting for its possible duplicates to be resolved for 2000-01",
+		},
+		"outbox resolving without a duplicate resolution": {
+			args:             []string{"outbox", "-resolve", "2000-01"},
+			expectedExitCode: 2,
+			expectedError:    "the possible duplicates must be resolved with -duplicates",
+		},
+		"doctor": {
+			args:           []string{"doctor"},
+			expectedOutput: []string{"✔ Shared budget", "✔ Magui's categories", "Ready to import the monthly expenses"},
+		},
+		"doctor as JSON": {
+			args:           []string{"doctor", "-format", "json"},
+			expectedOutput: []string{`"ready": true`},
+		},
+		"doctor with an invalid configuration": {
+			config:           "version: 1\n",
+			args:             []string{"doctor"},
+			expectedExitCode: 1,
+			expectedOutput:   []string{"✘ Configuration", "shared.budget: is required"},
+			expectedError:    "the monthly expenses cannot be imported until the failed checks are fixed",
+		},
+		"doctor as JSON with an invalid configuration": {
+			config:           "version: 1\n",
+			args:             []string{"doctor", "-format", "json"},
+			expectedExitCode: 1,
+			expectedOutput:   []string{`"ready": false`, `"status": "fail"`},
+			expectedError:    "the monthly expenses cannot be imported until the failed checks are fixed",
+		},
+	}
+
+	for testName, testCase := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			config := testCase.config
+			if config == "" {
+				config = demoConfig
+			}
+			setupDemoHousehold(t, config)
+
+			for _, args := range testCase.before {
+				assert.Equal(t, 0, Run(args, strings.NewReader(""), io.Discard, io.Discard), "Expected %s to succeed", args[0])
+			}
+
+			var stdout, stderr strings.Builder
+			exitCode := Run(testCase.args, strings.NewReader(""), &stdout, &stderr)
+
+			assert.Equal(t, testCase.expectedExitCode, exitCode, stderr.String())
+			for _, expectedOutput := range testCase.expectedOutput {
+				assert.Contains(t, stdout.String(), expectedOutput)
+			}
+			if testCase.expectedError != "" {
+				assert.Contains(t, stderr.String(), testCase.expectedError)
+			}
+			if strings.Contains(strings.Join(testCase.args, " "), "-format json") {
+				assert.True(t, json.Valid([]byte(stdout.String())), "Expected the output to be valid JSON, got %s", stdout.String())
+			}
+		})
+	}
+}
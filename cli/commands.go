@@ -0,0 +1,584 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"ynab-monthly-expenses-manager/api"
+	backendpkg "ynab-monthly-expenses-manager/backend"
+)
+
+// runSplit splits the shared monthly expenses and prints the individual share of each participant for each category
+func runSplit(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	combinedMonthlyExpenses, err := backend.SplitMonthlyExpenses(options.amounts)
+	if err != nil {
+		return err
+	}
+
+	if options.format == OutputFormatJSON {
+		return writeJSON(stdout, combinedMonthlyExpenses)
+	}
+
+	return writeSplit(stdout, backend.GetCategoryNames(), combinedMonthlyExpenses)
+}
+
+// runPlan splits the shared monthly expenses and prints the YNAB transactions that importing them would create, without sending anything to YNAB
+func runPlan(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	combinedMonthlyExpenses, err := backend.SplitMonthlyExpenses(options.amounts)
+	if err != nil {
+		return err
+	}
+
+	importPlan, err := backend.GetImportPlan(combinedMonthlyExpenses)
+	if err != nil {
+		return err
+	}
+
+	if options.format == OutputFormatJSON {
+		return writeJSON(stdout, importPlan)
+	}
+
+	return writeImportPlan(stdout, importPlan)
+}
+
+// runImport splits the shared monthly expenses and imports them into YNAB, printing what happened in each YNAB budget
+func runImport(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	if !backend.IsSetupValid() {
+		return errors.New("the YNAB budgets, accounts or categories declared in the configuration could not be found")
+	}
+
+	combinedMonthlyExpenses, err := backend.SplitMonthlyExpenses(options.amounts)
+	if err != nil {
+		return err
+	}
+
+	stopCancelingOnInterrupt := cancelOnInterrupt(backend)
+	importResult := backend.CreateMonthlyExpensesTransactions(combinedMonthlyExpenses, options.duplicates)
+	stopCancelingOnInterrupt()
+
+	if options.format == OutputFormatJSON {
+		err = writeJSON(stdout, importResult)
+	} else {
+		err = writeImportResult(stdout, importResult)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !importResult.Success && !importResult.Queued {
+		return errors.New(importResult.Error)
+	}
+
+	return nil
+}
+
+// runUndo deletes the transactions created by the last import from YNAB, printing what happened in each YNAB budget, or why undoing the import was refused
+func runUndo(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	stopCancelingOnInterrupt := cancelOnInterrupt(backend)
+	undoResult := backend.UndoLastImport()
+	stopCancelingOnInterrupt()
+
+	var err error
+	if options.format == OutputFormatJSON {
+		err = writeJSON(stdout, undoResult)
+	} else {
+		err = writeUndoResult(stdout, undoResult)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !undoResult.Success {
+		return errors.New(undoResult.Error)
+	}
+
+	return nil
+}
+
+// runHistory prints the splits and imports recorded in the history, the rounding recorded in the rounding ledger for previous imports and the cumulative rounding of each participant
+func runHistory(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	records, err := backend.GetHistory(options.month)
+	if err != nil {
+		return err
+	}
+
+	entries := backend.GetRoundingLedgerEntries(options.month)
+	balances := backend.GetRoundingBalances()
+
+	if options.format == OutputFormatJSON {
+		return writeJSON(stdout, struct {
+			Records  []backendpkg.HistoryRecord       `json:"records"`
+			Entries  []backendpkg.RoundingLedgerEntry `json:"entries"`
+			Balances map[string]decimal.Decimal       `json:"balances"`
+		}{
+			Records:  records,
+			Entries:  entries,
+			Balances: balances,
+		})
+	}
+
+	if err = writeHistoryRecords(stdout, backend.GetCategoryNames(), records); err != nil {
+		return err
+	}
+
+	return writeHistory(stdout, backend.GetParticipantNames(), entries, balances)
+}
+
+// runOutbox prints the imports queued while YNAB could not be reached, after submitting them, resolving the possible duplicates of one of them and submitting them or discarding one of them if asked to
+func runOutbox(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	queuedImports := backend.GetQueuedImports()
+
+	switch {
+	case options.submit || options.resolve != "":
+		if backend.SetupError != nil {
+			return backend.SetupError
+		}
+		if options.resolve != "" {
+			if err := backend.ResolveQueuedImport(options.resolve, options.duplicates); err != nil {
+				return err
+			}
+		}
+		stopCancelingOnInterrupt := cancelOnInterrupt(backend)
+		queuedImports = backend.SubmitQueuedImports()
+		stopCancelingOnInterrupt()
+	case options.discard != "":
+		if err := backend.DiscardQueuedImport(options.discard); err != nil {
+			return err
+		}
+		queuedImports = backend.GetQueuedImports()
+	}
+
+	if options.format == OutputFormatJSON {
+		return writeJSON(stdout, queuedImports)
+	}
+
+	return writeOutbox(stdout, queuedImports)
+}
+
+// runServe serves the local HTTP API until it fails, on the address given by flag or declared in the configuration
+// The imports queued in the outbox are submitted in the background meanwhile
+func runServe(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	server, err := api.NewServer(backend, backend.Config.GetAPIKey())
+	if err != nil {
+		return err
+	}
+
+	address := options.address
+	if address == "" {
+		address = backend.Config.GetServerAddress()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go backend.RunOutbox(ctx)
+
+	fmt.Fprintf(stdout, "Serving the API on http://%s, described at http://%s/openapi.yaml\n", address, address)
+
+	return server.ListenAndServe(address)
+}
+
+// runLogin authorizes a YNAB login with OAuth, printing the authorization URL to open in a browser on the same machine, and saves its tokens
+func runLogin(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	switch {
+	case backend.Config.OAuth.ClientId == "":
+		return errors.New("no YNAB OAuth application is declared in the configuration")
+	case backend.AccessTokenStore == nil:
+		return backendpkg.ErrNoAccessTokenStore
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backendpkg.OAuthAuthorizationTimeout)
+	defer cancel()
+
+	token, err := backendpkg.NewOAuthClient(backend.Config.OAuth).Authorize(ctx, func(authorizationURL string) error {
+		_, err := fmt.Fprintf(stdout, "Open this URL in a browser on this machine to authorize your YNAB login:\n\n  %s\n\n", authorizationURL)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = backendpkg.SaveOAuthToken(backend.AccessTokenStore.OAuthTokenFilePath, token); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "YNAB login authorized")
+
+	return nil
+}
+
+// runDoctor prints every check run while setting up the backend, with what to do about the failed ones, and fails if the monthly expenses cannot be imported
+func runDoctor(options *options, backend *backendpkg.Backend, stdout io.Writer) error {
+	diagnostics := backend.GetDiagnostics()
+
+	var err error
+	if options.format == OutputFormatJSON {
+		err = writeJSON(stdout, diagnostics)
+	} else {
+		err = writeDiagnostics(stdout, diagnostics, backend.GetRateLimitStatus())
+	}
+	if err != nil {
+		return err
+	}
+
+	if !diagnostics.Ready {
+		return errors.New("the monthly expenses cannot be imported until the failed checks are fixed")
+	}
+
+	return nil
+}
+
+// writeSplit writes a table with the shared amount and the individual share of each participant for each category
+func writeSplit(stdout io.Writer, categoryNames []string, combinedMonthlyExpenses *backendpkg.CombinedMonthlyExpenses) error {
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	header := []string{"Category", "Total"}
+	for _, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+		header = append(header, individualMonthlyExpenses.ParticipantName)
+	}
+	fmt.Fprintln(table, strings.Join(header, "\t")+"\t")
+
+	totals := make([]decimal.Decimal, len(header)-1)
+
+	for _, categoryName := range categoryNames {
+		row := []string{categoryName}
+		amounts := []decimal.Decimal{combinedMonthlyExpenses.SharedMonthlyExpenses.Expenses[categoryName].Amount}
+		for _, individualMonthlyExpenses := range combinedMonthlyExpenses.IndividualMonthlyExpenses {
+			amounts = append(amounts, individualMonthlyExpenses.Expenses[categoryName].Amount)
+		}
+
+		for amountIndex, amount := range amounts {
+			totals[amountIndex] = totals[amountIndex].Add(amount)
+			row = append(row, amount.StringFixed(2))
+		}
+		fmt.Fprintln(table, strings.Join(row, "\t")+"\t")
+	}
+
+	row := []string{"Total"}
+	for _, total := range totals {
+		row = append(row, total.StringFixed(2))
+	}
+	fmt.Fprintln(table, strings.Join(row, "\t")+"\t")
+
+	return table.Flush()
+}
+
+// writeImportPlan writes the YNAB transactions planned for each YNAB budget, with their sub-transactions indented below them
+func writeImportPlan(stdout io.Writer, importPlan backendpkg.ImportPlan) error {
+	fmt.Fprintf(stdout, "Import plan for %s\n", importPlan.Month)
+
+	for _, budgetImportPlan := range importPlan.Budgets {
+		fmt.Fprintf(stdout, "\n%s\n", getBudgetTitle(budgetImportPlan.BudgetId, budgetImportPlan.ParticipantName))
+
+		table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+		for _, plannedTransaction := range budgetImportPlan.Transactions {
+			transaction := plannedTransaction.Transaction
+
+			payeeName := ""
+			if transaction.PayeeName != nil {
+				payeeName = *transaction.PayeeName
+			}
+
+			fmt.Fprintf(table, "  %s\t%s\t%s\t%d\n", plannedTransaction.Description, payeeName, plannedTransaction.FormattedAmount, transaction.Amount)
+
+			for subTransactionIndex, subTransaction := range transaction.SubTransactions {
+				fmt.Fprintf(table, "    %s\t\t%s\t%d\n",
+					plannedTransaction.SubTransactionDescriptions[subTransactionIndex],
+					plannedTransaction.FormattedSubTransactionAmounts[subTransactionIndex],
+					subTransaction.Amount)
+			}
+		}
+
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeImportResult writes the outcome of the import in each YNAB budget, or the transactions already entered in YNAB that it would duplicate
+func writeImportResult(stdout io.Writer, importResult backendpkg.ImportResult) error {
+	if len(importResult.PossibleDuplicates) > 0 {
+		return writePossibleDuplicates(stdout, importResult.PossibleDuplicates)
+	}
+
+	for _, budgetImportResult := range importResult.Budgets {
+		fmt.Fprintf(stdout, "%s: %s, %d transactions created\n",
+			getBudgetTitle(budgetImportResult.BudgetId, budgetImportResult.ParticipantName),
+			strings.ReplaceAll(budgetImportResult.Status, "_", " "),
+			len(budgetImportResult.CreatedTransactionIds))
+
+		if len(budgetImportResult.RolledBackTransactionIds) > 0 {
+			fmt.Fprintf(stdout, "  %d transactions rolled back\n", len(budgetImportResult.RolledBackTransactionIds))
+		}
+		if len(budgetImportResult.AlreadyPresent) > 0 {
+			fmt.Fprintf(stdout, "  already in YNAB: %s\n", strings.Join(budgetImportResult.AlreadyPresent, ", "))
+		}
+		if len(budgetImportResult.ReplacedTransactionIds) > 0 {
+			fmt.Fprintf(stdout, "  %d existing transactions replaced\n", len(budgetImportResult.ReplacedTransactionIds))
+		}
+		if budgetImportResult.Error != "" {
+			fmt.Fprintf(stdout, "  error: %s\n", budgetImportResult.Error)
+		}
+	}
+
+	if importResult.Success {
+		fmt.Fprintln(stdout, "Import succeeded")
+	} else if importResult.Queued {
+		fmt.Fprintln(stdout, "YNAB could not be reached, the import was queued and will be submitted by 'outbox -submit' or while serving the API")
+	} else if importResult.Canceled {
+		fmt.Fprintln(stdout, "Import canceled")
+	} else if importResult.RolledBack {
+		fmt.Fprintln(stdout, "Import failed, no transactions were left in YNAB")
+	} else {
+		fmt.Fprintln(stdout, "Import failed")
+	}
+
+	return nil
+}
+
+// writePossibleDuplicates writes the transactions already entered in YNAB this month that each planned transaction would duplicate, and how to go on with the import
+func writePossibleDuplicates(stdout io.Writer, possibleDuplicates []backendpkg.PossibleDuplicate) error {
+	fmt.Fprintln(stdout, "This month's expenses appear to be already entered in YNAB:")
+
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, possibleDuplicate := range possibleDuplicates {
+		for transactionIndex, transaction := range possibleDuplicate.ExistingTransactions {
+			fmt.Fprintf(table, "  %s\t%s\t%s\t%s\t%s\n",
+				getBudgetTitle(possibleDuplicate.BudgetId, possibleDuplicate.ParticipantName),
+				possibleDuplicate.Description,
+				transaction.Date,
+				transaction.PayeeName,
+				possibleDuplicate.FormattedAmounts[transactionIndex])
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "Nothing was imported, run the import again with -duplicates skip, replace or proceed")
+
+	return nil
+}
+
+// writeUndoResult writes the transactions deleted and left behind in each YNAB budget by undoing the last import, or why undoing it was refused
+func writeUndoResult(stdout io.Writer, undoResult backendpkg.UndoResult) error {
+	// Without a month, there was no import to undo, or YNAB could not be reached, and the error says so
+	if undoResult.Month == "" {
+		return nil
+	}
+
+	if undoResult.Refused {
+		fmt.Fprintf(stdout, "The import of %s cannot be undone, as some of its transactions were changed in YNAB since:\n", undoResult.Month)
+		for _, problem := range undoResult.Problems {
+			fmt.Fprintf(stdout, "  %s\n", problem)
+		}
+		return nil
+	}
+
+	for _, budgetImportResult := range undoResult.Budgets {
+		fmt.Fprintf(stdout, "%s: %d transactions deleted\n",
+			getBudgetTitle(budgetImportResult.BudgetId, budgetImportResult.ParticipantName),
+			len(budgetImportResult.RolledBackTransactionIds))
+
+		if len(budgetImportResult.CreatedTransactionIds) > 0 {
+			fmt.Fprintf(stdout, "  %d transactions left in YNAB\n", len(budgetImportResult.CreatedTransactionIds))
+		}
+		if budgetImportResult.Error != "" {
+			fmt.Fprintf(stdout, "  error: %s\n", budgetImportResult.Error)
+		}
+	}
+
+	if undoResult.Success {
+		fmt.Fprintf(stdout, "Import of %s undone\n", undoResult.Month)
+	} else {
+		fmt.Fprintln(stdout, "Undoing the import failed")
+	}
+
+	return nil
+}
+
+// writeHistory writes the recorded rounding of each month, category and participant, followed by the cumulative rounding of each participant
+func writeHistory(stdout io.Writer, participantNames []string, entries []backendpkg.RoundingLedgerEntry, balances map[string]decimal.Decimal) error {
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "Month\tCategory\tParticipant\tExact share\tRounded share\tIn their favour")
+	for _, entry := range entries {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Month, entry.CategoryName, entry.ParticipantName, entry.ExactShare.String(), entry.RoundedShare.StringFixed(2), entry.Amount.String())
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	otherParticipantNames := maps.Keys(balances)
+	slices.Sort(otherParticipantNames)
+
+	balanceParticipantNames := slices.Clone(participantNames)
+	for _, participantName := range otherParticipantNames {
+		if !slices.Contains(balanceParticipantNames, participantName) {
+			balanceParticipantNames = append(balanceParticipantNames, participantName)
+		}
+	}
+
+	fmt.Fprintln(stdout, "\nCumulative rounding in each participant's favour")
+	for _, participantName := range balanceParticipantNames {
+		fmt.Fprintf(stdout, "  %s: %s\n", participantName, balances[participantName].String())
+	}
+
+	return nil
+}
+
+// writeHistoryRecords writes when each split and import was recorded, the amount of each category and, for imports, what happened in YNAB
+func writeHistoryRecords(stdout io.Writer, categoryNames []string, records []backendpkg.HistoryRecord) error {
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "Month\tRecorded at\tKind\tAmounts\tOutcome\tVersion")
+	for _, record := range records {
+		recordCategoryNames := maps.Keys(record.Amounts)
+		slices.SortStableFunc(recordCategoryNames, func(categoryName string, otherCategoryName string) bool {
+			categoryIndex, otherCategoryIndex := slices.Index(categoryNames, categoryName), slices.Index(categoryNames, otherCategoryName)
+			if categoryIndex == otherCategoryIndex {
+				return categoryName < otherCategoryName
+			}
+			// Categories no longer declared in the configuration go last
+			return uint(categoryIndex) < uint(otherCategoryIndex)
+		})
+
+		amounts := make([]string, 0, len(recordCategoryNames))
+		for _, categoryName := range recordCategoryNames {
+			amounts = append(amounts, fmt.Sprintf("%s=%s", categoryName, record.Amounts[categoryName].StringFixed(2)))
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Month, record.RecordedAt.Local().Format("2006-01-02 15:04"), record.Kind, strings.Join(amounts, ", "), getHistoryRecordOutcome(record), record.AppVersion)
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout)
+
+	return nil
+}
+
+// getHistoryRecordOutcome describes what happened in YNAB when importing, e.g. "succeeded, 5 transactions created", or nothing for a split
+func getHistoryRecordOutcome(record backendpkg.HistoryRecord) string {
+	importResult := record.ImportResult
+	if importResult == nil {
+		return ""
+	}
+
+	createdTransactionsCount := 0
+	for _, budgetImportResult := range importResult.Budgets {
+		createdTransactionsCount += len(budgetImportResult.CreatedTransactionIds)
+	}
+
+	switch {
+	case importResult.Success:
+		return fmt.Sprintf("succeeded, %d transactions created", createdTransactionsCount)
+	case importResult.Queued:
+		return "queued"
+	case importResult.Canceled:
+		return "canceled"
+	default:
+		return "failed"
+	}
+}
+
+// writeDiagnostics writes the status of each startup check, followed by what to do about it if it failed, and the YNAB API requests left within the rate limit
+func writeDiagnostics(stdout io.Writer, diagnostics *backendpkg.Diagnostics, rateLimitStatus backendpkg.RateLimitStatus) error {
+	symbols := map[string]string{
+		backendpkg.DiagnosticStatusPass:    "✔",
+		backendpkg.DiagnosticStatusWarning: "!",
+		backendpkg.DiagnosticStatusFail:    "✘",
+		backendpkg.DiagnosticStatusSkipped: "-",
+	}
+
+	for _, check := range diagnostics.Checks {
+		fmt.Fprintf(stdout, "%s %s: %s\n", symbols[check.Status], check.Name, check.Message)
+		if check.Remediation != "" {
+			fmt.Fprintf(stdout, "    %s\n", check.Remediation)
+		}
+	}
+
+	fmt.Fprintf(stdout, "\nYNAB API requests left this hour: %d of %d\n", rateLimitStatus.Remaining, rateLimitStatus.Limit)
+
+	if diagnostics.Ready {
+		fmt.Fprintln(stdout, "Ready to import the monthly expenses")
+	}
+
+	return nil
+}
+
+// writeOutbox writes the month, status and attempts of each import in the outbox, and how to resolve the possible duplicates of the ones waiting for it
+func writeOutbox(stdout io.Writer, queuedImports []backendpkg.QueuedImport) error {
+	if len(queuedImports) == 0 {
+		fmt.Fprintln(stdout, "No queued imports")
+		return nil
+	}
+
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "Month\tStatus\tQueued at\tAttempts\tError")
+	for _, queuedImport := range queuedImports {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\n",
+			queuedImport.Month, queuedImport.Status, queuedImport.QueuedAt.Local().Format(time.DateTime), queuedImport.Attempts, queuedImport.Error)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, queuedImport := range queuedImports {
+		if queuedImport.Status == backendpkg.QueuedImportStatusPossibleDuplicates {
+			fmt.Fprintf(stdout, "\nResolve the possible duplicates of %s with -resolve %s -duplicates skip, replace or proceed\n", queuedImport.Month, queuedImport.Month)
+		}
+	}
+
+	return nil
+}
+
+// getBudgetTitle describes a YNAB budget by the participant it belongs to, or as the shared budget
+func getBudgetTitle(budgetId string, participantName string) string {
+	if participantName == "" {
+		return fmt.Sprintf("Shared budget (%s)", budgetId)
+	}
+
+	return fmt.Sprintf("%s's budget (%s)", participantName, budgetId)
+}
+
+// cancelOnInterrupt cancels the YNAB API requests of the backend when the process is interrupted, so that an interrupted import is rolled back instead of left halfway
+// Once interrupted, or once the returned function is called, interrupts terminate the process again
+func cancelOnInterrupt(backend *backendpkg.Backend) func() {
+	interrupts := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(interrupts, os.Interrupt)
+
+	go func() {
+		defer signal.Stop(interrupts)
+
+		select {
+		case <-interrupts:
+			backend.CancelRequests()
+		case <-stopped:
+		}
+	}()
+
+	return func() {
+		close(stopped)
+	}
+}
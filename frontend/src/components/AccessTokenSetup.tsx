@@ -0,0 +1,118 @@
+import { useState } from "react";
+import {
+  Alert,
+  AlertDescription,
+  AlertIcon,
+  Button,
+  Card,
+  CardBody,
+  CardHeader,
+  FormControl,
+  FormHelperText,
+  FormLabel,
+  Input,
+  Stack,
+  Text
+} from "@chakra-ui/react";
+
+import { AuthorizeWithYNAB, SaveAccessToken, UnlockAccessTokenVault } from "../../wailsjs/go/backend/Backend";
+
+export function AccessTokenSetup({ accessTokenStatus }) {
+  const [accessToken, setAccessToken] = useState("")
+  const [passphrase, setPassphrase] = useState("")
+  const [error, setError] = useState("")
+  const [isLoading, setIsLoading] = useState(false)
+
+  const isLocked = accessTokenStatus?.locked
+
+  const submit = () => {
+    setIsLoading(true);
+    setError("");
+
+    const request = isLocked ? UnlockAccessTokenVault(passphrase) : SaveAccessToken(accessToken, passphrase);
+
+    request.catch(message => {
+      setError(String(message));
+    }).finally(() => {
+      setIsLoading(false);
+    });
+  };
+
+  const authorize = () => {
+    setIsLoading(true);
+    setError("");
+
+    AuthorizeWithYNAB().catch(message => {
+      setError(String(message));
+    }).finally(() => {
+      setIsLoading(false);
+    });
+  };
+
+  return (
+    <>
+      <Card className="access-token-setup">
+        <CardHeader>
+          <Text className="access-token-setup-title">
+            {isLocked ? "Unlock your YNAB access token" : "Connect to YNAB"}
+          </Text>
+        </CardHeader>
+        <CardBody>
+          <Stack spacing="4">
+            {!isLocked && accessTokenStatus?.oauth_available && (
+              <>
+                <Button
+                  isLoading={isLoading}
+                  onClick={authorize}
+                >
+                  Sign in with YNAB
+                </Button>
+                <Text className="access-token-setup-separator">or use a Personal Access Token</Text>
+              </>
+            )}
+            {!isLocked && (
+              <FormControl>
+                <FormLabel>Personal Access Token</FormLabel>
+                <Input
+                  type="password"
+                  value={accessToken}
+                  onChange={event => setAccessToken(event.target.value)}
+                />
+                <FormHelperText>
+                  Create one under Account Settings, Developer Settings in YNAB. It is checked against YNAB before being saved.
+                </FormHelperText>
+              </FormControl>
+            )}
+            <FormControl>
+              <FormLabel>Passphrase</FormLabel>
+              <Input
+                type="password"
+                value={passphrase}
+                onChange={event => setPassphrase(event.target.value)}
+              />
+              {!isLocked && (
+                <FormHelperText>
+                  Optional. With a passphrase the token is saved encrypted and must be unlocked on every start,
+                  otherwise it is saved in a file only readable by you.
+                </FormHelperText>
+              )}
+            </FormControl>
+            {error && (
+              <Alert status="error">
+                <AlertIcon />
+                <AlertDescription>{error}</AlertDescription>
+              </Alert>
+            )}
+            <Button
+              isLoading={isLoading}
+              isDisabled={isLocked ? passphrase === "" : accessToken === ""}
+              onClick={submit}
+            >
+              {isLocked ? "Unlock" : "Save"}
+            </Button>
+          </Stack>
+        </CardBody>
+      </Card>
+    </>
+  );
+}
@@ -2,7 +2,7 @@ import {
   Box, Button, Icon, Text
 } from "@chakra-ui/react";
 import { LuSplit } from "react-icons/lu";
-import { FcOk, FcHighPriority } from "react-icons/fc";
+import { FcOk, FcHighPriority, FcClock } from "react-icons/fc";
 
 export function SplitButton({ isDisabled, onClick }) {
   return (
@@ -42,6 +42,10 @@ export function ImportButton({ content, isDisabled, isLoading, onClick }) {
             return (
               <Icon as={FcHighPriority} />
             )
+          } else if (content === "Queued") {
+            return (
+              <Icon as={FcClock} />
+            )
           }
         })()}
         <Text>{content}</Text>
@@ -0,0 +1,54 @@
+import {
+  Alert,
+  AlertDescription,
+  AlertIcon,
+  AlertTitle,
+  Box,
+  Card,
+  CardBody,
+  CardHeader,
+  Stack,
+  Text
+} from "@chakra-ui/react";
+
+export function DiagnosticsReport({ diagnostics }) {
+  const failedChecks = (diagnostics?.checks ?? []).filter(check => check.status === "fail")
+
+  return (
+    <>
+      <Card className="diagnostics-report">
+        <CardHeader>
+          <Text className="diagnostics-report-title">
+            The application could not be set up
+          </Text>
+        </CardHeader>
+        <CardBody>
+          <Stack spacing="3">
+            {failedChecks.length === 0 && (
+              <Alert status="error">
+                <AlertIcon />
+                <AlertDescription>
+                  The monthly expenses cannot be imported with the current configuration
+                </AlertDescription>
+              </Alert>
+            )}
+            {failedChecks.map(check => (
+              <Alert status="error" alignItems="flex-start" key={check.name}>
+                <AlertIcon />
+                <Box>
+                  <AlertTitle>{check.name}</AlertTitle>
+                  <AlertDescription>
+                    <Text>{check.message}</Text>
+                    {check.remediation && (
+                      <Text className="diagnostics-report-remediation">{check.remediation}</Text>
+                    )}
+                  </AlertDescription>
+                </Box>
+              </Alert>
+            ))}
+          </Stack>
+        </CardBody>
+      </Card>
+    </>
+  );
+}
@@ -0,0 +1,112 @@
+import { useEffect, useState } from "react";
+import {
+  Modal,
+  ModalBody,
+  ModalCloseButton,
+  ModalContent,
+  ModalHeader,
+  ModalOverlay,
+  Select,
+  Table,
+  Tbody,
+  Td,
+  Text,
+  Th,
+  Thead,
+  Tr
+} from "@chakra-ui/react";
+
+import { backend } from "../../wailsjs/go/models";
+import { GetHistory, GetHistoryMonths } from "../../wailsjs/go/backend/Backend";
+
+function getOutcome(record: backend.HistoryRecord) {
+  const importResult = record.import_result;
+  if (!importResult) {
+    return "";
+  }
+
+  const createdTransactionsCount = importResult.budgets
+    .reduce((count, budgetImportResult) => count + budgetImportResult.created_transaction_ids.length, 0);
+
+  if (importResult.success) {
+    return `Succeeded, ${createdTransactionsCount} transactions created`;
+  } else if (importResult.queued) {
+    return "Queued";
+  } else if (importResult.canceled) {
+    return "Canceled";
+  }
+  return `Failed: ${importResult.error}`;
+}
+
+export function HistoryModal({ isOpen, onClose }) {
+  const [months, setMonths] = useState<string[]>([])
+  const [selectedMonth, setSelectedMonth] = useState("")
+  const [records, setRecords] = useState<backend.HistoryRecord[]>([])
+
+  useEffect(() => {
+    if (!isOpen) {
+      return;
+    }
+    GetHistoryMonths().then(months => {
+      setMonths(months);
+      setSelectedMonth(months[0] ?? "");
+    });
+  }, [isOpen]);
+
+  useEffect(() => {
+    if (selectedMonth === "") {
+      setRecords([]);
+      return;
+    }
+    GetHistory(selectedMonth).then(setRecords);
+  }, [selectedMonth]);
+
+  return (
+    <>
+      <Modal isOpen={isOpen} onClose={onClose} size="2xl" scrollBehavior="inside">
+        <ModalOverlay />
+        <ModalContent className="import-plan-modal">
+          <ModalHeader>History</ModalHeader>
+          <ModalCloseButton />
+          <ModalBody>
+            {months.length === 0 ? (
+              <Text>Nothing was split or imported yet.</Text>
+            ) : (
+              <>
+                <Select size="sm" value={selectedMonth} onChange={event => setSelectedMonth(event.target.value)}>
+                  {months.map(month => (
+                    <option key={month} value={month}>{month}</option>
+                  ))}
+                </Select>
+                <Table size="sm">
+                  <Thead>
+                    <Tr>
+                      <Th>Recorded at</Th>
+                      <Th>Kind</Th>
+                      <Th>Amounts</Th>
+                      <Th>Outcome</Th>
+                    </Tr>
+                  </Thead>
+                  <Tbody>
+                    {records.map(record => (
+                      <Tr key={record.id}>
+                        <Td>{new Date(record.recorded_at).toLocaleString()}</Td>
+                        <Td>{record.kind}</Td>
+                        <Td>
+                          {Object.entries(record.amounts).map(([categoryName, amount]) => (
+                            <Text key={categoryName}>{categoryName}: {amount}</Text>
+                          ))}
+                        </Td>
+                        <Td>{getOutcome(record)}</Td>
+                      </Tr>
+                    ))}
+                  </Tbody>
+                </Table>
+              </>
+            )}
+          </ModalBody>
+        </ModalContent>
+      </Modal>
+    </>
+  );
+}
@@ -0,0 +1,75 @@
+import { Fragment } from "react";
+import {
+  Button,
+  Modal,
+  ModalBody,
+  ModalCloseButton,
+  ModalContent,
+  ModalFooter,
+  ModalHeader,
+  ModalOverlay,
+  Table,
+  Tbody,
+  Td,
+  Text,
+  Th,
+  Thead,
+  Tr
+} from "@chakra-ui/react";
+
+export function ImportPlanModal({ importPlan, isOpen, onClose, onConfirm }) {
+  return (
+    <>
+      <Modal isOpen={isOpen} onClose={onClose} size="2xl" scrollBehavior="inside">
+        <ModalOverlay />
+        <ModalContent className="import-plan-modal">
+          <ModalHeader>Transactions to import for {importPlan?.month}</ModalHeader>
+          <ModalCloseButton />
+          <ModalBody>
+            {importPlan?.budgets.map(budget => (
+              <div key={budget.budget_id}>
+                <Text className="import-plan-budget">
+                  {budget.participant_name ? `${budget.participant_name}'s budget` : "Shared budget"}
+                </Text>
+                <Table size="sm">
+                  <Thead>
+                    <Tr>
+                      <Th>Transaction</Th>
+                      <Th>Payee</Th>
+                      <Th isNumeric>Amount</Th>
+                      <Th isNumeric>Milliunits</Th>
+                    </Tr>
+                  </Thead>
+                  <Tbody>
+                    {budget.transactions.map(plannedTransaction => (
+                      <Fragment key={plannedTransaction.transaction.import_id}>
+                        <Tr>
+                          <Td>{plannedTransaction.description}</Td>
+                          <Td>{plannedTransaction.transaction.payee_name}</Td>
+                          <Td isNumeric>{plannedTransaction.formatted_amount}</Td>
+                          <Td isNumeric>{plannedTransaction.transaction.amount}</Td>
+                        </Tr>
+                        {(plannedTransaction.transaction.subtransactions ?? []).map((subTransaction, index) => (
+                          <Tr key={`${plannedTransaction.transaction.import_id}-${index}`} className="import-plan-subtransaction">
+                            <Td>{plannedTransaction.subtransaction_descriptions[index]}</Td>
+                            <Td></Td>
+                            <Td isNumeric>{plannedTransaction.formatted_subtransaction_amounts[index]}</Td>
+                            <Td isNumeric>{subTransaction.amount}</Td>
+                          </Tr>
+                        ))}
+                      </Fragment>
+                    ))}
+                  </Tbody>
+                </Table>
+              </div>
+            ))}
+          </ModalBody>
+          <ModalFooter>
+            <Button variant="ghost" onClick={onClose}>Cancel</Button>
+            <Button onClick={onConfirm}>Import</Button>
+          </ModalFooter>
+        </ModalContent>
+      </Modal>
+    </>
+  );
+}
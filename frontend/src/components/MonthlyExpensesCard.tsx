@@ -3,6 +3,7 @@ import {
   Box,
   Card,
   CardBody,
+  CardFooter,
   CardHeader,
   Flex,
   FormLabel,
@@ -12,11 +13,12 @@ import {
   InputLeftAddon,
   NumberInput,
   NumberInputField,
+  Select,
   Stack,
   StackDivider,
   Text
 } from "@chakra-ui/react";
-import { FcBusinesswoman, FcDepartment, FcHome, FcIdea, FcSimCard } from "react-icons/fc";
+import { FcBusinesswoman, FcDepartment, FcHome, FcIdea, FcSimCard, FcViewDetails } from "react-icons/fc";
 import { IoWater } from "react-icons/io5";
 
 function MonthlyExpenseIcon({ categoryName }) {
@@ -39,6 +41,10 @@ function MonthlyExpenseIcon({ categoryName }) {
       return <Icon
         as={FcSimCard}
       />;
+    default:
+      return <Icon
+        as={FcViewDetails}
+      />;
   }
 }
 
@@ -96,7 +102,7 @@ function MonthlyExpenseDisabledInput({ categoryName, amount }) {
   );
 }
 
-export function SharedMonthlyExpensesCard({ monthlyExpenses, onChange }) {
+export function SharedMonthlyExpensesCard({ categoryNames, monthlyExpenses, onChange }) {
   return (
     <>
       <Box className="expenses-card">
@@ -109,34 +115,15 @@ export function SharedMonthlyExpensesCard({ monthlyExpenses, onChange }) {
           </CardHeader>
           <CardBody>
             <Stack divider={<StackDivider />} spacing="5">
-              <Box>
-                <MonthlyExpenseInput
-                  categoryName="Condominium"
-                  amount={monthlyExpenses?.expenses?.["Condominium"]?.amount || ""}
-                  onChange={onChange}
-                />
-              </Box>
-              <Box>
-                <MonthlyExpenseInput
-                  categoryName="Electricity"
-                  amount={monthlyExpenses?.expenses?.["Electricity"]?.amount || ""}
-                  onChange={onChange}
-                />
-              </Box>
-              <Box>
-                <MonthlyExpenseInput
-                  categoryName="Water"
-                  amount={monthlyExpenses?.expenses?.["Water"]?.amount || ""}
-                  onChange={onChange}
-                />
-              </Box>
-              <Box>
-                <MonthlyExpenseInput
-                  categoryName="TV / Internet / Phone"
-                  amount={monthlyExpenses?.expenses?.["TV / Internet / Phone"]?.amount || ""}
-                  onChange={onChange}
-                />
-              </Box>
+              {categoryNames.map((categoryName: string) => (
+                <Box key={categoryName}>
+                  <MonthlyExpenseInput
+                    categoryName={categoryName}
+                    amount={monthlyExpenses?.expenses?.[categoryName]?.amount || ""}
+                    onChange={onChange}
+                  />
+                </Box>
+              ))}
             </Stack>
           </CardBody>
         </Card>
@@ -145,7 +132,23 @@ export function SharedMonthlyExpensesCard({ monthlyExpenses, onChange }) {
   );
 }
 
-export function IndividualMonthlyExpensesCard({ monthlyExpenses }) {
+function RoundingBalance({ balance }) {
+  const amount = parseFloat(balance) || 0;
+
+  return (
+    <>
+      <Text className="rounding-balance">
+        {amount === 0
+          ? "Rounding is even across months"
+          : `Rounding is ${Math.abs(amount).toFixed(3)} € ${amount > 0 ? "in favour" : "against"} across months`}
+      </Text>
+    </>
+  );
+}
+
+export function IndividualMonthlyExpensesCard({ categoryNames, participantNames, monthlyExpenses, roundingBalances, selectedParticipant, onSelectParticipant }) {
+  const participantMonthlyExpenses = monthlyExpenses?.[selectedParticipant];
+
   return (
     <>
       <Box className="expenses-card">
@@ -154,36 +157,33 @@ export function IndividualMonthlyExpensesCard({ monthlyExpenses }) {
             <Flex>
               <Avatar icon={<FcBusinesswoman />} className="individual-avatar" />
               <Text>Individual Share</Text>
+              <Select
+                size="sm"
+                variant="filled"
+                value={selectedParticipant}
+                onChange={(event) => onSelectParticipant(parseInt(event.target.value))}
+              >
+                {participantNames.map((participantName: string, index: number) => (
+                  <option key={participantName} value={index}>{participantName}</option>
+                ))}
+              </Select>
             </Flex>
           </CardHeader>
           <CardBody>
             <Stack divider={<StackDivider />} spacing="5">
-              <Box>
-                <MonthlyExpenseDisabledInput
-                  categoryName="Condominium"
-                  amount={monthlyExpenses?.expenses?.["Condominium"]?.amount || ""}
-                />
-              </Box>
-              <Box>
-                <MonthlyExpenseDisabledInput
-                  categoryName="Electricity"
-                  amount={monthlyExpenses?.expenses?.["Electricity"]?.amount || ""}
-                />
-              </Box>
-              <Box>
-                <MonthlyExpenseDisabledInput
-                  categoryName="Water"
-                  amount={monthlyExpenses?.expenses?.["Water"]?.amount || ""}
-                />
-              </Box>
-              <Box>
-                <MonthlyExpenseDisabledInput
-                  categoryName="TV / Internet / Phone"
-                  amount={monthlyExpenses?.expenses?.["TV / Internet / Phone"]?.amount || ""}
-                />
-              </Box>
+              {categoryNames.map((categoryName: string) => (
+                <Box key={categoryName}>
+                  <MonthlyExpenseDisabledInput
+                    categoryName={categoryName}
+                    amount={participantMonthlyExpenses?.expenses?.[categoryName]?.amount || ""}
+                  />
+                </Box>
+              ))}
             </Stack>
           </CardBody>
+          <CardFooter>
+            <RoundingBalance balance={roundingBalances?.[participantNames[selectedParticipant]]} />
+          </CardFooter>
         </Card>
       </Box>
     </>
@@ -0,0 +1,70 @@
+import {
+  Button,
+  Modal,
+  ModalBody,
+  ModalCloseButton,
+  ModalContent,
+  ModalFooter,
+  ModalHeader,
+  ModalOverlay,
+  Table,
+  Tbody,
+  Td,
+  Text,
+  Th,
+  Thead,
+  Tr
+} from "@chakra-ui/react";
+
+export function PossibleDuplicatesModal({ possibleDuplicates, isOpen, onClose, onResolve }) {
+  // Reconciled transactions are never deleted, so they cannot be replaced
+  const hasReconciledTransactions = possibleDuplicates?.some(possibleDuplicate => possibleDuplicate.existing_transactions
+    .some(transaction => transaction.cleared === "reconciled"));
+
+  return (
+    <>
+      <Modal isOpen={isOpen} onClose={onClose} size="2xl" scrollBehavior="inside">
+        <ModalOverlay />
+        <ModalContent className="import-plan-modal">
+          <ModalHeader>This month's expenses appear to be already in YNAB</ModalHeader>
+          <ModalCloseButton />
+          <ModalBody>
+            <Text>
+              These transactions were entered this month with the same payee and category as the ones to import.
+              Skip leaves them as they are and imports the rest, Replace deletes them once the import succeeds,
+              and Import anyway keeps both.
+            </Text>
+            <Table size="sm">
+              <Thead>
+                <Tr>
+                  <Th>Budget</Th>
+                  <Th>Transaction</Th>
+                  <Th>Date</Th>
+                  <Th>Payee</Th>
+                  <Th isNumeric>Amount</Th>
+                </Tr>
+              </Thead>
+              <Tbody>
+                {possibleDuplicates?.flatMap(possibleDuplicate => possibleDuplicate.existing_transactions.map((transaction, index) => (
+                  <Tr key={`${possibleDuplicate.import_id}-${transaction.id}`}>
+                    <Td>{possibleDuplicate.participant_name ? `${possibleDuplicate.participant_name}'s budget` : "Shared budget"}</Td>
+                    <Td>{possibleDuplicate.description}</Td>
+                    <Td>{transaction.date}</Td>
+                    <Td>{transaction.payee_name}</Td>
+                    <Td isNumeric>{possibleDuplicate.formatted_amounts[index]}</Td>
+                  </Tr>
+                )))}
+              </Tbody>
+            </Table>
+          </ModalBody>
+          <ModalFooter>
+            <Button variant="ghost" onClick={onClose}>Cancel</Button>
+            <Button variant="ghost" onClick={() => onResolve("proceed")}>Import anyway</Button>
+            <Button variant="ghost" isDisabled={hasReconciledTransactions} onClick={() => onResolve("replace")}>Replace</Button>
+            <Button onClick={() => onResolve("skip")}>Skip</Button>
+          </ModalFooter>
+        </ModalContent>
+      </Modal>
+    </>
+  );
+}
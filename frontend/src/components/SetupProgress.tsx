@@ -0,0 +1,24 @@
+import {
+  Box,
+  Progress,
+  Spinner,
+  Text
+} from "@chakra-ui/react";
+
+export function SetupProgress({ setupProgress }) {
+  return (
+    <Box className="setup-progress">
+      <Spinner
+        speed="0.65s"
+      />
+      {setupProgress?.total > 0 && (
+        <Progress
+          size="xs"
+          value={setupProgress.completed}
+          max={setupProgress.total}
+        />
+      )}
+      <Text>{setupProgress?.message ?? "Loading"}</Text>
+    </Box>
+  )
+}
@@ -1,7 +1,7 @@
 import React, { useState, useEffect } from "react";
 import { render } from "react-dom";
 import {
-  ChakraProvider, Alert, AlertIcon, AlertDescription, Box, Flex, Spinner
+  ChakraProvider, Alert, AlertIcon, AlertDescription, Box, Button, Flex, createStandaloneToast
 } from "@chakra-ui/react";
 
 import "./index.css";
@@ -9,16 +9,51 @@ import { theme } from "./themes/theme"
 import { Header } from "./components/Header"
 import { SharedMonthlyExpensesCard, IndividualMonthlyExpensesCard } from "./components/MonthlyExpensesCard"
 import { SplitButton, ImportButton } from "./components/Button"
+import { SetupProgress } from "./components/SetupProgress"
+import { ImportPlanModal } from "./components/ImportPlanModal"
+import { PossibleDuplicatesModal } from "./components/PossibleDuplicatesModal"
+import { HistoryModal } from "./components/HistoryModal"
+import { AccessTokenSetup } from "./components/AccessTokenSetup"
+import { DiagnosticsReport } from "./components/DiagnosticsReport"
 
 import { backend } from "../wailsjs/go/models";
-import { GetSharedMonthlyExpenses, CreateMonthlyExpensesTransactions } from "../wailsjs/go/backend/Backend";
+import { GetAccessTokenStatus, GetSetupProgress, GetQueuedImports, SubmitQueuedImports, ResolveQueuedImport, CancelRequests, GetCategoryNames, GetParticipantNames, GetRoundingBalances, GetSharedMonthlyExpenses, GetImportPlan, CreateMonthlyExpensesTransactions, GetLastImport, UndoLastImport } from "../wailsjs/go/backend/Backend";
 import { EventsEmit, EventsOn } from "../wailsjs/runtime";
 
+const { ToastContainer, toast } = createStandaloneToast({ theme });
+
+function getQueuedImportAlertStatus(queuedImport: backend.QueuedImport) {
+  switch (queuedImport.status) {
+    case "failed":
+      return "error";
+    case "possible_duplicates":
+      return "warning";
+  }
+  return "info";
+}
+
+function getQueuedImportDescription(queuedImport: backend.QueuedImport) {
+  switch (queuedImport.status) {
+    case "failed":
+      return `The queued import of ${queuedImport.month} was rejected by YNAB: ${queuedImport.error}`;
+    case "possible_duplicates":
+      return `The expenses of the queued import of ${queuedImport.month} appear to be already entered in YNAB, nothing is imported until they are reviewed`;
+  }
+  return `The import of ${queuedImport.month} is queued until YNAB is reachable (${queuedImport.attempts} attempts so far)`;
+}
+
 const App = () => {
   const [backendLoaded, setBackendLoaded] = useState(null)
+  const [diagnostics, setDiagnostics] = useState<backend.Diagnostics>()
+  const [accessTokenStatus, setAccessTokenStatus] = useState<backend.AccessTokenStatus>()
+
+  const [categoryNames, setCategoryNames] = useState<string[]>([])
+  const [participantNames, setParticipantNames] = useState<string[]>([])
+  const [selectedParticipant, setSelectedParticipant] = useState(0)
+  const [roundingBalances, setRoundingBalances] = useState<{ [participantName: string]: string }>({})
 
   const [sharedMonthlyExpenses, setSharedMonthlyExpenses] = useState<backend.MonthlyExpenses>()
-  const [individualMonthlyExpenses, setIndividualMonthlyExpenses] = useState<backend.MonthlyExpenses>()
+  const [individualMonthlyExpenses, setIndividualMonthlyExpenses] = useState<backend.MonthlyExpenses[]>()
 
   const [splitButtonDisabled, setSplitButtonDisabled] = useState(true)
 
@@ -26,18 +61,73 @@ const App = () => {
   const [importButtonDisabled, setImportButtonDisabled] = useState(true)
   const [importButtonLoading, setImportButtonLoading] = useState(false)
 
+  const [importPlan, setImportPlan] = useState<backend.ImportPlan>()
+  const [possibleDuplicates, setPossibleDuplicates] = useState<backend.PossibleDuplicate[]>()
+  const [queuedImports, setQueuedImports] = useState<backend.QueuedImport[]>([])
+  const [queuedImportToResolve, setQueuedImportToResolve] = useState<backend.QueuedImport>()
+  const [lastImport, setLastImport] = useState<backend.ImportRecord>()
+  const [undoButtonLoading, setUndoButtonLoading] = useState(false)
+  const [historyOpen, setHistoryOpen] = useState(false)
+  const [setupProgress, setSetupProgress] = useState<backend.SetupProgress>()
+
+  useEffect(() => {
+    GetSetupProgress().then(progress => {
+      setSetupProgress(progress);
+    });
+    EventsOn("setupProgress", function(progress?: backend.SetupProgress) {
+      setSetupProgress(progress);
+    });
+  }, []);
+
   useEffect(() => {
-    EventsOn("backendSetupComplete", function(args?: any) {
+    EventsOn("backendSetupComplete", function(args?: backend.Diagnostics) {
+      GetAccessTokenStatus().then(status => {
+        setAccessTokenStatus(status);
+      });
+      GetCategoryNames().then(names => {
+        setCategoryNames(names);
+      });
+      GetParticipantNames().then(names => {
+        setParticipantNames(names);
+      });
+      GetRoundingBalances().then(balances => {
+        setRoundingBalances(balances);
+      });
+      GetQueuedImports().then(imports => {
+        setQueuedImports(imports);
+      });
+      GetLastImport().then(record => {
+        setLastImport(record);
+      });
+      GetSharedMonthlyExpenses().then(monthlyExpenses => {
+        setSharedMonthlyExpenses(monthlyExpenses);
+      });
+      setDiagnostics(args);
       setTimeout(() => {
-        setBackendLoaded(args);
+        setBackendLoaded(args.ready);
       }, 1000);
     })
   }, []);
 
   useEffect(() => {
-    GetSharedMonthlyExpenses().then(monthlyExpenses=> {
-      setSharedMonthlyExpenses(monthlyExpenses);
-    });
+    EventsOn("outboxChanged", function(imports?: backend.QueuedImport[], submittedImports?: backend.QueuedImport[]) {
+      setQueuedImports(imports);
+      (submittedImports ?? []).forEach(submittedImport => {
+        toast({
+          title: `The queued import of ${submittedImport.month} was submitted to YNAB`,
+          status: "success",
+          isClosable: true,
+        });
+      });
+      if (submittedImports?.length > 0) {
+        GetRoundingBalances().then(balances => {
+          setRoundingBalances(balances);
+        });
+        GetLastImport().then(record => {
+          setLastImport(record);
+        });
+      }
+    })
   }, []);
 
   useEffect(() => {
@@ -50,6 +140,18 @@ const App = () => {
     })
   });
 
+  useEffect(() => {
+    EventsOn("sharedMonthlyExpensesSplitFailed", function(message?: any) {
+      setImportButtonDisabled(true);
+      toast({
+        title: "Unable to split the monthly expenses",
+        description: message,
+        status: "error",
+        isClosable: true,
+      });
+    })
+  }, []);
+
   const handleChange = (event) => {
     const { name, value } = event.target;
 
@@ -65,13 +167,35 @@ const App = () => {
     }));
 
     setSplitButtonDisabled(false);
+    // The individual shares no longer match the amounts until they are split again
+    setImportButtonDisabled(true);
   };
 
   const splitSharedMonthlyExpenses = () => {
     EventsEmit("sharedMonthlyExpensesInput", sharedMonthlyExpenses);
   };
 
-  const createMonthlyExpensesTransactions = () => {
+  const showImportPlan = () => {
+    GetImportPlan(
+      new backend.CombinedMonthlyExpenses({
+        shared_monthly_expenses: sharedMonthlyExpenses,
+        individual_monthly_expenses: individualMonthlyExpenses
+      })
+    ).then(plan => {
+      setImportPlan(plan);
+    }).catch(error => {
+      toast({
+        title: "Unable to show the import plan",
+        description: String(error),
+        status: "error",
+        isClosable: true,
+      });
+    });
+  }
+
+  const createMonthlyExpensesTransactions = (duplicateResolution: string) => {
+    setImportPlan(undefined);
+    setPossibleDuplicates(undefined);
     setSplitButtonDisabled(true);
     setImportButtonDisabled(true);
     setImportButtonLoading(true);
@@ -80,27 +204,198 @@ const App = () => {
       new backend.CombinedMonthlyExpenses({
         shared_monthly_expenses: sharedMonthlyExpenses,
         individual_monthly_expenses: individualMonthlyExpenses
-      })
+      }),
+      duplicateResolution
     ).then(response => {
       setTimeout(() => {
         setImportButtonLoading(false);
-        if (response === true) {
+        if (response.possible_duplicates?.length > 0) {
+          setImportButtonContent("Import");
+          setImportButtonDisabled(false);
+          setSplitButtonDisabled(false);
+          setPossibleDuplicates(response.possible_duplicates);
+        } else if (response.success) {
           setImportButtonContent("Done");
+          const alreadyPresent = response.budgets.flatMap(budget => budget.already_present);
+          if (alreadyPresent.length > 0) {
+            toast({
+              title: "Some transactions were already in YNAB",
+              description: `Not imported again: ${alreadyPresent.join(", ")}`,
+              status: "info",
+              isClosable: true,
+            });
+          }
+          const replacedCount = response.budgets.reduce((count, budget) => count + budget.replaced_transaction_ids.length, 0);
+          if (replacedCount > 0) {
+            toast({
+              title: "Some transactions were replaced",
+              description: `${replacedCount} transactions already in YNAB were deleted in favour of the imported ones`,
+              status: "info",
+              isClosable: true,
+            });
+          }
+          GetRoundingBalances().then(balances => {
+            setRoundingBalances(balances);
+          });
+          GetLastImport().then(record => {
+            setLastImport(record);
+          });
+        } else if (response.canceled) {
+          setImportButtonContent("Import");
+          setImportButtonDisabled(false);
+          setSplitButtonDisabled(false);
+          toast({
+            title: "The import was canceled",
+            description: response.rolled_back ? "No transactions were left in YNAB" : undefined,
+            status: "info",
+            isClosable: true,
+          });
+        } else if (response.queued) {
+          setImportButtonContent("Queued");
+          toast({
+            title: "YNAB could not be reached, the import was queued",
+            description: "It will be submitted as soon as YNAB is reachable again",
+            status: "warning",
+            isClosable: true,
+          });
         } else {
           setImportButtonContent("Error");
           setSplitButtonDisabled(false);
+          toast({
+            title: response.rolled_back
+              ? "Unable to import the monthly expenses, no transactions were left in YNAB"
+              : "Unable to import the monthly expenses",
+            description: response.error,
+            status: "error",
+            isClosable: true,
+          });
         }
       }, 1000);
     });
   }
 
+  const resolveQueuedImport = (duplicateResolution: string) => {
+    const month = queuedImportToResolve.month;
+    setQueuedImportToResolve(undefined);
+
+    ResolveQueuedImport(month, duplicateResolution)
+      .then(() => SubmitQueuedImports())
+      .then(setQueuedImports)
+      .catch(error => {
+        toast({
+          title: `Unable to resolve the possible duplicates of ${month}`,
+          description: String(error),
+          status: "error",
+          isClosable: true,
+        });
+      });
+  }
+
+  const undoLastImport = () => {
+    setUndoButtonLoading(true);
+
+    UndoLastImport().then(response => {
+      setUndoButtonLoading(false);
+      GetLastImport().then(record => {
+        setLastImport(record);
+      });
+      if (response.success) {
+        setImportButtonContent("Import");
+        setImportButtonDisabled(individualMonthlyExpenses === undefined);
+        setSplitButtonDisabled(false);
+        GetRoundingBalances().then(balances => {
+          setRoundingBalances(balances);
+        });
+        toast({
+          title: `The import of ${response.month} was undone`,
+          description: "Its transactions were deleted from YNAB",
+          status: "success",
+          isClosable: true,
+        });
+      } else if (response.refused) {
+        toast({
+          title: `The import of ${response.month} cannot be undone`,
+          description: `Some of its transactions were changed in YNAB since: ${response.problems.join(", ")}`,
+          status: "warning",
+          isClosable: true,
+        });
+      } else {
+        toast({
+          title: "Unable to undo the import",
+          description: response.error,
+          status: "error",
+          isClosable: true,
+        });
+      }
+    });
+  }
+
   return (
     <>
       <ChakraProvider theme={theme}>
+        <ToastContainer />
+        <ImportPlanModal
+          importPlan={importPlan}
+          isOpen={importPlan !== undefined}
+          onClose={() => setImportPlan(undefined)}
+          onConfirm={() => createMonthlyExpensesTransactions("")}
+        />
+        <PossibleDuplicatesModal
+          possibleDuplicates={possibleDuplicates}
+          isOpen={possibleDuplicates !== undefined}
+          onClose={() => setPossibleDuplicates(undefined)}
+          onResolve={createMonthlyExpensesTransactions}
+        />
+        <PossibleDuplicatesModal
+          possibleDuplicates={queuedImportToResolve?.result?.possible_duplicates}
+          isOpen={queuedImportToResolve !== undefined}
+          onClose={() => setQueuedImportToResolve(undefined)}
+          onResolve={resolveQueuedImport}
+        />
+        <HistoryModal
+          isOpen={historyOpen}
+          onClose={() => setHistoryOpen(false)}
+        />
         <Box className="main-container">
           <Header/>
+          {backendLoaded && diagnostics?.checks.filter(check => check.status === "warning").map(check => (
+            <Alert status="warning" className="diagnostics-warning" key={check.name}>
+              <AlertIcon />
+              <AlertDescription>{check.message}. {check.remediation}</AlertDescription>
+            </Alert>
+          ))}
+          {queuedImports.map(queuedImport => (
+            <Alert status={getQueuedImportAlertStatus(queuedImport)} className="diagnostics-warning" key={queuedImport.month}>
+              <AlertIcon />
+              <AlertDescription>
+                {getQueuedImportDescription(queuedImport)}
+              </AlertDescription>
+              {queuedImport.status === "queued" && (
+                <Button size="sm" marginLeft="auto" onClick={() => SubmitQueuedImports().then(setQueuedImports)}>
+                  Retry now
+                </Button>
+              )}
+              {queuedImport.status === "possible_duplicates" && (
+                <Button size="sm" marginLeft="auto" onClick={() => setQueuedImportToResolve(queuedImport)}>
+                  Review
+                </Button>
+              )}
+            </Alert>
+          ))}
+          {lastImport && (
+            <Alert status="info" className="diagnostics-warning">
+              <AlertIcon />
+              <AlertDescription>
+                The last import, of {lastImport.month}, can be undone until its transactions are reconciled or edited in YNAB
+              </AlertDescription>
+              <Button size="sm" marginLeft="auto" isLoading={undoButtonLoading} onClick={undoLastImport}>
+                Undo import
+              </Button>
+            </Alert>
+          )}
           <Flex className="body-container">
             <SharedMonthlyExpensesCard
+              categoryNames={categoryNames}
               monthlyExpenses={sharedMonthlyExpenses}
               onChange={handleChange}
             />
@@ -113,31 +408,43 @@ const App = () => {
                 content={importButtonContent}
                 isDisabled={importButtonDisabled}
                 isLoading={importButtonLoading}
-                onClick={createMonthlyExpensesTransactions}
+                onClick={showImportPlan}
               />
+              {importButtonLoading && (
+                <Button size="xs" variant="ghost" onClick={() => CancelRequests()}>
+                  Cancel
+                </Button>
+              )}
+              <Button size="xs" variant="ghost" isDisabled={!backendLoaded} onClick={() => setHistoryOpen(true)}>
+                History
+              </Button>
             </Box>
             <IndividualMonthlyExpensesCard
+              categoryNames={categoryNames}
+              participantNames={participantNames}
               monthlyExpenses={individualMonthlyExpenses}
+              roundingBalances={roundingBalances}
+              selectedParticipant={selectedParticipant}
+              onSelectParticipant={setSelectedParticipant}
             />
           </Flex>
           {(() => {
             if (backendLoaded === null) {
               return (
                 <Box className="overlay-container">
-                  <Spinner
-                    speed="0.65s"
-                  />
+                  <SetupProgress setupProgress={setupProgress} />
+                </Box>
+              )
+            } else if (backendLoaded === false && accessTokenStatus && (!accessTokenStatus.configured || accessTokenStatus.locked)) {
+              return (
+                <Box className="overlay-container">
+                  <AccessTokenSetup accessTokenStatus={accessTokenStatus} />
                 </Box>
               )
             } else if (backendLoaded === false) {
               return (
                 <Box className="overlay-container">
-                  <Alert status="error">
-                    <AlertIcon />
-                    <AlertDescription maxWidth='sm'>
-                      Error setting up the application
-                    </AlertDescription>
-                  </Alert>
+                  <DiagnosticsReport diagnostics={diagnostics} />
                 </Box>
               )
             }
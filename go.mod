@@ -4,7 +4,6 @@ go 1.21
 
 require (
 	github.com/Azure/go-autorest/autorest/to v0.4.0
-	github.com/agiledragon/gomonkey/v2 v2.11.0
 	github.com/brianvoe/gofakeit/v6 v6.28.0
 	github.com/forPelevin/gomoji v1.1.8
 	github.com/go-resty/resty/v2 v2.11.0
@@ -12,7 +11,10 @@ require (
 	github.com/shopspring/decimal v1.3.1
 	github.com/stretchr/testify v1.8.4
 	github.com/wailsapp/wails/v2 v2.7.1
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.14.0
 	golang.org/x/exp v0.0.0-20230522175609-2e198f4a06a1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -41,9 +43,7 @@ require (
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	github.com/wailsapp/go-webview2 v1.0.10 // indirect
 	github.com/wailsapp/mimetype v1.4.1 // indirect
-	golang.org/x/crypto v0.14.0 // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
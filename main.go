@@ -20,7 +20,7 @@ var icon []byte
 func main() {
 	app := backendpkg.SetupApp()
 
-	backend := backendpkg.SetupBackend()
+	backend := backendpkg.NewBackend()
 
 	wails.Run(&options.App{
 		Title:         "YNAB Monthly Expenses Manager",
@@ -37,6 +37,9 @@ func main() {
 		OnDomReady: func(context context.Context) {
 			backend.DomReady(context)
 		},
+		OnShutdown: func(context context.Context) {
+			backend.Shutdown(context)
+		},
 		Bind: []interface{}{
 			app,
 			backend,